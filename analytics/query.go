@@ -0,0 +1,221 @@
+package analytics
+
+import (
+	"errors"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// ErrInvalidMetric 不支持的排序指标
+var ErrInvalidMetric = errors.New("无效的指标")
+
+// Metrics 可用于排行的指标
+var Metrics = []string{"views", "likes", "collects", "comments"}
+
+// DayStat 一天的数据
+type DayStat struct {
+	Day           string `json:"day"` // YYYY-MM-DD
+	Views         int64  `json:"views"`
+	FollowerViews int64  `json:"follower_views"`
+	Likes         int64  `json:"likes"`
+	Collects      int64  `json:"collects"`
+	Comments      int64  `json:"comments"`
+	NewFans       int64  `json:"new_fans,omitempty"`
+}
+
+// Totals 一段时间内的合计
+type Totals struct {
+	Views         int64 `json:"views"`
+	FollowerViews int64 `json:"follower_views"`
+	Likes         int64 `json:"likes"`
+	Collects      int64 `json:"collects"`
+	Comments      int64 `json:"comments"`
+	NewFans       int64 `json:"new_fans"`
+}
+
+// Audience 浏览来源：粉丝与非粉丝
+type Audience struct {
+	FollowerViews    int64   `json:"follower_views"`
+	NonFollowerViews int64   `json:"non_follower_views"`
+	FollowerRatio    float64 `json:"follower_ratio"`
+}
+
+// FanPoint 粉丝增长曲线上的一天
+type FanPoint struct {
+	Day     string `json:"day"`
+	NewFans int64  `json:"new_fans"` // 当天关注且仍在关注的人数
+	Total   int64  `json:"total"`    // 当天结束时的粉丝数（按现有粉丝的关注时间倒推）
+}
+
+// NoteTotal 排行中的一篇笔记
+type NoteTotal struct {
+	NoteID    uint   `json:"note_id"`
+	NoteTitle string `json:"note_title"`
+	Value     int64  `json:"value"`
+}
+
+// window 最近 days 天（含今天）的起始日期和日期列表
+func window(days int) (time.Time, []string) {
+	since := dayStart(time.Now()).AddDate(0, 0, -(days - 1))
+	labels := make([]string, 0, days)
+	for i := 0; i < days; i++ {
+		labels = append(labels, since.AddDate(0, 0, i).Format("2006-01-02"))
+	}
+	return since, labels
+}
+
+// fill 按日期补齐没有数据的天
+func fill(labels []string, rows []DayStat) []DayStat {
+	byDay := make(map[string]DayStat, len(rows))
+	for _, r := range rows {
+		byDay[r.Day] = r
+	}
+	series := make([]DayStat, 0, len(labels))
+	for _, day := range labels {
+		stat := byDay[day]
+		stat.Day = day
+		series = append(series, stat)
+	}
+	return series
+}
+
+// dailyRows 按天汇总笔记数据，where 为附加条件
+func dailyRows(since time.Time, where string, arg uint) ([]DayStat, error) {
+	var rows []struct {
+		Day           time.Time
+		Views         int64
+		FollowerViews int64
+		Likes         int64
+		Collects      int64
+		Comments      int64
+	}
+	if err := global.Db.Model(&models.NoteDailyStat{}).
+		Select("day, SUM(views) AS views, SUM(follower_views) AS follower_views, SUM(likes) AS likes, SUM(collects) AS collects, SUM(comments) AS comments").
+		Where(where, arg).
+		Where("day >= ?", since).
+		Group("day").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	stats := make([]DayStat, 0, len(rows))
+	for _, r := range rows {
+		stats = append(stats, DayStat{
+			Day:           r.Day.Format("2006-01-02"),
+			Views:         r.Views,
+			FollowerViews: r.FollowerViews,
+			Likes:         r.Likes,
+			Collects:      r.Collects,
+			Comments:      r.Comments,
+		})
+	}
+	return stats, nil
+}
+
+// NoteSeries 单篇笔记最近 days 天的逐日数据
+func NoteSeries(noteID uint, days int) ([]DayStat, error) {
+	since, labels := window(days)
+	rows, err := dailyRows(since, "note_id = ?", noteID)
+	if err != nil {
+		return nil, err
+	}
+	return fill(labels, rows), nil
+}
+
+// AccountSeries 账号下所有笔记最近 days 天的逐日数据，包含新增粉丝
+func AccountSeries(userID uint, days int) ([]DayStat, error) {
+	since, labels := window(days)
+	rows, err := dailyRows(since, "author_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	series := fill(labels, rows)
+
+	fans, err := newFans(userID, since)
+	if err != nil {
+		return nil, err
+	}
+	for i := range series {
+		series[i].NewFans = fans[series[i].Day]
+	}
+	return series, nil
+}
+
+// newFans 每天新增的粉丝数
+func newFans(userID uint, since time.Time) (map[string]int64, error) {
+	var stats []models.AccountDailyStat
+	if err := global.Db.Where("user_id = ? AND day >= ?", userID, since).Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	fans := make(map[string]int64, len(stats))
+	for _, s := range stats {
+		fans[s.Day.Format("2006-01-02")] = s.NewFans
+	}
+	return fans, nil
+}
+
+// Sum 合计逐日数据
+func Sum(series []DayStat) Totals {
+	var t Totals
+	for _, s := range series {
+		t.Views += s.Views
+		t.FollowerViews += s.FollowerViews
+		t.Likes += s.Likes
+		t.Collects += s.Collects
+		t.Comments += s.Comments
+		t.NewFans += s.NewFans
+	}
+	return t
+}
+
+// AudienceOf 浏览的粉丝/非粉丝构成
+func AudienceOf(t Totals) Audience {
+	a := Audience{FollowerViews: t.FollowerViews, NonFollowerViews: t.Views - t.FollowerViews}
+	if t.Views > 0 {
+		a.FollowerRatio = float64(t.FollowerViews) / float64(t.Views)
+	}
+	return a
+}
+
+// FollowerGrowth 最近 days 天的粉丝增长曲线，以当前粉丝数为终点倒推
+func FollowerGrowth(userID uint, days int) ([]FanPoint, error) {
+	since, labels := window(days)
+	fans, err := newFans(userID, since)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	if err := global.Db.Model(&models.Follower{}).Where("fid = ?", userID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	points := make([]FanPoint, len(labels))
+	for i := len(labels) - 1; i >= 0; i-- {
+		points[i] = FanPoint{Day: labels[i], NewFans: fans[labels[i]], Total: total}
+		total -= fans[labels[i]]
+	}
+	return points, nil
+}
+
+// TopNotes 账号最近 days 天按指标排名的笔记
+func TopNotes(userID uint, days int, metric string, limit int) ([]NoteTotal, error) {
+	valid := false
+	for _, m := range Metrics {
+		valid = valid || m == metric
+	}
+	if !valid {
+		return nil, ErrInvalidMetric
+	}
+	since, _ := window(days)
+	var top []NoteTotal
+	err := global.Db.Model(&models.NoteDailyStat{}).
+		Select("note_daily_stats.note_id, notes.note_title, SUM(note_daily_stats."+metric+") AS value").
+		Joins("JOIN notes ON notes.note_id = note_daily_stats.note_id").
+		Where("note_daily_stats.author_id = ? AND note_daily_stats.day >= ?", userID, since).
+		Group("note_daily_stats.note_id, notes.note_title").
+		Having("value > 0").
+		Order("value DESC, note_daily_stats.note_id DESC").
+		Limit(limit).
+		Scan(&top).Error
+	return top, err
+}
@@ -0,0 +1,209 @@
+package analytics
+
+//创作者数据按天汇总：点赞、收藏、评论和新增粉丝定期从明细表重新聚合最近几天（幂等，取消点赞等会反映出来），
+//浏览没有明细，由浏览计数写回时直接累加
+
+import (
+	"log"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Options 汇总任务参数
+type Options struct {
+	IntervalMinutes int // 重新聚合的间隔（分钟）
+	RecomputeDays   int // 每次重新聚合最近多少天
+	BackfillDays    int // 启动时回填多少天的历史数据
+}
+
+var opts = Options{
+	IntervalMinutes: 30,
+	RecomputeDays:   3,
+	BackfillDays:    90,
+}
+
+// Init 设置参数并启动汇总任务，未配置的项使用默认值，需在数据库初始化之后调用
+func Init(o Options) {
+	if o.IntervalMinutes > 0 {
+		opts.IntervalMinutes = o.IntervalMinutes
+	}
+	if o.RecomputeDays > 0 {
+		opts.RecomputeDays = o.RecomputeDays
+	}
+	if o.BackfillDays > 0 {
+		opts.BackfillDays = o.BackfillDays
+	}
+	go run()
+}
+
+func run() {
+	if err := rollup(opts.BackfillDays); err != nil {
+		log.Printf("回填创作者数据失败: %v", err)
+	}
+	ticker := time.NewTicker(time.Duration(opts.IntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := rollup(opts.RecomputeDays); err != nil {
+			log.Printf("汇总创作者数据失败: %v", err)
+		}
+	}
+}
+
+// dayStart 某天 0 点（本地时间）
+func dayStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// noteDayCount 按笔记和日期分组计数的查询结果
+type noteDayCount struct {
+	NID      uint
+	AuthorID uint
+	Day      time.Time
+	Count    int64
+}
+
+// 从明细表重新聚合的笔记指标：列名 → 聚合语句
+var noteMetrics = map[string]string{
+	"likes": `SELECT l.nid AS n_id, n.note_creator_id AS author_id, DATE(l.create_date) AS day, COUNT(*) AS count
+		FROM likes l JOIN notes n ON n.note_id = l.nid
+		WHERE l.nid IS NOT NULL AND l.create_date >= ? GROUP BY l.nid, n.note_creator_id, DATE(l.create_date)`,
+	"collects": `SELECT c.nid AS n_id, n.note_creator_id AS author_id, DATE(c.create_date) AS day, COUNT(*) AS count
+		FROM collects c JOIN notes n ON n.note_id = c.nid
+		WHERE c.create_date >= ? GROUP BY c.nid, n.note_creator_id, DATE(c.create_date)`,
+	"comments": `SELECT c.note_id AS n_id, n.note_creator_id AS author_id, DATE(c.created_at) AS day, COUNT(*) AS count
+		FROM comments c JOIN notes n ON n.note_id = c.note_id
+		WHERE c.created_at >= ? GROUP BY c.note_id, n.note_creator_id, DATE(c.created_at)`,
+}
+
+// rollup 重新聚合最近 days 天的点赞、收藏、评论和新增粉丝
+func rollup(days int) error {
+	since := dayStart(time.Now()).AddDate(0, 0, -(days - 1))
+	for column, query := range noteMetrics {
+		var rows []noteDayCount
+		if err := global.Db.Raw(query, since).Scan(&rows).Error; err != nil {
+			return err
+		}
+		stats := make([]models.NoteDailyStat, 0, len(rows))
+		for _, r := range rows {
+			stat := models.NoteDailyStat{NoteID: r.NID, AuthorID: r.AuthorID, Day: r.Day}
+			switch column {
+			case "likes":
+				stat.Likes = r.Count
+			case "collects":
+				stat.Collects = r.Count
+			case "comments":
+				stat.Comments = r.Count
+			}
+			stats = append(stats, stat)
+		}
+		err := global.Db.Transaction(func(tx *gorm.DB) error {
+			// 先清零再写入，全部被取消的当天数据也能归零
+			if err := tx.Model(&models.NoteDailyStat{}).Where("day >= ?", since).Update(column, 0).Error; err != nil {
+				return err
+			}
+			if len(stats) == 0 {
+				return nil
+			}
+			return tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "note_id"}, {Name: "day"}},
+				DoUpdates: clause.AssignmentColumns([]string{column}),
+			}).CreateInBatches(&stats, 500).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var fans []struct {
+		Fid   uint
+		Day   time.Time
+		Count int64
+	}
+	if err := global.Db.Model(&models.Follower{}).
+		Select("fid, DATE(created_at) AS day, COUNT(*) AS count").
+		Where("created_at >= ?", since).
+		Group("fid, DATE(created_at)").
+		Scan(&fans).Error; err != nil {
+		return err
+	}
+	stats := make([]models.AccountDailyStat, 0, len(fans))
+	for _, f := range fans {
+		stats = append(stats, models.AccountDailyStat{UserID: f.Fid, Day: f.Day, NewFans: f.Count})
+	}
+	return global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AccountDailyStat{}).Where("day >= ?", since).Update("new_fans", 0).Error; err != nil {
+			return err
+		}
+		if len(stats) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+			DoUpdates: clause.AssignmentColumns([]string{"new_fans"}),
+		}).CreateInBatches(&stats, 500).Error
+	})
+}
+
+// ViewBatch 一批待记入看板的浏览：同一浏览者对同一笔记的浏览次数
+type ViewBatch struct {
+	NoteID   uint
+	AuthorID uint
+	ViewerID uint // 未登录为 0
+	Count    uint
+}
+
+// AddViews 把浏览计入当天的汇总，并按浏览者是否关注了作者区分粉丝浏览
+func AddViews(batches []ViewBatch) error {
+	if len(batches) == 0 {
+		return nil
+	}
+	var viewerIDs, authorIDs []uint
+	for _, b := range batches {
+		if b.ViewerID > 0 {
+			viewerIDs = append(viewerIDs, b.ViewerID)
+			authorIDs = append(authorIDs, b.AuthorID)
+		}
+	}
+	following := make(map[[2]uint]bool)
+	if len(viewerIDs) > 0 {
+		var pairs []struct{ Uid, Fid uint }
+		if err := global.Db.Model(&models.Follower{}).Select("uid", "fid").
+			Where("uid IN ? AND fid IN ?", viewerIDs, authorIDs).
+			Scan(&pairs).Error; err != nil {
+			return err
+		}
+		for _, p := range pairs {
+			following[[2]uint{p.Uid, p.Fid}] = true
+		}
+	}
+
+	today := dayStart(time.Now())
+	byNote := make(map[uint]*models.NoteDailyStat)
+	for _, b := range batches {
+		stat, ok := byNote[b.NoteID]
+		if !ok {
+			stat = &models.NoteDailyStat{NoteID: b.NoteID, AuthorID: b.AuthorID, Day: today}
+			byNote[b.NoteID] = stat
+		}
+		stat.Views += int64(b.Count)
+		if following[[2]uint{b.ViewerID, b.AuthorID}] {
+			stat.FollowerViews += int64(b.Count)
+		}
+	}
+	stats := make([]models.NoteDailyStat, 0, len(byNote))
+	for _, stat := range byNote {
+		stats = append(stats, *stat)
+	}
+	return global.Db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "note_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"views":          gorm.Expr("views + VALUES(views)"),
+			"follower_views": gorm.Expr("follower_views + VALUES(follower_views)"),
+		}),
+	}).CreateInBatches(&stats, 500).Error
+}
@@ -16,6 +16,163 @@ type Config struct {
 		MaxIdleConns int
 		MaxOpenConns int
 	}
+	Notification struct {
+		AggregateWindowMinutes int // 同一目标的同类通知在该时间窗口内聚合为一条
+		RecentActors           int // 聚合通知保留的最近参与者人数
+	}
+	Mail struct {
+		Driver   string // 邮件通道：smtp/memory，留空不发送邮件；memory 只记录不发送，仅用于测试
+		Host     string
+		Port     int
+		Username string
+		Password string
+		From     string // 发件人，如 "TravelFromSYSU <noreply@example.com>"
+	}
+	Digest struct {
+		Enabled         bool
+		IntervalMinutes int    // 扫描间隔（分钟）
+		SendHour        int    // 在用户本地时间几点之后发送
+		MaxItems        int    // 单封邮件最多列出的通知条数
+		SiteURL         string // 邮件中笔记/用户链接的站点地址
+		APIURL          string // 退订链接指向的后端地址
+		Secret          string // 退订链接签名密钥
+	}
+	Push struct {
+		Enabled       bool
+		Workers       int // 推送协程数
+		QueueSize     int // 推送队列长度
+		MaxRetries    int // 可重试错误的最大重试次数
+		BackoffMillis int // 首次重试等待时间（毫秒），之后指数增长
+		SweepSeconds  int // 扫描待推送通知的间隔（秒）
+
+		Fake bool // 启用本地假推送（只记录不发送，可用 fake 平台登记设备），仅限开发/测试环境
+
+		APNs struct {
+			KeyFile    string // .p8 私钥文件路径，为空则不启用
+			KeyID      string
+			TeamID     string
+			Topic      string // 应用 Bundle ID
+			Production bool
+		}
+		FCM struct {
+			CredentialsFile string // 服务账号 JSON 文件路径，为空则不启用
+			ProjectID       string
+		}
+	}
+	Search struct {
+		ScoreWeight    float64 // 热度分在搜索排序中的权重，0 表示只按相关度
+		RefreshMinutes int     // 索引热度分刷新间隔（分钟）
+		SnippetLength  int     // 搜索结果摘要长度（字符）
+
+		HistoryLimit     int      // 每个用户保留的搜索历史条数
+		DedupeMinutes    int      // 同一用户重复搜索同一查询在该时间内只计一次热度
+		LogRetentionDays int      // 搜索明细保留天数
+		TrendingMinUsers int      // 进入热搜所需的最少独立用户数
+		SuggestMinCount  int      // 进入输入联想所需的最少累计搜索次数
+		SuggestMinUsers  int      // 进入输入联想所需的最少独立用户数
+		Blocklist        []string // 屏蔽词，不进入热搜和联想
+	}
+	Feed struct {
+		CandidateDays           int     // 只召回该天数内更新的笔记
+		SeenDays                int     // 曝光过的笔记在该天数内不再推荐
+		ExplorationRate         float64 // 每页中探索内容的比例（0~1）
+		ImpressionRetentionDays int     // 曝光记录保留天数
+		DefaultRanker           string  // 未进入实验的用户使用的排序策略：personalized/fresh/popular
+		Experiments             []struct {
+			Name     string // 实验名，写入曝光记录
+			Traffic  int    // 进入该实验的用户百分比，各实验按顺序切分流量，合计不超过 100
+			Variants []struct {
+				Name   string // 分组名
+				Ranker string // 该组使用的排序策略
+				Weight int    // 组内流量的相对权重
+			}
+		}
+	}
+	Timeline struct {
+		BigAccountFans int // 粉丝数不低于该值的作者不写扩散，粉丝读时拉取
+		MaxEntries     int // 每个用户时间线保留的条数
+		BackfillCount  int // 关注时回填对方最近的笔记数
+		BatchSize      int // 写扩散每批写入的粉丝数
+		TrimMinutes    int // 截断时间线的间隔（分钟）
+	}
+	Views struct {
+		DedupeMinutes int // 同一浏览者在该时间内重复浏览同一笔记只计一次
+		FlushSeconds  int // 浏览计数写回数据库的间隔（秒）
+		BatchSize     int // 每条 UPDATE 合并的笔记数
+		DevicesPerIP  int // 去重窗口内同一 IP 最多计入的未登录设备数
+	}
+	Analytics struct {
+		IntervalMinutes int // 从明细表重新汇总的间隔（分钟）
+		RecomputeDays   int // 每次重新汇总最近多少天
+		BackfillDays    int // 启动时回填多少天的历史数据
+	}
+	HotScore struct {
+		Enabled         bool
+		IntervalSeconds int     // 增量重算间隔（秒）
+		DecayMinutes    int     // 对仍有热度的笔记做衰减重算的间隔（分钟）
+		WindowHours     int     // 窗口热度（trending）统计最近多少小时的互动
+		BatchSize       int     // 每批读写的笔记数
+		LikeWeight      float64 // 点赞权重
+		CollectWeight   float64 // 收藏权重
+		CommentWeight   float64 // 评论权重
+		ViewWeight      float64 // 浏览权重
+		Gravity         float64 // 时间衰减指数：分母为 (发布小时数 + 2) ^ Gravity
+		HalfScore       float64 // 原始分等于该值时映射为 50 分
+	}
+	Storage struct {
+		Driver string // local/aliyun/s3
+		Local  struct {
+			Dir     string // 存放目录
+			BaseURL string // 对外访问的服务地址
+			Prefix  string // 文件访问的路由前缀
+			Secret  string // 签名 URL 的密钥
+		}
+		Aliyun struct { // 为空时读取 .env 中的 OSS_* 变量
+			Endpoint        string
+			Bucket          string
+			AccessKeyID     string
+			AccessKeySecret string
+		}
+		S3 struct {
+			Endpoint        string
+			Region          string
+			Bucket          string
+			AccessKeyID     string
+			SecretAccessKey string
+			PathStyle       bool   // MinIO 需要开启
+			PublicBaseURL   string // 对外访问地址（如 CDN）
+		}
+	}
+	Resumable struct {
+		TempDir        string // 分片临时文件目录
+		SessionHours   int    // 会话在最后一次收到分片后保留的小时数
+		MaxSizeMB      int64  // 单个文件大小上限（MB）
+		MaxChunkMB     int64  // 单个分片大小上限（MB）
+		CleanupMinutes int    // 清理过期会话的间隔（分钟）
+	}
+	Media struct {
+		PresignMinutes int   // 直传地址的有效期（分钟）
+		MaxImageMB     int64 // 直传图片的大小上限（MB）
+		MaxVideoMB     int64 // 直传视频的大小上限（MB）
+
+		GCIntervalMinutes int  // 回收未引用文件的间隔（分钟）
+		GCGraceHours      int  // 上传后超过多少小时仍未被引用才回收
+		GCBatchSize       int  // 每次回收最多处理的记录数
+		GCDryRun          bool // 只输出回收报告，不实际删除
+	}
+	Imaging struct {
+		MaxDimension       int // 原图长边上限（像素）
+		MediumDimension    int // 中图长边（像素）
+		ThumbnailDimension int // 缩略图长边（像素）
+		Quality            int // JPEG 编码质量 1~100
+		MaxMegapixels      int // 像素数上限（百万），防止解压炸弹
+		MaxMegabytes       int // 图片文件大小上限（MB）
+	}
+	Realtime struct {
+		SendBuffer       int      // 每个连接的发送缓冲区大小，写满即断开慢客户端
+		HeartbeatSeconds int      // 心跳间隔（秒）
+		AllowedOrigins   []string // 允许建立 WebSocket 连接的跨域来源，如 https://example.com；"*" 表示不限制
+	}
 }
 
 var AppCongfig *Config
@@ -32,6 +32,11 @@ func InitDB() {
 	if err != nil {
 		log.Fatalf("Error migrating Follower table: %v", err)
 	}
+	// 再迁移拉黑关系表
+	err = db.AutoMigrate(&models.UserBlock{})
+	if err != nil {
+		log.Fatalf("Error migrating UserBlock table: %v", err)
+	}
 	// 再迁移 tag 表
 	err = db.AutoMigrate(&models.Tag{})
 	if err != nil {
@@ -63,10 +68,50 @@ func InitDB() {
 		log.Fatalf("Error migrating collect table: %v", err)
 	}
 	// 再迁移 Notification 表
-	err = db.AutoMigrate(&models.Notification{})
+	err = db.AutoMigrate(&models.Notification{}, &models.NotificationActor{}, &models.NotificationPreference{}, &models.NotificationSetting{}, &models.DigestLog{}, &models.DeviceToken{})
 	if err != nil {
 		log.Fatalf("Error migrating Notification table: %v", err)
 	}
+	// 再迁移 mention 表
+	err = db.AutoMigrate(&models.Mention{})
+	if err != nil {
+		log.Fatalf("Error migrating mention table: %v", err)
+	}
+	// 再迁移搜索历史相关表
+	err = db.AutoMigrate(&models.SearchHistory{}, &models.SearchSetting{}, &models.SearchQueryLog{}, &models.SearchQueryStat{})
+	if err != nil {
+		log.Fatalf("Error migrating search tables: %v", err)
+	}
+	// 再迁移推荐流相关表
+	err = db.AutoMigrate(&models.TagFollow{}, &models.FeedFeedback{}, &models.FeedImpression{})
+	if err != nil {
+		log.Fatalf("Error migrating feed tables: %v", err)
+	}
+	// 再迁移关注流时间线表
+	err = db.AutoMigrate(&models.TimelineEntry{})
+	if err != nil {
+		log.Fatalf("Error migrating timeline tables: %v", err)
+	}
+	// 再迁移创作者数据汇总表
+	err = db.AutoMigrate(&models.NoteDailyStat{}, &models.AccountDailyStat{})
+	if err != nil {
+		log.Fatalf("Error migrating analytics tables: %v", err)
+	}
+	// 再迁移旅伴群组相关表
+	err = db.AutoMigrate(&models.TripGroup{}, &models.TripGroupMember{}, &models.GroupMessage{})
+	if err != nil {
+		log.Fatalf("Error migrating trip group tables: %v", err)
+	}
+	// 再迁移断点续传会话表
+	err = db.AutoMigrate(&models.UploadSession{})
+	if err != nil {
+		log.Fatalf("Error migrating upload session table: %v", err)
+	}
+	// 再迁移媒体文件登记表
+	err = db.AutoMigrate(&models.Media{})
+	if err != nil {
+		log.Fatalf("Error migrating media table: %v", err)
+	}
 
 	if err != nil {
 		log.Fatalf("Fail to initialize database, got error: %v", err)
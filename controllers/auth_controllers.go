@@ -1,15 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
-	"path/filepath"
-	"strings"
+	"strconv"
 	"time"
 	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/imaging"
+	"travel-from-sysu-backend/media"
 	"travel-from-sysu-backend/models"
-	"travel-from-sysu-backend/oss"
 	"travel-from-sysu-backend/utils"
 )
 
@@ -463,27 +465,19 @@ func UploadAvatar(ctx *gin.Context) {
 		return
 	}
 
-	// 校验文件类型
-	allowedExtensions := []string{".jpg", ".jpeg", ".png", ".webp"}
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	isAllowed := false
-	for _, allowedExt := range allowedExtensions {
-		if ext == allowedExt {
-			isAllowed = true
-			break
-		}
-	}
-	if !isAllowed {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "文件类型不支持，仅支持 jpg、png、webp 格式"})
-		return
-	}
-
-	// 将文件上传到阿里云 OSS
-	filePath, err := oss.UploadFileToAliyunOss(file, "avatar")
+	// 按内容校验格式，去除元数据并压缩后上传
+	image, err := imaging.Upload(file, "avatar")
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "OSS上传失败: " + err.Error()})
+		if errors.Is(err, imaging.ErrUnsupported) || errors.Is(err, imaging.ErrTooLarge) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "文件上传失败: " + err.Error()})
 		return
 	}
+	filePath := image.URL
+	userID, _ := strconv.ParseUint(uid, 10, 64)
+	registerMedia(uint(userID), filePath, models.MediaKindAvatar, image.ContentType, image.Size, image.Hash)
 
 	// 更新用户表的 avatar 字段
 	var user models.User
@@ -491,15 +485,27 @@ func UploadAvatar(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户未找到"})
 		return
 	}
+	oldAvatar := user.Avatar
 	user.Avatar = filePath
 	if err := global.Db.Save(&user).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户头像失败: " + err.Error()})
 		return
 	}
 
+	// 新头像记为已引用，旧头像取消引用后由回收任务清理
+	if err := media.Attach(0, []string{filePath}); err != nil {
+		log.Printf("记录头像引用失败: %v", err)
+	}
+	if oldAvatar != "" && oldAvatar != filePath {
+		if err := media.Detach([]string{oldAvatar}); err != nil {
+			log.Printf("取消旧头像引用失败: %v", err)
+		}
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "头像上传成功",
-		"avatar":  filePath,
+		"message":          "头像上传成功",
+		"avatar":           filePath,
+		"avatar_thumbnail": image.ThumbnailURL,
 	})
 }
 
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/hotscore"
 	"travel-from-sysu-backend/models"
 )
 
@@ -130,9 +131,16 @@ func PublishComment(ctx *gin.Context) {
 
 	// 提交事务
 	tx.Commit()
-
-	// 添加通知记录
-	if err := AddNotificationAndUpdateUnreadCount(req.CreatorId, req.ReplyUid, "comment"); err != nil {
+	hotscore.MarkDirty(req.NoteId)
+
+	// 添加通知记录：回复评论时通知被回复的用户，否则通知笔记作者
+	var note models.Note
+	global.Db.First(&note, "note_id = ?", req.NoteId)
+	recipientID := req.ReplyUid
+	if recipientID == 0 {
+		recipientID = note.NoteCreatorID
+	}
+	if err := AddNotificationAndUpdateUnreadCount(req.CreatorId, recipientID, "comment", CommentNotificationTarget(comment, note)); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -141,6 +149,9 @@ func PublishComment(ctx *gin.Context) {
 		return
 	}
 
+	// 解析评论中的 @，已经收到评论通知的人不再重复提醒
+	syncMentions("comment", comment.CommentId, req.CreatorId, req.Content, CommentNotificationTarget(comment, note), recipientID)
+
 	// 成功响应
 	ctx.JSON(http.StatusOK, PublishCommentResponse{
 		Status: "评论发布成功",
@@ -201,6 +212,8 @@ func DeleteComment(ctx *gin.Context) {
 		})
 		return
 	}
+	deleteMentions("comment", []uint{comment.CommentId})
+	hotscore.MarkDirty(comment.NoteId)
 
 	// 更新 note 表中的 comment_count
 	if err := global.Db.Model(&models.Note{}).
@@ -256,6 +269,8 @@ func GetCommentById(ctx *gin.Context) {
 		return
 	}
 
+	comment.Mentions = loadMentionEntities("comment", []uint{comment.CommentId})[comment.CommentId]
+
 	// 成功响应
 	ctx.JSON(http.StatusOK, GetCommentResponse{
 		Status:   "成功",
@@ -309,6 +324,8 @@ func GetFirstLevelCommentsByNoteId(ctx *gin.Context) {
 		return
 	}
 
+	fillCommentMentions(comments)
+
 	// 成功响应：返回评论列表
 	ctx.JSON(http.StatusOK, gin.H{
 		"status":   "成功",
@@ -361,6 +378,8 @@ func GetSecondLevelCommentsByParentId(ctx *gin.Context) {
 		return
 	}
 
+	fillCommentMentions(comments)
+
 	// 成功响应
 	ctx.JSON(http.StatusOK, GetSecondLevelCommentsResponse{
 		Status:   "成功",
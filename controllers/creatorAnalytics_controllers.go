@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"travel-from-sysu-backend/analytics"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// parseCreatorQuery 解析创作者看板的 user_id 和 days 参数，days 默认 30，最多 90
+func parseCreatorQuery(ctx *gin.Context) (uint, int, bool) {
+	userID, err := strconv.ParseUint(ctx.Query("user_id"), 10, 64)
+	if err != nil || userID == 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "user_id 参数格式不正确",
+		})
+		return 0, 0, false
+	}
+	days, err := strconv.Atoi(ctx.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 || days > 90 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "days 需要为 1~90 的整数",
+		})
+		return 0, 0, false
+	}
+	return uint(userID), days, true
+}
+
+// GetCreatorOverview 账号数据总览：逐日浏览/点赞/收藏/评论/新增粉丝、合计、粉丝增长和观众构成
+func GetCreatorOverview(ctx *gin.Context) {
+	userID, days, ok := parseCreatorQuery(ctx)
+	if !ok {
+		return
+	}
+
+	series, err := analytics.AccountSeries(userID, days)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询账号数据失败",
+		})
+		return
+	}
+	growth, err := analytics.FollowerGrowth(userID, days)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询粉丝增长失败",
+		})
+		return
+	}
+	totals := analytics.Sum(series)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"days":            days,
+			"series":          series,
+			"totals":          totals,
+			"audience":        analytics.AudienceOf(totals),
+			"follower_growth": growth,
+		},
+	})
+}
+
+// GetCreatorNoteStats 单篇笔记的逐日数据，只能查看自己的笔记
+func GetCreatorNoteStats(ctx *gin.Context) {
+	userID, days, ok := parseCreatorQuery(ctx)
+	if !ok {
+		return
+	}
+	noteID, err := strconv.ParseUint(ctx.Query("note_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "note_id 参数格式不正确",
+		})
+		return
+	}
+
+	var note models.Note
+	if err := global.Db.First(&note, "note_id = ?", noteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Status: "失败",
+				Code:   404,
+				Error:  "笔记不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询笔记失败",
+		})
+		return
+	}
+	if note.NoteCreatorID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Status: "失败",
+			Code:   403,
+			Error:  "只能查看自己笔记的数据",
+		})
+		return
+	}
+
+	series, err := analytics.NoteSeries(note.NoteID, days)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询笔记数据失败",
+		})
+		return
+	}
+	totals := analytics.Sum(series)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"note_id":  note.NoteID,
+			"days":     days,
+			"series":   series,
+			"totals":   totals,
+			"audience": analytics.AudienceOf(totals),
+			"lifetime": gin.H{
+				"view_count":     note.ViewCount,
+				"like_counts":    note.LikeCounts,
+				"collect_counts": note.CollectCounts,
+				"comment_counts": note.CommentCounts,
+			},
+		},
+	})
+}
+
+// GetCreatorTopNotes 最近一段时间表现最好的笔记，metric 可选 views/likes/collects/comments
+func GetCreatorTopNotes(ctx *gin.Context) {
+	userID, days, ok := parseCreatorQuery(ctx)
+	if !ok {
+		return
+	}
+	limit := 10
+	if n, err := strconv.Atoi(ctx.DefaultQuery("num", "10")); err == nil && n > 0 && n <= 50 {
+		limit = n
+	}
+	metric := ctx.DefaultQuery("metric", "views")
+
+	top, err := analytics.TopNotes(userID, days, metric, limit)
+	if err != nil {
+		if errors.Is(err, analytics.ErrInvalidMetric) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  "metric 只能是 views/likes/collects/comments",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询笔记排行失败",
+		})
+		return
+	}
+	if top == nil {
+		top = []analytics.NoteTotal{}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"metric": metric,
+			"days":   days,
+			"notes":  top,
+		},
+	})
+}
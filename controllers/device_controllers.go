@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/push"
+)
+
+// RegisterDeviceRequest 登记推送设备请求结构
+type RegisterDeviceRequest struct {
+	Uid      uint   `json:"uid" binding:"required"`      // 当前用户 ID
+	Token    string `json:"token" binding:"required"`    // 厂商下发的设备令牌
+	Platform string `json:"platform" binding:"required"` // 设备平台：ios/android
+}
+
+// UnregisterDeviceRequest 注销推送设备请求结构
+type UnregisterDeviceRequest struct {
+	Uid   uint   `json:"uid" binding:"required"`   // 当前用户 ID
+	Token string `json:"token" binding:"required"` // 设备令牌
+}
+
+// RegisterDevice 登记推送设备，应用启动或令牌刷新时调用
+// 同一令牌再次登记会改绑到当前用户（同一台设备换账号登录）
+func RegisterDevice(ctx *gin.Context) {
+	var req RegisterDeviceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	provider := push.ProviderForPlatform(req.Platform)
+	if provider == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "不支持的设备平台：" + req.Platform,
+		})
+		return
+	}
+	if len(req.Token) > 255 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "设备令牌过长",
+		})
+		return
+	}
+
+	device := models.DeviceToken{
+		UserID:   req.Uid,
+		Platform: req.Platform,
+		Provider: provider,
+		Token:    req.Token,
+	}
+	if err := global.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "provider", "updated_at"}),
+	}).Create(&device).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "登记设备失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "登记成功",
+		"code":   200,
+	})
+}
+
+// UnregisterDevice 注销推送设备，退出登录时调用
+func UnregisterDevice(ctx *gin.Context) {
+	var req UnregisterDeviceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	result := global.Db.Where("user_id = ? AND token = ?", req.Uid, req.Token).Delete(&models.DeviceToken{})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "注销设备失败：" + result.Error.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":   "注销成功",
+		"code":     200,
+		"affected": result.RowsAffected,
+	})
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"travel-from-sysu-backend/media"
+)
+
+// PresignUploadRequest 申请直传凭证的请求
+type PresignUploadRequest struct {
+	Uid         uint   `json:"uid" binding:"required"`          // 上传者 ID
+	Kind        string `json:"kind" binding:"required"`         // image/video
+	ContentType string `json:"content_type" binding:"required"` // 文件的内容类型，上传时必须一致
+	Size        int64  `json:"size" binding:"required"`         // 文件大小（字节），上传的文件不能超过该值
+}
+
+// CompleteUploadRequest 直传完成回调的请求
+type CompleteUploadRequest struct {
+	Uid uint   `json:"uid" binding:"required"` // 上传者 ID
+	Key string `json:"key" binding:"required"` // 申请凭证时返回的对象路径
+}
+
+// PresignUpload 签发直传对象存储的 PUT 地址和 POST 表单策略，文件不经过本服务
+func PresignUpload(ctx *gin.Context) {
+	var req PresignUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	ticket, err := media.Presign(req.Uid, req.Kind, req.ContentType, req.Size)
+	if err != nil {
+		if errors.Is(err, media.ErrInvalidType) || errors.Is(err, media.ErrTooLarge) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "签发直传凭证失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data":   ticket,
+	})
+}
+
+// CompleteUpload 直传完成后回调，服务端核对文件后登记，返回可用于发布笔记的 URL
+func CompleteUpload(ctx *gin.Context) {
+	var req CompleteUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	completed, err := media.Complete(req.Uid, req.Key)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Status: "失败",
+				Code:   404,
+				Error:  err.Error(),
+			})
+		case errors.Is(err, media.ErrNotUploaded):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Status: "失败",
+				Code:   409,
+				Error:  err.Error(),
+			})
+		case errors.Is(err, media.ErrMismatch):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+				Status: "失败",
+				Code:   500,
+				Error:  "确认上传失败",
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data":   completed,
+	})
+}
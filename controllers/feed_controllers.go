@@ -0,0 +1,333 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"travel-from-sysu-backend/feed"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/utils"
+)
+
+// FeedNoteResponse 推荐流中的笔记
+type FeedNoteResponse struct {
+	HotRecNoteResponse
+	Sources []string `json:"sources"` // 推荐理由（召回来源）
+}
+
+// NotInterestedRequest 不感兴趣请求
+type NotInterestedRequest struct {
+	Uid        uint   `json:"uid" binding:"required"`         // 当前用户 ID
+	TargetType string `json:"target_type" binding:"required"` // note/author/tag
+	Target     string `json:"target" binding:"required"`      // 笔记 ID、作者 ID 或标签名
+}
+
+// TagFollowRequest 关注/取消关注标签请求
+type TagFollowRequest struct {
+	Uid     uint   `json:"uid" binding:"required"`      // 当前用户 ID
+	TagName string `json:"tag_name" binding:"required"` // 标签名
+}
+
+// GetRecommendFeed 个性化推荐流，每次返回尚未看过的笔记
+func GetRecommendFeed(ctx *gin.Context) {
+	uid := ctx.Query("user_id")
+	num := ctx.DefaultQuery("num", "20")
+
+	userID, err := strconv.Atoi(uid)
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "uid 参数格式不正确",
+		})
+		return
+	}
+	limit := 20
+	if n, err := strconv.Atoi(num); err == nil && n > 0 && n <= 30 {
+		limit = n
+	}
+
+	items, assignment, err := feed.Recommend(uint(userID), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"success": false,
+			"msg":     "生成推荐失败",
+		})
+		return
+	}
+
+	notes := make([]models.Note, 0, len(items))
+	for _, item := range items {
+		notes = append(notes, item.Note)
+	}
+	states := utils.LoadViewerStates(userID, notes)
+
+	responseNotes := make([]FeedNoteResponse, 0, len(items))
+	for _, item := range items {
+		note := item.Note
+		responseNotes = append(responseNotes, FeedNoteResponse{
+			HotRecNoteResponse: HotRecNoteResponse{
+				NoteID:         note.NoteID,
+				NoteTitle:      note.NoteTitle,
+				NoteContent:    note.NoteContent,
+				LikeCounts:     uint(note.LikeCounts),
+				CollectCounts:  note.CollectCounts,
+				CommentCounts:  note.CommentCounts,
+				NoteCreatorID:  note.NoteCreatorID,
+				NoteUpdateTime: uint(note.NoteUpdateTime),
+				ViewCount:      note.ViewCount,
+				NoteTagList:    note.NoteTagList,
+				NoteURLs:       note.NoteURLs,
+				Thumbnail:      utils.NoteThumbnail(note.NoteURLs),
+				Score:          note.Score,
+				Status:         states.Of(note),
+			},
+			Sources: item.Sources,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"success": true,
+		"msg":     "成功",
+		"data": gin.H{
+			"notes":      responseNotes,
+			"experiment": assignment.Experiment, // 客户端上报点击时无需回传，仅用于调试
+			"variant":    assignment.Variant,
+		},
+	})
+}
+
+// FeedClickRequest 推荐流点击上报请求
+type FeedClickRequest struct {
+	Uid    uint `json:"uid" binding:"required"`     // 当前用户 ID
+	NoteID uint `json:"note_id" binding:"required"` // 点击的笔记 ID
+}
+
+// RecordFeedClick 上报推荐流中的笔记点击，用于统计各实验分组的点击率
+func RecordFeedClick(ctx *gin.Context) {
+	var req FeedClickRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	if err := feed.RecordClick(req.Uid, req.NoteID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "记录点击失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+	})
+}
+
+// GetFeedExperimentStats 各实验分组最近若干天的点击率和点赞率
+func GetFeedExperimentStats(ctx *gin.Context) {
+	days, err := strconv.Atoi(ctx.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > 90 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "days 需要为 1~90 的整数",
+		})
+		return
+	}
+
+	stats, err := feed.ExperimentStats(strings.TrimSpace(ctx.Query("experiment")), days)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "统计实验数据失败",
+		})
+		return
+	}
+	if stats == nil {
+		stats = []feed.VariantStats{}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"days":     days,
+			"variants": stats,
+			"rankers":  feed.RankerNames(),
+		},
+	})
+}
+
+// MarkNotInterested 对笔记、作者或标签标记不感兴趣
+func MarkNotInterested(ctx *gin.Context) {
+	handleNotInterested(ctx, feed.MarkNotInterested)
+}
+
+// UndoNotInterested 撤销不感兴趣
+func UndoNotInterested(ctx *gin.Context) {
+	handleNotInterested(ctx, feed.UndoNotInterested)
+}
+
+func handleNotInterested(ctx *gin.Context, apply func(userID uint, targetType, targetKey string) error) {
+	var req NotInterestedRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	if err := apply(req.Uid, req.TargetType, req.Target); err != nil {
+		if errors.Is(err, feed.ErrInvalidFeedback) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "保存反馈失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+	})
+}
+
+// FollowTag 关注标签
+func FollowTag(ctx *gin.Context) {
+	var req TagFollowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	var tag models.Tag
+	if err := global.Db.Where("t_name = ?", strings.TrimSpace(req.TagName)).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Status: "失败",
+				Code:   404,
+				Error:  "标签不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询标签失败",
+		})
+		return
+	}
+
+	follow := models.TagFollow{UserID: req.Uid, TagID: tag.ID, CreatedAt: time.Now()}
+	if err := global.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "关注标签失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+	})
+}
+
+// UnfollowTag 取消关注标签
+func UnfollowTag(ctx *gin.Context) {
+	var req TagFollowRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	if err := global.Db.
+		Where("user_id = ? AND tag_id IN (?)", req.Uid,
+			global.Db.Model(&models.Tag{}).Select("id").Where("t_name = ?", strings.TrimSpace(req.TagName))).
+		Delete(&models.TagFollow{}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "取消关注标签失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+	})
+}
+
+// GetFollowedTags 获取关注的标签
+func GetFollowedTags(ctx *gin.Context) {
+	userID, err := strconv.ParseUint(ctx.Query("user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "user_id 参数格式不正确",
+		})
+		return
+	}
+
+	var tagNames []string
+	if err := global.Db.Model(&models.Tag{}).
+		Joins("JOIN tag_follows ON tag_follows.tag_id = tags.id").
+		Where("tag_follows.user_id = ?", userID).
+		Order("tag_follows.id DESC").
+		Pluck("tags.t_name", &tagNames).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询关注的标签失败",
+		})
+		return
+	}
+	if tagNames == nil {
+		tagNames = []string{}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"tags": tagNames,
+		},
+	})
+}
@@ -4,11 +4,13 @@ import (
 	"errors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 	"travel-from-sysu-backend/global"
 	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/timeline"
 	"travel-from-sysu-backend/utils"
 )
 
@@ -57,6 +59,19 @@ type SimplifiedUser struct {
 	Avatar        string `json:"avatar"`
 }
 
+// simplifyUser 转为精简的用户信息
+func simplifyUser(user models.User) SimplifiedUser {
+	return SimplifiedUser{
+		UserID:        user.UserId,
+		Name:          user.Username,
+		Description:   user.Description,
+		FanCount:      user.FanCount,
+		FollowerCount: user.FollowerCount,
+		Gender:        user.Gender,
+		Avatar:        user.Avatar,
+	}
+}
+
 // Follow 关注接口
 // @Summary 用户关注接口
 // @Description 当前用户可以通过此接口关注目标用户
@@ -92,6 +107,17 @@ func Follow(ctx *gin.Context) {
 		return
 	}
 
+	// 存在拉黑关系时不能关注
+	if isBlockedBetween(req.CurrentUserID, req.TargetUserID) {
+		ctx.JSON(http.StatusForbidden, FollowResponse{
+			Code:    403,
+			Success: false,
+			Msg:     "无法关注该用户",
+			FStatus: "",
+		})
+		return
+	}
+
 	// 检查是否已经关注
 	var existingFollower models.Follower
 	if err := global.Db.Where("uid = ? AND fid = ?", req.CurrentUserID, req.TargetUserID).First(&existingFollower).Error; err == nil {
@@ -124,8 +150,9 @@ func Follow(ctx *gin.Context) {
 	// 更新计数
 	global.Db.Model(&models.User{}).Where("user_id = ?", req.TargetUserID).Update("fan_count", gorm.Expr("fan_count + ?", 1))
 	global.Db.Model(&models.User{}).Where("user_id = ?", req.CurrentUserID).Update("follower_count", gorm.Expr("follower_count + ?", 1))
+	timeline.Backfill(req.CurrentUserID, req.TargetUserID)
 
-	if err := AddNotificationAndUpdateUnreadCount(req.CurrentUserID, req.TargetUserID, "follow"); err != nil {
+	if err := AddNotificationAndUpdateUnreadCount(req.CurrentUserID, req.TargetUserID, "follow", UserNotificationTarget(req.TargetUserID)); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -193,6 +220,12 @@ func Unfollow(ctx *gin.Context) {
 	// 更新计数
 	global.Db.Model(&models.User{}).Where("user_id = ?", req.TargetUserID).Update("fan_count", gorm.Expr("fan_count - ?", 1))
 	global.Db.Model(&models.User{}).Where("user_id = ?", req.CurrentUserID).Update("follower_count", gorm.Expr("follower_count - ?", 1))
+	timeline.RemoveAuthor(req.CurrentUserID, req.TargetUserID)
+
+	// 撤回关注通知，失败不影响取消关注
+	if err := RetractNotification(req.CurrentUserID, req.TargetUserID, "follow", UserNotificationTarget(req.TargetUserID)); err != nil {
+		log.Printf("撤回关注通知失败: %v", err)
+	}
 
 	ctx.JSON(http.StatusOK, FollowResponse{
 		Code:    200,
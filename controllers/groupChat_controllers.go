@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/realtime"
+	"travel-from-sysu-backend/utils"
+)
+
+// SendGroupMessageRequest 发送群聊消息请求结构
+type SendGroupMessageRequest struct {
+	GroupID uint   `json:"group_id" binding:"required"` // 群组 ID
+	Uid     uint   `json:"uid" binding:"required"`      // 发送者 ID
+	Content string `json:"content" binding:"required"`  // 消息内容，支持 @用户名
+}
+
+// PinGroupMessageRequest 置顶/取消置顶群聊消息请求结构
+type PinGroupMessageRequest struct {
+	MessageID uint `json:"message_id" binding:"required"` // 消息 ID
+	Uid       uint `json:"uid" binding:"required"`        // 当前用户 ID
+}
+
+// GroupMessageResponse 群聊消息操作响应结构
+type GroupMessageResponse struct {
+	Status    string `json:"status"`
+	Code      int    `json:"code"`
+	MessageID uint   `json:"message_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// resolveGroupMentions 解析消息中 @ 的用户名，只保留群内成员（不含发送者自己）
+func resolveGroupMentions(groupID uint, senderID uint, content string) []uint {
+	tokens := utils.ParseMentions(content)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var usernames []string
+	for _, token := range tokens {
+		usernames = append(usernames, token.Username)
+	}
+
+	var mentionedIDs []uint
+	global.Db.Table("trip_group_members").
+		Select("trip_group_members.uid").
+		Joins("JOIN users ON users.user_id = trip_group_members.uid").
+		Where("trip_group_members.group_id = ? AND users.username IN ? AND trip_group_members.uid <> ?", groupID, usernames, senderID).
+		Pluck("trip_group_members.uid", &mentionedIDs)
+	return mentionedIDs
+}
+
+// publishGroupMessage 将群聊消息实时推送给所有群成员
+func publishGroupMessage(message *models.GroupMessage) {
+	if message == nil {
+		return
+	}
+	var memberIDs []uint
+	global.Db.Model(&models.TripGroupMember{}).Where("group_id = ?", message.GroupID).Pluck("uid", &memberIDs)
+	for _, memberID := range memberIDs {
+		realtime.Publish(memberID, realtime.EventChatMessage, message)
+	}
+}
+
+// SendGroupMessage 发送群聊消息，被 @ 的群成员会收到通知
+func SendGroupMessage(ctx *gin.Context) {
+	var req SendGroupMessageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, GroupMessageResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	if _, err := getGroupMember(req.GroupID, req.Uid); err != nil {
+		ctx.JSON(http.StatusForbidden, GroupMessageResponse{
+			Status: "失败",
+			Code:   403,
+			Error:  "不在该群组中",
+		})
+		return
+	}
+
+	mentionedIDs := resolveGroupMentions(req.GroupID, req.Uid, req.Content)
+	mentionsJSON, _ := json.Marshal(mentionedIDs)
+	if mentionedIDs == nil {
+		mentionsJSON = []byte("[]")
+	}
+
+	message := models.GroupMessage{
+		GroupID:   req.GroupID,
+		SenderID:  req.Uid,
+		MsgType:   "text",
+		Content:   req.Content,
+		Mentions:  string(mentionsJSON),
+		CreatedAt: time.Now(),
+	}
+	if err := global.Db.Create(&message).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, GroupMessageResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "消息发送失败：" + err.Error(),
+		})
+		return
+	}
+
+	publishGroupMessage(&message)
+
+	// 通知被 @ 的成员，通知失败不影响消息发送
+	target := models.NotificationTarget{
+		TargetType:     "group",
+		TargetID:       req.GroupID,
+		CommentExcerpt: utils.TruncateRunes(req.Content, 60),
+	}
+	for _, mentionedID := range mentionedIDs {
+		if err := AddNotificationAndUpdateUnreadCount(req.Uid, mentionedID, "chat_mention", target); err != nil {
+			log.Printf("群聊 @ 通知创建失败: %v", err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, GroupMessageResponse{
+		Status:    "发送成功",
+		Code:      200,
+		MessageID: message.MessageID,
+	})
+}
+
+// GetGroupMessages 获取群聊历史消息，只返回当前用户入群之后的消息，按消息 ID 倒序游标分页
+func GetGroupMessages(ctx *gin.Context) {
+	groupID := ctx.Query("group_id")
+	uid := ctx.Query("uid")
+	cursor := ctx.Query("cursor")
+	num := ctx.DefaultQuery("num", "20")
+
+	if groupID == "" || uid == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "缺少 group_id 或 uid 参数",
+		})
+		return
+	}
+
+	groupIDUint, err := strconv.ParseUint(groupID, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "group_id 参数格式错误",
+		})
+		return
+	}
+	userID, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "uid 参数格式错误",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(num)
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	member, err := getGroupMember(uint(groupIDUint), uint(userID))
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"status": "失败",
+			"code":   403,
+			"error":  "不在该群组中",
+		})
+		return
+	}
+
+	// 只能看到入群系统消息及之后的消息
+	query := global.Db.Where("group_id = ? AND message_id >= ?", groupIDUint, member.JoinMsgID)
+	if cursor != "" {
+		if cursorID, err := strconv.Atoi(cursor); err == nil {
+			query = query.Where("message_id < ?", cursorID)
+		}
+	}
+
+	var messages []models.GroupMessage
+	if err := query.Order("message_id DESC").Limit(limit).Find(&messages).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "查询群聊消息失败：" + err.Error(),
+		})
+		return
+	}
+
+	nextCursor := ""
+	if len(messages) == limit {
+		nextCursor = strconv.Itoa(int(messages[len(messages)-1].MessageID))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"messages":    messages,
+			"next_cursor": nextCursor,
+		},
+	})
+}
+
+// setGroupMessagePinned 置顶或取消置顶消息，只有能看到该消息的群成员可以操作
+func setGroupMessagePinned(ctx *gin.Context, pinned bool) {
+	var req PinGroupMessageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, GroupMessageResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	var message models.GroupMessage
+	if err := global.Db.First(&message, "message_id = ?", req.MessageID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, GroupMessageResponse{
+			Status: "失败",
+			Code:   404,
+			Error:  "消息不存在",
+		})
+		return
+	}
+
+	member, err := getGroupMember(message.GroupID, req.Uid)
+	if err != nil || message.MessageID < member.JoinMsgID {
+		ctx.JSON(http.StatusForbidden, GroupMessageResponse{
+			Status: "失败",
+			Code:   403,
+			Error:  "无权操作该消息",
+		})
+		return
+	}
+	if message.MsgType == "system" {
+		ctx.JSON(http.StatusBadRequest, GroupMessageResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "系统消息不能置顶",
+		})
+		return
+	}
+
+	var user models.User
+	global.Db.Select("username").First(&user, "user_id = ?", req.Uid)
+
+	var systemMessage *models.GroupMessage
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"is_pinned": pinned,
+			"pinned_by": uint(0),
+			"pinned_at": nil,
+		}
+		notice := user.Username + " 取消了一条置顶消息"
+		if pinned {
+			updates["pinned_by"] = req.Uid
+			updates["pinned_at"] = time.Now()
+			notice = user.Username + " 置顶了一条消息"
+		}
+		if err := tx.Model(&models.GroupMessage{}).Where("message_id = ?", req.MessageID).Updates(updates).Error; err != nil {
+			return err
+		}
+		var err error
+		systemMessage, err = addGroupSystemMessage(tx, message.GroupID, notice)
+		return err
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, GroupMessageResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "更新置顶状态失败：" + err.Error(),
+		})
+		return
+	}
+
+	publishGroupMessage(systemMessage)
+
+	status := "取消置顶成功"
+	if pinned {
+		status = "置顶成功"
+	}
+	ctx.JSON(http.StatusOK, GroupMessageResponse{
+		Status:    status,
+		Code:      200,
+		MessageID: req.MessageID,
+	})
+}
+
+// PinGroupMessage 置顶群聊消息
+func PinGroupMessage(ctx *gin.Context) {
+	setGroupMessagePinned(ctx, true)
+}
+
+// UnpinGroupMessage 取消置顶群聊消息
+func UnpinGroupMessage(ctx *gin.Context) {
+	setGroupMessagePinned(ctx, false)
+}
+
+// GetPinnedGroupMessages 获取群聊置顶消息（只返回入群之后的消息）
+func GetPinnedGroupMessages(ctx *gin.Context) {
+	groupID := ctx.Query("group_id")
+	uid := ctx.Query("uid")
+
+	if groupID == "" || uid == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "缺少 group_id 或 uid 参数",
+		})
+		return
+	}
+
+	groupIDUint, err := strconv.ParseUint(groupID, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "group_id 参数格式错误",
+		})
+		return
+	}
+	userID, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "uid 参数格式错误",
+		})
+		return
+	}
+
+	member, err := getGroupMember(uint(groupIDUint), uint(userID))
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"status": "失败",
+			"code":   403,
+			"error":  "不在该群组中",
+		})
+		return
+	}
+
+	var messages []models.GroupMessage
+	if err := global.Db.Where("group_id = ? AND is_pinned = ? AND message_id >= ?", groupIDUint, true, member.JoinMsgID).
+		Order("pinned_at DESC").Find(&messages).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "查询置顶消息失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"messages": messages,
+		},
+	})
+}
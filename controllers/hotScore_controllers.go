@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"travel-from-sysu-backend/hotscore"
+)
+
+// hotScorePreviewLimit 单次预览的最大笔记数
+const hotScorePreviewLimit = 50
+
+// PreviewHotScores 试算笔记热度，不写库。可通过 like_weight、collect_weight、comment_weight、
+// view_weight、gravity、half_score 覆盖当前参数，用于调参时对比效果
+func PreviewHotScores(ctx *gin.Context) {
+	var noteIDs []uint
+	for _, part := range strings.Split(ctx.Query("note_ids"), ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || id == 0 {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  "note_ids 参数格式不正确",
+			})
+			return
+		}
+		noteIDs = append(noteIDs, uint(id))
+	}
+	if len(noteIDs) == 0 || len(noteIDs) > hotScorePreviewLimit {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "note_ids 需要 1~" + strconv.Itoa(hotScorePreviewLimit) + " 个笔记 ID",
+		})
+		return
+	}
+
+	var override hotscore.Weights
+	for _, p := range []struct {
+		name string
+		dst  *float64
+	}{
+		{"like_weight", &override.Like},
+		{"collect_weight", &override.Collect},
+		{"comment_weight", &override.Comment},
+		{"view_weight", &override.View},
+		{"gravity", &override.Gravity},
+		{"half_score", &override.HalfScore},
+	} {
+		value := ctx.Query(p.name)
+		if value == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v <= 0 {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  p.name + " 需要为正数",
+			})
+			return
+		}
+		*p.dst = v
+	}
+
+	weights, items, err := hotscore.Preview(noteIDs, override)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "计算热度失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"weights": weights,
+			"current": hotscore.DefaultWeights(),
+			"notes":   items,
+		},
+	})
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/hotscore"
 	"travel-from-sysu-backend/models"
 	"travel-from-sysu-backend/utils"
 )
@@ -95,10 +97,11 @@ func Like(ctx *gin.Context) {
 		global.Db.Model(&models.Tag{}).
 			Where("t_name IN ?", tags).
 			Update("collect_count", gorm.Expr("like_count + ?", 1))
+		hotscore.MarkDirty(note.NoteID)
 	}
 
 	// 添加通知记录
-	if err := AddNotificationAndUpdateUnreadCount(req.Uid, note.NoteCreatorID, "like"); err != nil {
+	if err := AddNotificationAndUpdateUnreadCount(req.Uid, note.NoteCreatorID, "like", NoteNotificationTarget(note)); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -158,6 +161,15 @@ func Dislike(ctx *gin.Context) {
 		return
 	}
 
+	// 撤回点赞通知，失败不影响取消点赞
+	var note models.Note
+	if err := global.Db.First(&note, req.NoteID).Error; err == nil {
+		hotscore.MarkDirty(note.NoteID)
+		if err := RetractNotification(req.Uid, note.NoteCreatorID, "like", NoteNotificationTarget(note)); err != nil {
+			log.Printf("撤回点赞通知失败: %v", err)
+		}
+	}
+
 	ctx.JSON(http.StatusOK, LikeOrCollectResponse{
 		Status: "取消点赞成功",
 		Code:   200,
@@ -221,10 +233,11 @@ func Collect(ctx *gin.Context) {
 		global.Db.Model(&models.Tag{}).
 			Where("t_name IN ?", tags).
 			Update("collect_count", gorm.Expr("collect_count + ?", 1))
+		hotscore.MarkDirty(note.NoteID)
 	}
 
 	// 添加通知记录
-	if err := AddNotificationAndUpdateUnreadCount(req.Uid, note.NoteCreatorID, "collect"); err != nil {
+	if err := AddNotificationAndUpdateUnreadCount(req.Uid, note.NoteCreatorID, "collect", NoteNotificationTarget(note)); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -302,6 +315,12 @@ func Uncollect(ctx *gin.Context) {
 		global.Db.Model(&models.Tag{}).
 			Where("t_name IN ?", tags).
 			Update("collect_count", gorm.Expr("collect_count - ?", 1))
+		hotscore.MarkDirty(note.NoteID)
+
+		// 撤回收藏通知，失败不影响取消收藏
+		if err := RetractNotification(req.Uid, note.NoteCreatorID, "collect", NoteNotificationTarget(note)); err != nil {
+			log.Printf("撤回收藏通知失败: %v", err)
+		}
 	}
 
 	ctx.JSON(http.StatusOK, LikeOrCollectResponse{
@@ -464,6 +483,21 @@ func LikeComment(ctx *gin.Context) {
 		return
 	}
 
+	// 添加通知记录（通知评论作者）
+	var comment models.Comments
+	if err := global.Db.First(&comment, "comment_id = ?", req.CommentID).Error; err == nil {
+		var note models.Note
+		global.Db.First(&note, "note_id = ?", comment.NoteId)
+		if err := AddNotificationAndUpdateUnreadCount(req.Uid, comment.CreatorId, "comment_like", CommentNotificationTarget(comment, note)); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"status": "失败",
+				"code":   500,
+				"error":  "通知记录创建失败：" + err.Error(),
+			})
+			return
+		}
+	}
+
 	ctx.JSON(http.StatusOK, LikeOrCollectResponse{
 		Status: "点赞成功",
 		Code:   200,
@@ -515,6 +549,15 @@ func UnLikeComment(ctx *gin.Context) {
 		return
 	}
 
+	// 撤回评论点赞通知，失败不影响取消点赞
+	var comment models.Comments
+	if err := global.Db.First(&comment, "comment_id = ?", req.CommentID).Error; err == nil {
+		target := models.NotificationTarget{TargetType: "comment", TargetID: comment.CommentId}
+		if err := RetractNotification(req.Uid, comment.CreatorId, "comment_like", target); err != nil {
+			log.Printf("撤回评论点赞通知失败: %v", err)
+		}
+	}
+
 	ctx.JSON(http.StatusOK, LikeOrCollectResponse{
 		Status: "取消点赞成功",
 		Code:   200,
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"travel-from-sysu-backend/media"
+)
+
+// GetMediaGCReport 试运行一次未引用媒体文件的回收，返回会被删除的文件，不做实际删除
+func GetMediaGCReport(ctx *gin.Context) {
+	report, err := media.CollectGarbage(true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "生成回收报告失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data":   report,
+	})
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/utils"
+)
+
+// maxMentionsPerSource 单篇笔记/单条评论最多生效的 @ 人数，防止刷提醒
+const maxMentionsPerSource = 20
+
+// syncMentions 重新解析正文中的 @ 并覆盖保存，只给本次新增的被提及者发送 mention 通知，skip 中的用户不发通知
+// 发布和更新笔记/评论后调用，失败只记录日志，不影响主流程
+func syncMentions(sourceType string, sourceID uint, authorID uint, content string, target models.NotificationTarget, skip ...uint) []models.MentionEntity {
+	tokens := utils.ParseMentions(content)
+
+	usernames := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		usernames = append(usernames, token.Username)
+	}
+	userIDs := make(map[string]uint)
+	if len(usernames) > 0 {
+		// 与作者存在拉黑关系的用户不能被 @，也不记录提及
+		query := global.Db.Select("user_id", "username").Where("username IN ?", usernames)
+		if blocked := blockedUserIDs(authorID); len(blocked) > 0 {
+			query = query.Where("user_id NOT IN ?", blocked)
+		}
+		var users []models.User
+		query.Find(&users)
+		for _, user := range users {
+			userIDs[user.Username] = user.UserId
+		}
+	}
+
+	var mentions []models.Mention
+	var entities []models.MentionEntity
+	distinct := make(map[uint]bool)
+	for _, token := range tokens {
+		uid, ok := userIDs[token.Username]
+		if !ok {
+			continue
+		}
+		if !distinct[uid] && len(distinct) >= maxMentionsPerSource {
+			continue
+		}
+		distinct[uid] = true
+		mentions = append(mentions, models.Mention{
+			SourceType:   sourceType,
+			SourceID:     sourceID,
+			MentionedUID: uid,
+			Offset:       token.Offset,
+			Length:       token.Length,
+		})
+		entities = append(entities, models.MentionEntity{
+			UID:      uid,
+			Username: token.Username,
+			Offset:   token.Offset,
+			Length:   token.Length,
+		})
+	}
+
+	var previous []uint
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Mention{}).
+			Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+			Distinct().Pluck("mentioned_uid", &previous).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+			Delete(&models.Mention{}).Error; err != nil {
+			return err
+		}
+		if len(mentions) == 0 {
+			return nil
+		}
+		return tx.Create(&mentions).Error
+	})
+	if err != nil {
+		log.Printf("保存 %s %d 的 @ 提及失败: %v", sourceType, sourceID, err)
+		return entities
+	}
+
+	// 编辑时已经提醒过的人不再重复提醒
+	notified := make(map[uint]bool, len(previous)+len(skip))
+	for _, uid := range previous {
+		notified[uid] = true
+	}
+	for _, uid := range skip {
+		notified[uid] = true
+	}
+	for uid := range distinct {
+		if notified[uid] {
+			continue
+		}
+		if err := AddNotificationAndUpdateUnreadCount(authorID, uid, "mention", target); err != nil {
+			log.Printf("@ 提及通知创建失败: %v", err)
+		}
+	}
+	return entities
+}
+
+// deleteMentions 删除笔记/评论对应的 @ 记录
+func deleteMentions(sourceType string, sourceIDs []uint) {
+	if len(sourceIDs) == 0 {
+		return
+	}
+	if err := global.Db.Where("source_type = ? AND source_id IN ?", sourceType, sourceIDs).
+		Delete(&models.Mention{}).Error; err != nil {
+		log.Printf("删除 %s 的 @ 提及失败: %v", sourceType, err)
+	}
+}
+
+// loadMentionEntities 批量加载笔记/评论的 @ 实体，用户名取当前值
+func loadMentionEntities(sourceType string, sourceIDs []uint) map[uint][]models.MentionEntity {
+	result := make(map[uint][]models.MentionEntity)
+	if len(sourceIDs) == 0 {
+		return result
+	}
+
+	var mentions []models.Mention
+	global.Db.Where("source_type = ? AND source_id IN ?", sourceType, sourceIDs).
+		Order("source_id, `offset`").Find(&mentions)
+	if len(mentions) == 0 {
+		return result
+	}
+
+	uidSet := make(map[uint]bool)
+	var uids []uint
+	for _, m := range mentions {
+		if !uidSet[m.MentionedUID] {
+			uidSet[m.MentionedUID] = true
+			uids = append(uids, m.MentionedUID)
+		}
+	}
+	var users []models.User
+	global.Db.Select("user_id", "username").Where("user_id IN ?", uids).Find(&users)
+	usernames := make(map[uint]string, len(users))
+	for _, u := range users {
+		usernames[u.UserId] = u.Username
+	}
+
+	for _, m := range mentions {
+		username, ok := usernames[m.MentionedUID]
+		if !ok {
+			continue // 用户已注销
+		}
+		result[m.SourceID] = append(result[m.SourceID], models.MentionEntity{
+			UID:      m.MentionedUID,
+			Username: username,
+			Offset:   m.Offset,
+			Length:   m.Length,
+		})
+	}
+	return result
+}
+
+// fillCommentMentions 为评论列表填充 @ 实体
+func fillCommentMentions(comments []models.Comments) {
+	ids := make([]uint, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.CommentId)
+	}
+	entities := loadMentionEntities("comment", ids)
+	for i := range comments {
+		comments[i].Mentions = entities[comments[i].CommentId]
+	}
+}
+
+// SuggestMentionUsers @ 输入联想：按用户名前缀匹配，自己关注的人排在前面，其次按粉丝数
+func SuggestMentionUsers(ctx *gin.Context) {
+	keyword := strings.TrimSpace(strings.TrimPrefix(ctx.Query("keyword"), "@"))
+	uid := ctx.Query("uid")
+	num := ctx.DefaultQuery("num", "10")
+
+	limit, err := strconv.Atoi(num)
+	if err != nil || limit <= 0 || limit > 20 {
+		limit = 10
+	}
+	userID, _ := strconv.ParseUint(uid, 10, 64)
+
+	// 转义 LIKE 通配符，只做前缀匹配以便走 username 唯一索引
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(keyword)
+	query := global.Db.Table("users").
+		Select("users.*").
+		Where("users.deleted_at IS NULL").
+		Where("users.user_id <> ?", userID)
+	// 与当前用户存在拉黑关系（任一方向）的用户不出现在联想中
+	if blocked := blockedUserIDs(uint(userID)); len(blocked) > 0 {
+		query = query.Where("users.user_id NOT IN ?", blocked)
+	}
+	if escaped != "" {
+		query = query.Where("users.username LIKE ?", escaped+"%")
+	}
+
+	var users []models.User
+	if err := query.
+		Joins("LEFT JOIN followers ON followers.fid = users.user_id AND followers.uid = ?", userID).
+		Order("followers.fid IS NULL, users.fan_count DESC, users.user_id").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "查询用户失败：" + err.Error(),
+		})
+		return
+	}
+
+	simplifiedUsers := make([]SimplifiedUser, 0, len(users))
+	for _, user := range users {
+		simplifiedUsers = append(simplifiedUsers, SimplifiedUser{
+			UserID:        user.UserId,
+			Name:          user.Username,
+			Description:   user.Description,
+			FanCount:      user.FanCount,
+			FollowerCount: user.FollowerCount,
+			Gender:        user.Gender,
+			Avatar:        user.Avatar,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"users": simplifiedUsers,
+		},
+	})
+}
@@ -2,19 +2,28 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/hotscore"
+	"travel-from-sysu-backend/imaging"
+	"travel-from-sysu-backend/media"
 	"travel-from-sysu-backend/models"
-	"travel-from-sysu-backend/oss"
+	"travel-from-sysu-backend/search"
+	"travel-from-sysu-backend/storage"
+	"travel-from-sysu-backend/timeline"
 	"travel-from-sysu-backend/utils"
+	"travel-from-sysu-backend/views"
 )
 
 type NewNote struct {
@@ -115,31 +124,110 @@ type DeleteNoteResponse struct {
 	Error  string `json:"error,omitempty"`
 }
 
-// cleanupUploadedFiles 删除已上传的文件
+// parseNoteLocation 解析可选的 latitude/longitude 表单参数，两者需同时提供，都为空时返回 nil
+func parseNoteLocation(ctx *gin.Context) (*float64, *float64, error) {
+	lat, lng := ctx.PostForm("latitude"), ctx.PostForm("longitude")
+	if lat == "" && lng == "" {
+		return nil, nil, nil
+	}
+	point, err := parseGeoPoint(lat, lng)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &point.Lat, &point.Lng, nil
+}
+
+// cleanupUploadedFiles 删除已上传的文件及其登记记录，失败的文件会记录日志
 func cleanupUploadedFiles(urls []string) {
-	for _, url := range urls {
-		if err := oss.DeleteFileFromAliyunOss(url); err != nil {
-			log.Printf("删除文件失败: %v", err)
-		}
+	media.Remove(urls)
+}
+
+// registerMedia 登记服务端上传的媒体文件，发布笔记时据此确认文件归属
+func registerMedia(ownerID uint, url, kind, contentType string, size int64, hash string) {
+	if err := media.Register(ownerID, url, kind, contentType, size, hash); err != nil {
+		log.Printf("登记媒体文件 %s 失败: %v", url, err)
+	}
+}
+
+// fileHash 计算上传文件的 SHA-256，失败时返回空字符串
+func fileHash(file *multipart.FileHeader) string {
+	src, err := file.Open()
+	if err != nil {
+		log.Printf("读取上传文件失败: %v", err)
+		return ""
 	}
+	defer src.Close()
+	hash, err := media.Hash(src)
+	if err != nil {
+		log.Printf("计算文件哈希失败: %v", err)
+		return ""
+	}
+	return hash
 }
 
-// DeleteUploadedFile 删除上传到OSS的文件
+// respondMediaError 返回媒体文件校验失败的响应
+func respondMediaError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrUnconfirmed) || errors.Is(err, media.ErrInvalidType):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  err.Error(),
+		})
+	case errors.Is(err, media.ErrNotOwned):
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"status": "失败",
+			"code":   403,
+			"error":  err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "校验媒体文件失败",
+		})
+	}
+}
+
+// isMediaRejected 媒体文件是否因未确认、类型不符或归属不对被拒绝
+func isMediaRejected(err error) bool {
+	return errors.Is(err, media.ErrUnconfirmed) || errors.Is(err, media.ErrInvalidType) || errors.Is(err, media.ErrNotOwned)
+}
+
+// DeleteUploadedFile 删除上传到对象存储、尚未被笔记使用的文件，只能删除自己上传的文件
 func DeleteUploadedFile(ctx *gin.Context) {
 	// 获取请求参数
 	fileURL := ctx.Query("file_url") // 文件的URL
-	if fileURL == "" {
+	uid, err := strconv.ParseUint(ctx.Query("uid"), 10, 64)
+	if fileURL == "" || err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"status": "失败",
 			"code":   400,
-			"error":  "缺少必要参数 file_url",
+			"error":  "缺少必要参数 file_url 或 uid",
 		})
 		return
 	}
 
-	// 调用OSS删除文件的方法
-	err := oss.DeleteFileFromAliyunOss(fileURL)
-	if err != nil {
+	// 已被笔记引用或不是本人上传的文件不能删除
+	if err := media.CheckRemovable(uint(uid), fileURL); err != nil {
+		if errors.Is(err, media.ErrNotOwned) {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"status": "失败",
+				"code":   403,
+				"error":  err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// 从对象存储删除文件，图片的中图和缩略图一并删除
+	if err := media.Remove([]string{fileURL}); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -156,33 +244,40 @@ func DeleteUploadedFile(ctx *gin.Context) {
 	})
 }
 
-// UploadNotePic 上传单张笔记图片至 OSS
+// UploadNotePic 上传单张笔记图片，处理后返回原图、中图和缩略图的 URL；图片登记在上传者名下，发布笔记时只能使用自己上传的图片
 func UploadNotePic(ctx *gin.Context) {
-	// 获取上传的文件
-	file, err := ctx.FormFile("file")
+	uid, err := strconv.ParseUint(ctx.PostForm("uid"), 10, 64)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"status": "失败",
 			"code":   400,
-			"error":  "未找到文件或请求格式不正确",
+			"error":  "缺少必要参数 uid",
 		})
 		return
 	}
 
-	// 检查文件扩展名是否支持
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".webp" {
+	// 获取上传的文件
+	file, err := ctx.FormFile("file")
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"status": "失败",
 			"code":   400,
-			"error":  "不支持的文件类型",
+			"error":  "未找到文件或请求格式不正确",
 		})
 		return
 	}
 
-	// 上传文件到 OSS
-	url, err := oss.UploadFileToAliyunOss(file, "note_pics")
+	// 按内容校验格式，处理后连同中图、缩略图一起上传
+	image, err := imaging.Upload(file, "note_pics")
 	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupported) || errors.Is(err, imaging.ErrTooLarge) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"status": "失败",
+				"code":   400,
+				"error":  err.Error(),
+			})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -191,11 +286,17 @@ func UploadNotePic(ctx *gin.Context) {
 		return
 	}
 
-	// 返回上传成功的 URL
+	registerMedia(uint(uid), image.URL, models.MediaKindImage, image.ContentType, image.Size, image.Hash)
+
+	// 返回原图 URL、尺寸和各规格的 URL
 	ctx.JSON(http.StatusOK, gin.H{
-		"status": "成功",
-		"code":   200,
-		"url":    url,
+		"status":        "成功",
+		"code":          200,
+		"url":           image.URL,
+		"width":         image.Width,
+		"height":        image.Height,
+		"medium_url":    image.MediumURL,
+		"thumbnail_url": image.ThumbnailURL,
 	})
 }
 
@@ -251,6 +352,24 @@ func PublishNoteWithPics(ctx *gin.Context) {
 		return
 	}
 
+	// 图片必须是本人上传、已确认且没有被其他笔记使用的文件
+	picURLs := utils.ParseNoteURLs(noteURLs)
+	if err := media.CheckOwned(uint(creatorID), 0, models.MediaKindImage, picURLs); err != nil {
+		respondMediaError(ctx, err)
+		return
+	}
+
+	// 可选的位置信息
+	latitude, longitude, err := parseNoteLocation(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  err.Error(),
+		})
+		return
+	}
+
 	// 创建 Note 记录
 	isFindingBuddyInt, _ := strconv.Atoi(isFindingBuddy)
 	note := models.Note{
@@ -264,10 +383,27 @@ func PublishNoteWithPics(ctx *gin.Context) {
 		NoteUpdateTime:   time.Now().Unix(),
 		IsFindingBuddy:   isFindingBuddyInt,
 		BuddyDescription: buddyDescription,
+		Latitude:         latitude,
+		Longitude:        longitude,
 	}
 
-	// 保存 Note 到数据库
-	if err := global.Db.Create(&note).Error; err != nil {
+	// 保存笔记、更新用户的 NoteCount 并记下引用的媒体文件，在同一事务中完成，
+	// 媒体文件在提前检查后被其他笔记抢先引用时整体回滚
+	if err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).
+			Where("user_id = ?", creatorID).
+			Update("note_count", gorm.Expr("note_count + ?", 1)).Error; err != nil {
+			return err
+		}
+		return media.Claim(tx, uint(creatorID), note.NoteID, models.MediaKindImage, picURLs)
+	}); err != nil {
+		if isMediaRejected(err) {
+			respondMediaError(ctx, err)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -276,19 +412,6 @@ func PublishNoteWithPics(ctx *gin.Context) {
 		return
 	}
 
-	// 更新用户的 NoteCount 字段
-	if err := global.Db.Model(&models.User{}).
-		Where("user_id = ?", creatorID).
-		Update("note_count", gorm.Expr("note_count + ?", 1)).Error; err != nil {
-		global.Db.Delete(&note)
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"status": "失败",
-			"code":   500,
-			"error":  "更新用户 NoteCount 失败",
-		})
-		return
-	}
-
 	// 处理 Tag 和 TagNoteRelation
 	tags := strings.Split(noteTagList, ",")
 	for _, tagName := range tags {
@@ -318,11 +441,19 @@ func PublishNoteWithPics(ctx *gin.Context) {
 		global.Db.Create(&relation)
 	}
 
+	search.IndexNote(note)
+	hotscore.MarkDirty(note.NoteID)
+	timeline.Publish(note)
+
+	// 解析正文中的 @ 并通知被提及的用户
+	mentions := syncMentions("note", note.NoteID, note.NoteCreatorID, note.NoteContent, NoteNotificationTarget(note))
+
 	// 成功响应
 	ctx.JSON(http.StatusOK, gin.H{
-		"status": "成功",
-		"code":   200,
-		"nid":    note.NoteID,
+		"status":   "成功",
+		"code":     200,
+		"nid":      note.NoteID,
+		"mentions": mentions,
 	})
 }
 
@@ -357,6 +488,17 @@ func UpdateNoteWithPics(ctx *gin.Context) {
 		return
 	}
 
+	// 可选的位置信息
+	latitude, longitude, locErr := parseNoteLocation(ctx)
+	if locErr != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  locErr.Error(),
+		})
+		return
+	}
+
 	// 根据 NoteID 查找笔记
 	var note models.Note
 	if err := global.Db.First(&note, "note_id = ?", noteID).Error; err != nil {
@@ -368,6 +510,31 @@ func UpdateNoteWithPics(ctx *gin.Context) {
 		return
 	}
 
+	// 存下旧的文件urls
+	var oldURLs []string
+	if err := json.Unmarshal([]byte(note.NoteURLs), &oldURLs); err != nil {
+		ctx.JSON(http.StatusInternalServerError, UpdateNoteResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "笔记更新失败:" + err.Error(),
+		})
+		return
+	}
+
+	// 先校验图片再改动 Tag，校验不通过时笔记保持原样。
+	// 保留的图片：原笔记中的图片或本人上传的新图片，与本次上传的文件合并为新的图片列表
+	keptURLs := utils.ParseNoteURLs(ctx.PostForm("note_urls"))
+	var addedURLs []string
+	for _, url := range keptURLs {
+		if !slices.Contains(oldURLs, url) {
+			addedURLs = append(addedURLs, url)
+		}
+	}
+	if err := media.CheckOwned(note.NoteCreatorID, note.NoteID, models.MediaKindImage, addedURLs); err != nil {
+		respondMediaError(ctx, err)
+		return
+	}
+
 	// 更新 Tag 和 TagNoteRelation
 	tagList := strings.Split(noteTagList, ",") // 新的 tag 列表
 
@@ -463,45 +630,37 @@ func UpdateNoteWithPics(ctx *gin.Context) {
 		note.BuddyDescription = buddyDescription
 	}
 
-	// 存下旧的文件urls
-	var oldURLs []string
-	if err := json.Unmarshal([]byte(note.NoteURLs), &oldURLs); err != nil {
-		ctx.JSON(http.StatusInternalServerError, UpdateNoteResponse{
-			Status: "失败",
-			Code:   500,
-			Error:  "笔记更新失败:" + err.Error(),
-		})
-		return
-	}
-
 	// 上传新文件
 	files := ctx.Request.MultipartForm.File["files"]
 	var newUploadedURLs []string
+	var newImages []imaging.Uploaded
 	for _, file := range files {
-		ext := strings.ToLower(filepath.Ext(file.Filename))
-		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".webp" {
-			ctx.JSON(http.StatusBadRequest, gin.H{
-				"status": "失败",
-				"code":   400,
-				"error":  "笔记更新文件不支持的文件类型",
-			})
-			return
-		}
-
-		url, err := oss.UploadFileToAliyunOss(file, "note_pics")
+		image, err := imaging.Upload(file, "note_pics")
 		if err != nil {
+			cleanupUploadedFiles(newUploadedURLs)
+			if errors.Is(err, imaging.ErrUnsupported) || errors.Is(err, imaging.ErrTooLarge) {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"status": "失败",
+					"code":   400,
+					"error":  "笔记更新文件" + err.Error(),
+				})
+				return
+			}
 			ctx.JSON(http.StatusInternalServerError, gin.H{
 				"status": "失败",
 				"code":   500,
-				"error":  "笔记更新文件上传至oss失败",
+				"error":  "笔记更新文件上传失败",
 			})
 			return
 		}
-		newUploadedURLs = append(newUploadedURLs, url)
+		registerMedia(note.NoteCreatorID, image.URL, models.MediaKindImage, image.ContentType, image.Size, image.Hash)
+		newUploadedURLs = append(newUploadedURLs, image.URL)
+		newImages = append(newImages, image)
 	}
 
 	// 更新 NoteURLs
-	noteURLsJSON, err := json.Marshal(newUploadedURLs)
+	noteURLs := append(keptURLs, newUploadedURLs...)
+	noteURLsJSON, err := json.Marshal(noteURLs)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
@@ -513,9 +672,22 @@ func UpdateNoteWithPics(ctx *gin.Context) {
 	note.NoteURLs = string(noteURLsJSON)
 
 	note.NoteUpdateTime = time.Now().Unix() // 更新时间戳
+	if latitude != nil {
+		note.Latitude, note.Longitude = latitude, longitude
+	}
 
-	// 保存到数据库
-	if err := global.Db.Save(&note).Error; err != nil {
+	// 保存笔记并记下新增图片的引用，在同一事务中完成
+	if err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&note).Error; err != nil {
+			return err
+		}
+		return media.Claim(tx, note.NoteCreatorID, note.NoteID, models.MediaKindImage, append(addedURLs, newUploadedURLs...))
+	}); err != nil {
+		cleanupUploadedFiles(newUploadedURLs)
+		if isMediaRejected(err) {
+			respondMediaError(ctx, err)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -524,17 +696,43 @@ func UpdateNoteWithPics(ctx *gin.Context) {
 		return
 	}
 
-	cleanupUploadedFiles(oldURLs) // 安心删除旧文件
+	// 记下新的引用后，安心删除不再使用的旧文件
+	var removedURLs []string
+	for _, url := range oldURLs {
+		if !slices.Contains(noteURLs, url) {
+			removedURLs = append(removedURLs, url)
+		}
+	}
+	cleanupUploadedFiles(removedURLs)
+
+	search.IndexNote(note)
+	hotscore.MarkDirty(note.NoteID)
+	timeline.Publish(note)
+
+	// 重新解析 @，只通知新增的被提及者
+	mentions := syncMentions("note", note.NoteID, note.NoteCreatorID, note.NoteContent, NoteNotificationTarget(note))
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"status": "成功",
-		"code":   200,
-		"urls":   newUploadedURLs,
+		"status":   "成功",
+		"code":     200,
+		"urls":     noteURLs,
+		"images":   newImages,
+		"mentions": mentions,
 	})
 }
 
-// UploadNoteVideo 上传视频到OSS并返回URL
+// UploadNoteVideo 上传视频到OSS并返回URL，视频登记在上传者名下
 func UploadNoteVideo(ctx *gin.Context) {
+	uid, err := strconv.ParseUint(ctx.PostForm("uid"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "缺少必要参数 uid",
+		})
+		return
+	}
+
 	// 获取上传的文件
 	videoFile, err := ctx.FormFile("file")
 	if err != nil {
@@ -566,7 +764,7 @@ func UploadNoteVideo(ctx *gin.Context) {
 	}
 
 	// 上传视频到OSS
-	videoURL, err := oss.UploadFileToAliyunOss(videoFile, "note_videos")
+	videoURL, err := storage.Upload(videoFile, "note_videos")
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
@@ -576,6 +774,8 @@ func UploadNoteVideo(ctx *gin.Context) {
 		return
 	}
 
+	registerMedia(uint(uid), videoURL, models.MediaKindVideo, videoFile.Header.Get("Content-Type"), videoFile.Size, fileHash(videoFile))
+
 	// 成功返回URL
 	ctx.JSON(http.StatusOK, gin.H{
 		"status": "成功",
@@ -637,6 +837,23 @@ func PublishNoteWithVideo(ctx *gin.Context) {
 		return
 	}
 
+	// 视频必须是本人上传、已确认且没有被其他笔记使用的文件
+	if err := media.CheckOwned(uint(creatorID), 0, models.MediaKindVideo, []string{videoURL}); err != nil {
+		respondMediaError(ctx, err)
+		return
+	}
+
+	// 可选的位置信息
+	latitude, longitude, err := parseNoteLocation(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  err.Error(),
+		})
+		return
+	}
+
 	// 创建 Note 记录
 	isFindingBuddyInt, _ := strconv.Atoi(isFindingBuddy)
 	note := models.Note{
@@ -650,10 +867,27 @@ func PublishNoteWithVideo(ctx *gin.Context) {
 		NoteUpdateTime:   time.Now().Unix(),
 		IsFindingBuddy:   isFindingBuddyInt,
 		BuddyDescription: buddyDescription,
+		Latitude:         latitude,
+		Longitude:        longitude,
 	}
 
-	// 保存 Note 到数据库
-	if err := global.Db.Create(&note).Error; err != nil {
+	// 保存笔记、更新用户的 NoteCount 并记下引用的媒体文件，在同一事务中完成，
+	// 媒体文件在提前检查后被其他笔记抢先引用时整体回滚
+	if err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).
+			Where("user_id = ?", creatorID).
+			Update("note_count", gorm.Expr("note_count + ?", 1)).Error; err != nil {
+			return err
+		}
+		return media.Claim(tx, uint(creatorID), note.NoteID, models.MediaKindVideo, []string{videoURL})
+	}); err != nil {
+		if isMediaRejected(err) {
+			respondMediaError(ctx, err)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -662,19 +896,6 @@ func PublishNoteWithVideo(ctx *gin.Context) {
 		return
 	}
 
-	// 更新用户的 NoteCount 字段
-	if err := global.Db.Model(&models.User{}).
-		Where("user_id = ?", creatorID).
-		Update("note_count", gorm.Expr("note_count + ?", 1)).Error; err != nil {
-		global.Db.Delete(&note)
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"status": "失败",
-			"code":   500,
-			"error":  "更新用户 NoteCount 失败",
-		})
-		return
-	}
-
 	// 处理 Tag 和 TagNoteRelation
 	tags := strings.Split(noteTagList, ",")
 	for _, tagName := range tags {
@@ -704,11 +925,19 @@ func PublishNoteWithVideo(ctx *gin.Context) {
 		global.Db.Create(&relation)
 	}
 
+	search.IndexNote(note)
+	hotscore.MarkDirty(note.NoteID)
+	timeline.Publish(note)
+
+	// 解析正文中的 @ 并通知被提及的用户
+	mentions := syncMentions("note", note.NoteID, note.NoteCreatorID, note.NoteContent, NoteNotificationTarget(note))
+
 	// 成功响应
 	ctx.JSON(http.StatusOK, gin.H{
-		"status": "成功",
-		"code":   200,
-		"nid":    note.NoteID,
+		"status":   "成功",
+		"code":     200,
+		"nid":      note.NoteID,
+		"mentions": mentions,
 	})
 }
 
@@ -743,6 +972,17 @@ func UpdateNoteWithVideo(ctx *gin.Context) {
 		return
 	}
 
+	// 可选的位置信息
+	latitude, longitude, locErr := parseNoteLocation(ctx)
+	if locErr != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  locErr.Error(),
+		})
+		return
+	}
+
 	// 根据 NoteID 查找笔记
 	var note models.Note
 	if err := global.Db.First(&note, "note_id = ?", noteID).Error; err != nil {
@@ -754,6 +994,27 @@ func UpdateNoteWithVideo(ctx *gin.Context) {
 		return
 	}
 
+	// 存下旧的文件urls
+	var oldURLs []string
+	if err := json.Unmarshal([]byte(note.NoteURLs), &oldURLs); err != nil {
+		ctx.JSON(http.StatusInternalServerError, UpdateNoteResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "笔记更新失败:" + err.Error(),
+		})
+		return
+	}
+
+	// 先校验视频再改动 Tag，校验不通过时笔记保持原样。
+	// 已通过断点续传或直传上传的视频直接使用，必须是本人上传且没有被其他笔记使用的文件
+	videoURL := ctx.PostForm("video_url")
+	if videoURL != "" && !slices.Contains(oldURLs, videoURL) {
+		if err := media.CheckOwned(note.NoteCreatorID, note.NoteID, models.MediaKindVideo, []string{videoURL}); err != nil {
+			respondMediaError(ctx, err)
+			return
+		}
+	}
+
 	// 更新 Tag 和 TagNoteRelation
 	tagList := strings.Split(noteTagList, ",") // 新的 tag 列表
 
@@ -849,67 +1110,60 @@ func UpdateNoteWithVideo(ctx *gin.Context) {
 		note.BuddyDescription = buddyDescription
 	}
 
-	// 存下旧的文件urls
-	var oldURLs []string
-	if err := json.Unmarshal([]byte(note.NoteURLs), &oldURLs); err != nil {
-		ctx.JSON(http.StatusInternalServerError, UpdateNoteResponse{
-			Status: "失败",
-			Code:   500,
-			Error:  "笔记更新失败:" + err.Error(),
-		})
-		return
-	}
-
-	// 处理文件
-	videoFile, err := ctx.FormFile("video_file")
-	if videoFile == nil {
-		ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Status: "失败",
-			Code:   400,
-			Error:  "没有上传文件，或者请求格式不正确",
-		})
-		return
-	}
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"status": "失败",
-			"code":   400,
-			"error":  "视频文件上传失败",
-		})
-		return
-	}
-	// 校验视频格式与大小
-	ext := strings.ToLower(filepath.Ext(videoFile.Filename))
-	if ext != ".mp4" && ext != ".mov" {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"status": "失败",
-			"code":   400,
-			"error":  "不支持的视频格式，仅支持 mp4 和 mov",
-		})
-		return
-	}
-	if videoFile.Size > 20*1024*1024*1024 { // 20GB
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"status": "失败",
-			"code":   400,
-			"error":  "视频文件大小超出限制，最大支持20GB",
-		})
-		return
-	}
-
 	var newVideoURLs []string
+	if videoURL != "" {
+		newVideoURLs = append(newVideoURLs, videoURL)
+	} else {
+		// 处理文件
+		videoFile, err := ctx.FormFile("video_file")
+		if videoFile == nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  "没有上传文件，或者请求格式不正确",
+			})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"status": "失败",
+				"code":   400,
+				"error":  "视频文件上传失败",
+			})
+			return
+		}
+		// 校验视频格式与大小
+		ext := strings.ToLower(filepath.Ext(videoFile.Filename))
+		if ext != ".mp4" && ext != ".mov" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"status": "失败",
+				"code":   400,
+				"error":  "不支持的视频格式，仅支持 mp4 和 mov",
+			})
+			return
+		}
+		if videoFile.Size > 20*1024*1024*1024 { // 20GB
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"status": "失败",
+				"code":   400,
+				"error":  "视频文件大小超出限制，最大支持20GB",
+			})
+			return
+		}
 
-	// 上传新视频文件到 OSS
-	newVideoURL, err := oss.UploadFileToAliyunOss(videoFile, "note_videos")
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"status": "失败",
-			"code":   500,
-			"error":  "视频上传失败",
-		})
-		return
+		// 上传新视频文件到 OSS
+		newVideoURL, err := storage.Upload(videoFile, "note_videos")
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"status": "失败",
+				"code":   500,
+				"error":  "视频上传失败",
+			})
+			return
+		}
+		registerMedia(note.NoteCreatorID, newVideoURL, models.MediaKindVideo, videoFile.Header.Get("Content-Type"), videoFile.Size, fileHash(videoFile))
+		newVideoURLs = append(newVideoURLs, newVideoURL)
 	}
-	newVideoURLs = append(newVideoURLs, newVideoURL)
 
 	// 更新 NoteURLs
 	noteURLsJSON, err := json.Marshal(newVideoURLs)
@@ -924,9 +1178,30 @@ func UpdateNoteWithVideo(ctx *gin.Context) {
 	note.NoteURLs = string(noteURLsJSON)
 
 	note.NoteUpdateTime = time.Now().Unix() // 更新时间戳
+	if latitude != nil {
+		note.Latitude, note.Longitude = latitude, longitude
+	}
 
-	// 保存到数据库
-	if err := global.Db.Save(&note).Error; err != nil {
+	// 保存笔记并记下新视频的引用，在同一事务中完成
+	var addedURLs []string
+	for _, url := range newVideoURLs {
+		if !slices.Contains(oldURLs, url) {
+			addedURLs = append(addedURLs, url)
+		}
+	}
+	if err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&note).Error; err != nil {
+			return err
+		}
+		return media.Claim(tx, note.NoteCreatorID, note.NoteID, models.MediaKindVideo, addedURLs)
+	}); err != nil {
+		if videoURL == "" {
+			cleanupUploadedFiles(newVideoURLs)
+		}
+		if isMediaRejected(err) {
+			respondMediaError(ctx, err)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"status": "失败",
 			"code":   500,
@@ -935,12 +1210,27 @@ func UpdateNoteWithVideo(ctx *gin.Context) {
 		return
 	}
 
-	cleanupUploadedFiles(oldURLs) // 安心删除旧文件
+	// 记下新的引用后，安心删除不再使用的旧文件
+	var removedURLs []string
+	for _, url := range oldURLs {
+		if !slices.Contains(newVideoURLs, url) {
+			removedURLs = append(removedURLs, url)
+		}
+	}
+	cleanupUploadedFiles(removedURLs)
+
+	search.IndexNote(note)
+	hotscore.MarkDirty(note.NoteID)
+	timeline.Publish(note)
+
+	// 重新解析 @，只通知新增的被提及者
+	mentions := syncMentions("note", note.NoteID, note.NoteCreatorID, note.NoteContent, NoteNotificationTarget(note))
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"status": "成功",
-		"code":   200,
-		"urls":   newVideoURLs,
+		"status":   "成功",
+		"code":     200,
+		"urls":     newVideoURLs,
+		"mentions": mentions,
 	})
 }
 
@@ -1023,6 +1313,9 @@ func DeleteNote(ctx *gin.Context) {
 			})
 			return
 		}
+		deleteMentions("note", []uint{note.NoteID})
+		search.RemoveNote(note.NoteID)
+		timeline.Remove(note.NoteID)
 
 		// 最后再删除oss笔记文件，调用 cleanupUploadedFiles 删除文件
 		var uploadedURLs []string
@@ -1071,7 +1364,7 @@ func GetFoNotes(ctx *gin.Context) {
 	// 获取请求参数
 	uid := ctx.Query("user_id")
 	num := ctx.Query("num")
-	cursor := ctx.Query("cursor") // 游标，用于分页（时间戳）
+	cursor := ctx.Query("cursor") // 游标，用于分页（上一页返回的 nextCursor）
 
 	// 参数校验
 	if uid == "" || num == "" {
@@ -1099,42 +1392,20 @@ func GetFoNotes(ctx *gin.Context) {
 		limit = n
 	}
 
-	// 查询用户关注的用户 ID
-	var followers []models.Follower
-	if err := global.Db.Where("uid = ?", userID).Find(&followers).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
+	// 游标为 (更新时间, 笔记 ID)，同一秒内更新的笔记也不会在翻页时漏掉
+	after, err := timeline.ParseCursor(cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
 			"success": false,
-			"msg":     "查询关注信息失败",
+			"msg":     "无效的游标参数",
 		})
 		return
 	}
 
-	// 获取关注的用户 ID 列表
-	var followedUserIDs []uint
-	for _, follower := range followers {
-		followedUserIDs = append(followedUserIDs, follower.Fid)
-	}
-
-	// 构造查询条件
-	query := global.Db.Where("note_creator_id IN ?", followedUserIDs)
-	if cursor != "" {
-		// 游标为时间戳（Unix 时间）
-		if timestamp, err := strconv.ParseInt(cursor, 10, 64); err == nil {
-			query = query.Where("note_update_time < ?", timestamp) // 返回最近更新的帖子，所以 <
-		} else {
-			ctx.JSON(http.StatusBadRequest, gin.H{
-				"code":    400,
-				"success": false,
-				"msg":     "无效的游标参数",
-			})
-			return
-		}
-	}
-
-	// 查询帖子数据
-	var notes []models.Note
-	if err := query.Order("note_update_time DESC").Limit(limit).Find(&notes).Error; err != nil {
+	// 从关注流时间线读取
+	notes, next, err := timeline.Read(uint(userID), after, limit)
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"success": false,
@@ -1145,11 +1416,8 @@ func GetFoNotes(ctx *gin.Context) {
 
 	// 构造返回结果
 	var responseNotes []gin.H
-	var nextCursor string
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -1164,19 +1432,11 @@ func GetFoNotes(ctx *gin.Context) {
 			"view_count":       note.ViewCount,
 			"is_finding_buddy": note.IsFindingBuddy,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"status":           states.Of(note),
 		})
 	}
 
-	// 设置下一个游标
-	if len(notes) > 0 {
-		nextCursor = strconv.FormatInt(notes[len(notes)-1].NoteUpdateTime, 10)
-	}
-
 	// 返回结果
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -1184,7 +1444,7 @@ func GetFoNotes(ctx *gin.Context) {
 		"msg":     "成功",
 		"data": gin.H{
 			"notes":      responseNotes,
-			"nextCursor": nextCursor, // 下次分页使用的游标
+			"nextCursor": next.String(), // 下次分页使用的游标
 		},
 	})
 }
@@ -1262,11 +1522,8 @@ func GetLikedNotes(ctx *gin.Context) {
 
 	var responseNotes []gin.H
 	var nextCursor string
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
-
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -1280,11 +1537,8 @@ func GetLikedNotes(ctx *gin.Context) {
 			"note_tag_list":    note.NoteTagList,
 			"view_count":       note.ViewCount,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"status":           states.Of(note),
 		})
 	}
 
@@ -1384,11 +1638,8 @@ func GetCollectedNotes(ctx *gin.Context) {
 	// 构造返回结果
 	var responseNotes []gin.H
 	var nextCursor string
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
-
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -1402,11 +1653,8 @@ func GetCollectedNotes(ctx *gin.Context) {
 			"note_tag_list":    note.NoteTagList,
 			"view_count":       note.ViewCount,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"status":           states.Of(note),
 		})
 	}
 
@@ -1462,6 +1710,9 @@ func GetNoteByID(ctx *gin.Context) {
 		})
 		return
 	}
+
+	// 记录浏览，窗口内重复浏览和作者本人浏览不计
+	views.Record(note.NoteID, note.NoteCreatorID, viewerFromRequest(ctx, ctx.Query("device_id")))
 	// 假设 noteURLs 是存储 JSON 字符串的字段
 	var noteURLs []string
 	if err := json.Unmarshal([]byte(note.NoteURLs), &noteURLs); err != nil {
@@ -1473,9 +1724,7 @@ func GetNoteByID(ctx *gin.Context) {
 		return
 	}
 
-	isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-	isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-	isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
+	status := utils.LoadViewerStates(userID, []models.Note{note}).Of(note)
 
 	// 返回笔记数据
 	ctx.JSON(http.StatusOK, gin.H{
@@ -1492,11 +1741,10 @@ func GetNoteByID(ctx *gin.Context) {
 		"like_counts":      uint(note.LikeCounts),
 		"collect_counts":   uint(int(note.CollectCounts)),
 		"note_urls":        noteURLs,
-		"status": gin.H{
-			"is_like":    isLike,
-			"is_collect": isCollect,
-			"is_follow":  isFollow,
-		},
+		"mentions":         loadMentionEntities("note", []uint{note.NoteID})[note.NoteID],
+		"latitude":         note.Latitude,
+		"longitude":        note.Longitude,
+		"status":           status,
 	})
 }
 
@@ -1578,10 +1826,8 @@ func GetNotesByCreatorID(ctx *gin.Context) {
 	// 构造返回结果
 	var responseNotes []gin.H
 	var nextCursor string
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -1595,11 +1841,8 @@ func GetNotesByCreatorID(ctx *gin.Context) {
 			"note_tag_list":    note.NoteTagList,
 			"view_count":       note.ViewCount,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"status":           states.Of(note),
 		})
 	}
 
@@ -1689,10 +1932,8 @@ func GetNotesByUpdateTime(ctx *gin.Context) {
 	// 构造返回结果
 	var responseNotes []gin.H
 	var nextCursor string
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -1706,11 +1947,8 @@ func GetNotesByUpdateTime(ctx *gin.Context) {
 			"note_tag_list":    note.NoteTagList,
 			"view_count":       note.ViewCount,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"status":           states.Of(note),
 		})
 	}
 
@@ -1796,10 +2034,8 @@ func GetNotesByLikes(ctx *gin.Context) {
 	// 构造返回结果
 	var responseNotes []gin.H
 	var nextCursor string
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -1813,11 +2049,8 @@ func GetNotesByLikes(ctx *gin.Context) {
 			"note_tag_list":    note.NoteTagList,
 			"view_count":       note.ViewCount,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"status":           states.Of(note),
 		})
 	}
 
@@ -1903,10 +2136,8 @@ func GetNotesByCollects(ctx *gin.Context) {
 	// 构造返回结果
 	var responseNotes []gin.H
 	var nextCursor string
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -1920,11 +2151,8 @@ func GetNotesByCollects(ctx *gin.Context) {
 			"note_tag_list":    note.NoteTagList,
 			"view_count":       note.ViewCount,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"status":           states.Of(note),
 		})
 	}
 
@@ -1961,23 +2189,21 @@ type RecommendationsData struct {
 
 // HotRecNoteResponse 笔记返回结构体
 type HotRecNoteResponse struct {
-	NoteID         uint    `json:"note_id"`
-	NoteTitle      string  `json:"note_title"`
-	NoteContent    string  `json:"note_content"`
-	LikeCounts     uint    `json:"like_counts"`
-	CollectCounts  uint    `json:"collect_counts"`
-	CommentCounts  uint    `json:"comment_counts"`
-	NoteCreatorID  uint    `json:"note_creator_id"`
-	NoteUpdateTime uint    `json:"note_update_time"`
-	ViewCount      uint    `json:"view_count"`
-	NoteTagList    string  `json:"note_tag_list"`
-	NoteURLs       string  `json:"note_urls"`
-	Score          float64 `json:"score"` // 热度分数
-	Status         struct {
-		IsLike    int `json:"is_like"`
-		IsCollect int `json:"is_collect"`
-		IsFollow  int `json:"is_follow"`
-	} `json:"status"`
+	NoteID         uint              `json:"note_id"`
+	NoteTitle      string            `json:"note_title"`
+	NoteContent    string            `json:"note_content"`
+	LikeCounts     uint              `json:"like_counts"`
+	CollectCounts  uint              `json:"collect_counts"`
+	CommentCounts  uint              `json:"comment_counts"`
+	NoteCreatorID  uint              `json:"note_creator_id"`
+	NoteUpdateTime uint              `json:"note_update_time"`
+	ViewCount      uint              `json:"view_count"`
+	NoteTagList    string            `json:"note_tag_list"`
+	NoteURLs       string            `json:"note_urls"`
+	Thumbnail      string            `json:"thumbnail"`      // 封面缩略图
+	Score          float64           `json:"score"`          // 热度分数
+	TrendingScore  float64           `json:"trending_score"` // 窗口热度分数
+	Status         utils.ViewerState `json:"status"`
 }
 
 // GetHotRecommendations 获取热度推荐
@@ -1985,7 +2211,8 @@ func GetHotRecommendations(ctx *gin.Context) {
 	// 获取请求参数
 	uid := ctx.Query("user_id")
 	numStr := ctx.Query("num")
-	cursorStr := ctx.Query("cursor") // 游标，用于分页（基于分数）
+	cursorStr := ctx.Query("cursor")        // 游标，用于分页（基于分数）
+	mode := ctx.DefaultQuery("mode", "hot") // hot：累计热度，trending：最近窗口内的热度
 
 	// 参数校验
 	if uid == "" || numStr == "" {
@@ -2020,8 +2247,25 @@ func GetHotRecommendations(ctx *gin.Context) {
 		return
 	}
 
+	scoreColumn := "score"
+	switch mode {
+	case "hot":
+	case "trending":
+		scoreColumn = "trending_score"
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"success": false,
+			"msg":     "无效的mode参数",
+		})
+		return
+	}
+
 	// 构造查询条件
 	query := global.Db.Model(&models.Note{})
+	if mode == "trending" {
+		query = query.Where("trending_score > 0")
+	}
 
 	// 如果有游标，添加过滤条件，只基于score进行分页
 	if cursorStr != "" {
@@ -2035,12 +2279,12 @@ func GetHotRecommendations(ctx *gin.Context) {
 			return
 		}
 		// 基于分数进行分页，score < 游标分数
-		query = query.Where("score <= ?", cursorScore)
+		query = query.Where(scoreColumn+" <= ?", cursorScore)
 	}
 
 	// 查询笔记数据，按分数降序排序
 	var notes []models.Note
-	if err := query.Order("score DESC").Limit(limit).Find(&notes).Error; err != nil {
+	if err := query.Order(scoreColumn + " DESC").Limit(limit).Find(&notes).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"success": false,
@@ -2051,10 +2295,8 @@ func GetHotRecommendations(ctx *gin.Context) {
 
 	// 构造返回结果
 	var responseNotes []HotRecNoteResponse
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
 		responseNotes = append(responseNotes, HotRecNoteResponse{
 			NoteID:         note.NoteID,
 			NoteTitle:      note.NoteTitle,
@@ -2067,12 +2309,10 @@ func GetHotRecommendations(ctx *gin.Context) {
 			ViewCount:      note.ViewCount,
 			NoteTagList:    note.NoteTagList,
 			NoteURLs:       note.NoteURLs,
+			Thumbnail:      utils.NoteThumbnail(note.NoteURLs),
 			Score:          note.Score,
-			Status: struct {
-				IsLike    int `json:"is_like"`
-				IsCollect int `json:"is_collect"`
-				IsFollow  int `json:"is_follow"`
-			}{isLike, isCollect, isFollow},
+			TrendingScore:  note.TrendingScore,
+			Status:         states.Of(note),
 		})
 	}
 
@@ -2080,7 +2320,11 @@ func GetHotRecommendations(ctx *gin.Context) {
 	var nextCursor string
 	if len(notes) == limit {
 		lastNote := notes[len(notes)-1]
-		nextCursor = strconv.FormatFloat(lastNote.Score, 'f', 6, 64) // 将最后一个笔记的score作为游标
+		lastScore := lastNote.Score
+		if mode == "trending" {
+			lastScore = lastNote.TrendingScore
+		}
+		nextCursor = strconv.FormatFloat(lastScore, 'f', 6, 64) // 将最后一个笔记的分数作为游标
 	}
 
 	// 构造响应
@@ -2181,10 +2425,8 @@ func GetNotesByTag(ctx *gin.Context) {
 	// 构造返回结果
 	var responseNotes []gin.H
 	var nextCursor string
+	states := utils.LoadViewerStates(userID, notes)
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -2198,11 +2440,8 @@ func GetNotesByTag(ctx *gin.Context) {
 			"note_tag_list":    note.NoteTagList,
 			"view_count":       note.ViewCount,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"status":           states.Of(note),
 		})
 	}
 
@@ -2223,15 +2462,26 @@ func GetNotesByTag(ctx *gin.Context) {
 	})
 }
 
+// GetNoteByKeywords 关键词全文检索笔记，支持过滤和多种排序，返回高亮标题、摘要和分面统计
+// 关键词为空时需至少提供一个过滤条件，此时按过滤条件浏览
 func GetNoteByKeywords(ctx *gin.Context) {
 	// 获取请求参数
 	uid := ctx.Query("user_id")
-	keyword := ctx.Query("keyword")
 	num := ctx.Query("num")
-	cursor := ctx.Query("cursor") // 游标，用于分页（笔记ID）
+	cursor := ctx.Query("cursor") // 游标，用于分页（上一页返回的 nextCursor）
+
+	req, err := parseSearchRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"success": false,
+			"msg":     err.Error(),
+		})
+		return
+	}
 
 	// 参数校验
-	if keyword == "" || num == "" || uid == "" {
+	if (req.Query == "" && !hasSearchFilter(req.Filter)) || num == "" || uid == "" {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"success": false,
@@ -2256,19 +2506,10 @@ func GetNoteByKeywords(ctx *gin.Context) {
 		limit = n
 	}
 
-	// 构造查询条件
-	query := global.Db.Table("notes").Where(
-		"note_content LIKE ? OR note_title LIKE ? OR note_tag_list LIKE ?",
-		"%"+keyword+"%", // 在内容中查找关键词
-		"%"+keyword+"%", // 在标题中查找关键词
-		"%"+keyword+"%", // 在标签列表中查找关键词
-	)
-
-	// 游标条件
-	if cursor != "" {
-		if noteIDCursor, err := strconv.Atoi(cursor); err == nil && noteIDCursor >= 0 {
-			query = query.Where("id < ?", noteIDCursor) // 返回ID较小的记录
-		} else {
+	// 全文检索，得到按排序方式排列的笔记 ID
+	result, err := search.Query(req, cursor, limit)
+	if err != nil {
+		if errors.Is(err, search.ErrInvalidCursor) {
 			ctx.JSON(http.StatusBadRequest, gin.H{
 				"code":    400,
 				"success": false,
@@ -2276,26 +2517,51 @@ func GetNoteByKeywords(ctx *gin.Context) {
 			})
 			return
 		}
-	}
-
-	// 查询笔记数据
-	var notes []models.Note
-	if err := query.Order("note_id DESC").Limit(limit).Find(&notes).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
 			"success": false,
-			"msg":     "查询笔记失败",
+			"msg":     err.Error(),
 		})
 		return
 	}
 
-	// 构造返回结果
-	var responseNotes []gin.H
-	var nextCursor string
+	// 首页请求计入搜索历史和热搜，翻页不重复记录
+	if cursor == "" && req.Query != "" {
+		if err := search.RecordQuery(uint(userID), req.Query); err != nil {
+			log.Printf("记录搜索历史失败: %v", err)
+		}
+	}
+
+	noteIDs := make([]uint, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		noteIDs = append(noteIDs, hit.ID)
+	}
+
+	// 查询笔记数据
+	var notes []models.Note
+	if len(noteIDs) > 0 {
+		if err := global.Db.Where("note_id IN ?", noteIDs).Find(&notes).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"success": false,
+				"msg":     "查询笔记失败",
+			})
+			return
+		}
+	}
+	noteMap := make(map[uint]models.Note, len(notes))
 	for _, note := range notes {
-		isLike := utils.CheckIfUserLiked(userID, int(note.NoteID))
-		isCollect := utils.CheckIfUserCollected(userID, int(note.NoteID))
-		isFollow := utils.CheckUserFollow(userID, int(note.NoteCreatorID))
+		noteMap[note.NoteID] = note
+	}
+
+	// 构造返回结果，保持检索结果的顺序
+	responseNotes := make([]gin.H, 0, len(result.Hits))
+	states := utils.LoadViewerStates(userID, notes)
+	for _, hit := range result.Hits {
+		note, ok := noteMap[hit.ID]
+		if !ok {
+			continue // 索引与数据库短暂不一致（刚被删除）
+		}
 		responseNotes = append(responseNotes, gin.H{
 			"note_id":          note.NoteID,
 			"note_title":       note.NoteTitle,
@@ -2309,18 +2575,16 @@ func GetNoteByKeywords(ctx *gin.Context) {
 			"note_tag_list":    note.NoteTagList,
 			"view_count":       note.ViewCount,
 			"note_urls":        note.NoteURLs,
-			"status": gin.H{
-				"is_like":    isLike,
-				"is_collect": isCollect,
-				"is_follow":  isFollow,
-			},
+			"thumbnail":        utils.NoteThumbnail(note.NoteURLs),
+			"highlight_title":  result.HighlightTitle(note.NoteTitle),
+			"snippet":          result.Snippet(note.NoteContent),
+			"relevance":        hit.Relevance,
+			"latitude":         note.Latitude,
+			"longitude":        note.Longitude,
+			"status":           states.Of(note),
 		})
 	}
-
-	// 设置下一个游标
-	if len(notes) > 0 {
-		nextCursor = strconv.Itoa(int(notes[len(notes)-1].NoteID)) // 使用最后一个笔记的ID作为游标
-	}
+	nextCursor := result.NextCursor
 
 	// 返回结果
 	ctx.JSON(http.StatusOK, gin.H{
@@ -2330,6 +2594,8 @@ func GetNoteByKeywords(ctx *gin.Context) {
 		"data": gin.H{
 			"notes":      responseNotes,
 			"nextCursor": nextCursor, // 下次分页使用的游标
+			"total":      result.Total,
+			"facets":     result.Facets,
 		},
 	})
 }
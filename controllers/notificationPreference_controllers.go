@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"time"
+	"travel-from-sysu-backend/digest"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/push"
+	"travel-from-sysu-backend/utils"
+)
+
+// notificationPreferenceTypes 可以单独设置接收方式的通知类型
+var notificationPreferenceTypes = []string{"comment", "like", "collect", "comment_like", "follow", "mention", "chat_mention"}
+
+// notificationModes 合法的接收方式
+var notificationModes = map[string]bool{
+	models.NotifyModeAll:       true,
+	models.NotifyModeFollowing: true,
+	models.NotifyModeDigest:    true,
+	models.NotifyModeOff:       true,
+}
+
+// QuietHoursRequest 免打扰设置
+type QuietHoursRequest struct {
+	Enabled  bool   `json:"enabled"`   // 是否开启
+	Start    string `json:"start"`     // 开始时间 HH:MM
+	End      string `json:"end"`       // 结束时间 HH:MM
+	TimeZone string `json:"time_zone"` // IANA 时区名，如 Asia/Shanghai
+}
+
+// UpdateNotificationPreferencesRequest 更新通知偏好请求结构，只更新传入的字段
+type UpdateNotificationPreferencesRequest struct {
+	Uid         uint               `json:"uid" binding:"required"` // 当前用户 ID
+	Preferences map[string]string  `json:"preferences"`            // 通知类型 -> 接收方式（all/following/digest/off）
+	QuietHours  *QuietHoursRequest `json:"quiet_hours"`            // 免打扰设置
+	Digest      *string            `json:"digest_frequency"`       // 未读通知摘要邮件：off/daily/weekly
+}
+
+// notificationDelivery 通知的投递决策
+type notificationDelivery struct {
+	Mode      string    // instant 实时推送 / digest 仅进入摘要
+	DeliverAt time.Time // 推送/邮件的最早投递时间
+}
+
+// pushStatus 新通知的初始推送状态，摘要方式的通知不推送到手机
+func (d notificationDelivery) pushStatus() string {
+	if d.Mode == models.NotifyModeDigest {
+		return push.StatusNone
+	}
+	return push.StatusPending
+}
+
+// defaultNotificationSetting 用户未设置时的默认通知设置
+func defaultNotificationSetting(userID uint) models.NotificationSetting {
+	return models.NotificationSetting{
+		UserID:          userID,
+		QuietStart:      "22:00",
+		QuietEnd:        "08:00",
+		TimeZone:        "Asia/Shanghai",
+		DigestFrequency: models.DigestOff,
+	}
+}
+
+// getNotificationSetting 读取用户的通知设置，没有记录时返回默认值
+func getNotificationSetting(userID uint) models.NotificationSetting {
+	setting := defaultNotificationSetting(userID)
+	global.Db.Where("user_id = ?", userID).Limit(1).Find(&setting)
+	return setting
+}
+
+// getNotificationMode 读取用户对某类通知的接收方式，没有记录时为 all
+func getNotificationMode(userID uint, notifType string) string {
+	var pref models.NotificationPreference
+	if err := global.Db.Where("user_id = ? AND type = ?", userID, notifType).First(&pref).Error; err != nil {
+		return models.NotifyModeAll
+	}
+	return pref.Mode
+}
+
+// notificationQuietUntil 用户当前处于免打扰时段时返回时段结束时刻，否则返回 now
+func notificationQuietUntil(userID uint, now time.Time) time.Time {
+	setting := getNotificationSetting(userID)
+	if !setting.QuietHoursEnabled {
+		return now
+	}
+	end, quiet := utils.QuietHoursEnd(now, setting.TimeZone, setting.QuietStart, setting.QuietEnd)
+	if !quiet {
+		return now
+	}
+	return end
+}
+
+// resolveNotificationDelivery 按接收者的偏好决定通知是否创建以及如何投递，所有通知创建都要先经过这里
+// 返回 false 表示不创建通知（自己触发自己、双方存在拉黑关系、关闭了该类型、或只接收关注的人而发起人不在关注列表中）
+func resolveNotificationDelivery(initiatorID uint, recipientID uint, notifType string) (notificationDelivery, bool) {
+	if initiatorID == recipientID || isBlockedBetween(initiatorID, recipientID) {
+		return notificationDelivery{}, false
+	}
+
+	delivery := notificationDelivery{Mode: "instant"}
+	switch getNotificationMode(recipientID, notifType) {
+	case models.NotifyModeOff:
+		return delivery, false
+	case models.NotifyModeFollowing:
+		var count int64
+		global.Db.Model(&models.Follower{}).Where("uid = ? AND fid = ?", recipientID, initiatorID).Count(&count)
+		if count == 0 {
+			return delivery, false
+		}
+	case models.NotifyModeDigest:
+		delivery.Mode = models.NotifyModeDigest
+	}
+
+	delivery.DeliverAt = notificationQuietUntil(recipientID, time.Now())
+	return delivery, true
+}
+
+// GetNotificationPreferences 获取用户的通知偏好和免打扰设置
+func GetNotificationPreferences(ctx *gin.Context) {
+	uid := ctx.Query("uid")
+	if uid == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "缺少 uid 参数",
+		})
+		return
+	}
+	userID, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "uid 参数格式错误",
+		})
+		return
+	}
+
+	var prefs []models.NotificationPreference
+	if err := global.Db.Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "查询通知偏好失败：" + err.Error(),
+		})
+		return
+	}
+
+	preferences := make(map[string]string, len(notificationPreferenceTypes))
+	for _, notifType := range notificationPreferenceTypes {
+		preferences[notifType] = models.NotifyModeAll
+	}
+	for _, pref := range prefs {
+		preferences[pref.Type] = pref.Mode
+	}
+
+	setting := getNotificationSetting(uint(userID))
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"preferences": preferences,
+			"quiet_hours": QuietHoursRequest{
+				Enabled:  setting.QuietHoursEnabled,
+				Start:    setting.QuietStart,
+				End:      setting.QuietEnd,
+				TimeZone: setting.TimeZone,
+			},
+			"digest_frequency": setting.DigestFrequency,
+		},
+	})
+}
+
+// validateQuietHours 校验免打扰设置
+func validateQuietHours(req *QuietHoursRequest) error {
+	if _, err := time.Parse("15:04", req.Start); err != nil {
+		return errors.New("免打扰开始时间格式应为 HH:MM")
+	}
+	if _, err := time.Parse("15:04", req.End); err != nil {
+		return errors.New("免打扰结束时间格式应为 HH:MM")
+	}
+	if _, err := time.LoadLocation(req.TimeZone); err != nil || req.TimeZone == "" {
+		return errors.New("无效的时区")
+	}
+	return nil
+}
+
+// UpdateNotificationPreferences 更新用户的通知偏好和免打扰设置
+func UpdateNotificationPreferences(ctx *gin.Context) {
+	var req UpdateNotificationPreferencesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	validTypes := make(map[string]bool, len(notificationPreferenceTypes))
+	for _, notifType := range notificationPreferenceTypes {
+		validTypes[notifType] = true
+	}
+	for notifType, mode := range req.Preferences {
+		if !validTypes[notifType] || !notificationModes[mode] {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  "无效的通知类型或接收方式：" + notifType + "=" + mode,
+			})
+			return
+		}
+	}
+	if req.Digest != nil && *req.Digest != models.DigestOff && *req.Digest != models.DigestDaily && *req.Digest != models.DigestWeekly {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "无效的摘要邮件频率：" + *req.Digest,
+		})
+		return
+	}
+	if req.QuietHours != nil {
+		if err := validateQuietHours(req.QuietHours); err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  err.Error(),
+			})
+			return
+		}
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		for notifType, mode := range req.Preferences {
+			pref := models.NotificationPreference{UserID: req.Uid, Type: notifType, Mode: mode}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"mode"}),
+			}).Create(&pref).Error; err != nil {
+				return err
+			}
+		}
+		if req.QuietHours == nil && req.Digest == nil {
+			return nil
+		}
+		setting := getNotificationSetting(req.Uid)
+		if req.QuietHours != nil {
+			setting.QuietHoursEnabled = req.QuietHours.Enabled
+			setting.QuietStart = req.QuietHours.Start
+			setting.QuietEnd = req.QuietHours.End
+			setting.TimeZone = req.QuietHours.TimeZone
+		}
+		if req.Digest != nil {
+			setting.DigestFrequency = *req.Digest
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&setting).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "更新通知偏好失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "更新成功",
+		"code":   200,
+	})
+}
+
+// UnsubscribeDigest 摘要邮件中的一键退订链接，凭签名免登录；GET 供浏览器点击，POST 供邮件客户端 RFC 8058 一键退订
+func UnsubscribeDigest(ctx *gin.Context) {
+	uid := ctx.Query("uid")
+	token := ctx.Query("token")
+	userID, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil || token == "" || !digest.VerifyUnsubscribeToken(uint(userID), token) {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Status: "失败",
+			Code:   403,
+			Error:  "退订链接无效",
+		})
+		return
+	}
+
+	if err := global.Db.Model(&models.NotificationSetting{}).
+		Where("user_id = ?", userID).
+		Update("digest_frequency", models.DigestOff).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "退订失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "已退订摘要邮件",
+		"code":   200,
+	})
+}
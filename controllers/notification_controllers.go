@@ -1,41 +1,335 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"travel-from-sysu-backend/config"
 	"travel-from-sysu-backend/global"
 	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/push"
+	"travel-from-sysu-backend/realtime"
+	"travel-from-sysu-backend/utils"
 )
 
+// NoteNotificationTarget 以笔记为目标的通知快照（点赞、收藏）
+func NoteNotificationTarget(note models.Note) models.NotificationTarget {
+	return models.NotificationTarget{
+		TargetType:   "note",
+		TargetID:     note.NoteID,
+		NoteID:       note.NoteID,
+		TitleExcerpt: utils.TruncateRunes(note.NoteTitle, 30),
+		Thumbnail:    utils.NoteThumbnail(note.NoteURLs),
+	}
+}
+
+// CommentNotificationTarget 以评论为目标的通知快照（评论、回复、评论点赞）
+func CommentNotificationTarget(comment models.Comments, note models.Note) models.NotificationTarget {
+	return models.NotificationTarget{
+		TargetType:     "comment",
+		TargetID:       comment.CommentId,
+		NoteID:         comment.NoteId,
+		TitleExcerpt:   utils.TruncateRunes(note.NoteTitle, 30),
+		CommentExcerpt: utils.TruncateRunes(comment.Content, 60),
+		Thumbnail:      utils.NoteThumbnail(note.NoteURLs),
+	}
+}
+
+// UserNotificationTarget 以用户为目标的通知（关注）
+func UserNotificationTarget(userID uint) models.NotificationTarget {
+	return models.NotificationTarget{
+		TargetType: "user",
+		TargetID:   userID,
+	}
+}
+
+// aggregatableNotificationTypes 会按目标聚合的通知类型
+var aggregatableNotificationTypes = map[string]bool{
+	"like":         true,
+	"collect":      true,
+	"comment_like": true,
+	"follow":       true,
+}
+
+// notificationAggregateWindow 通知聚合的时间窗口
+func notificationAggregateWindow() time.Duration {
+	if config.AppCongfig != nil && config.AppCongfig.Notification.AggregateWindowMinutes > 0 {
+		return time.Duration(config.AppCongfig.Notification.AggregateWindowMinutes) * time.Minute
+	}
+	return 24 * time.Hour
+}
+
+// notificationRecentActorLimit 聚合通知保留的最近参与者人数
+func notificationRecentActorLimit() int {
+	if config.AppCongfig != nil && config.AppCongfig.Notification.RecentActors > 0 {
+		return config.AppCongfig.Notification.RecentActors
+	}
+	return 3
+}
+
 // AddNotificationAndUpdateUnreadCount 添加通知记录并增加未读消息计数
-func AddNotificationAndUpdateUnreadCount(initiatorID uint, recipientID uint, notifType string) error {
+// 先按接收者的通知偏好决定是否创建、是否实时推送；
+// 点赞、收藏、关注等类型在时间窗口内会合并到同一目标的未读通知上，此时未读数不变
+func AddNotificationAndUpdateUnreadCount(initiatorID uint, recipientID uint, notifType string, target models.NotificationTarget) error {
+	delivery, ok := resolveNotificationDelivery(initiatorID, recipientID, notifType)
+	if !ok {
+		return nil
+	}
+	if aggregatableNotificationTypes[notifType] {
+		return addAggregatedNotification(initiatorID, recipientID, notifType, target, delivery)
+	}
+
 	// 创建通知记录
 	notification := models.Notification{
-		InitiatorID: initiatorID,
-		RecipientID: recipientID,
-		Type:        notifType,
-		InitiatedAt: time.Now(),
-		IsRead:      false,
+		InitiatorID:        initiatorID,
+		RecipientID:        recipientID,
+		Type:               notifType,
+		InitiatedAt:        time.Now(),
+		IsRead:             false,
+		AggregateCount:     1,
+		RecentActors:       fmt.Sprintf("[%d]", initiatorID),
+		Delivery:           delivery.Mode,
+		DeliverAt:          delivery.DeliverAt,
+		PushStatus:         delivery.pushStatus(),
+		NotificationTarget: target,
+	}
+
+	// 插入通知记录并更新未读消息计数，两者在同一事务中完成
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("user_id = ?", recipientID).
+			Update("unread_noti_count", gorm.Expr("unread_noti_count + ?", 1)).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// 实时推送新通知和未读数，摘要方式的通知只更新未读数
+	if delivery.Mode != models.NotifyModeDigest {
+		realtime.Publish(recipientID, realtime.EventNotification, notification)
+	}
+	pushUnreadCount(recipientID)
+	if notification.PushStatus == push.StatusPending {
+		push.Notify(notification.ID)
 	}
 
-	// 插入通知记录
-	if err := global.Db.Create(&notification).Error; err != nil {
+	return nil
+}
+
+// addAggregatedNotification 合并到窗口内同一目标的未读通知，没有则新建
+func addAggregatedNotification(initiatorID uint, recipientID uint, notifType string, target models.NotificationTarget, delivery notificationDelivery) error {
+	now := time.Now()
+	var notification models.Notification
+	created := false
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		// 窗口内还没有聚合通知时 FOR UPDATE 锁不到任何行，并发的两次点赞会各自新建一条；
+		// 先锁住接收者的用户行，同一接收者的聚合与撤回串行执行
+		if err := lockNotificationRecipient(tx, recipientID); err != nil {
+			return err
+		}
+		err := tx.
+			Where("recipient_id = ? AND type = ? AND target_type = ? AND target_id = ? AND is_read = ? AND initiated_at >= ?",
+				recipientID, notifType, target.TargetType, target.TargetID, false, now.Add(-notificationAggregateWindow())).
+			Order("id DESC").
+			First(&notification).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			notification = models.Notification{
+				InitiatorID:        initiatorID,
+				RecipientID:        recipientID,
+				Type:               notifType,
+				InitiatedAt:        now,
+				IsRead:             false,
+				Delivery:           delivery.Mode,
+				DeliverAt:          delivery.DeliverAt,
+				PushStatus:         delivery.pushStatus(),
+				NotificationTarget: target,
+			}
+			if err := tx.Create(&notification).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.User{}).
+				Where("user_id = ?", recipientID).
+				Update("unread_noti_count", gorm.Expr("unread_noti_count + ?", 1)).Error; err != nil {
+				return err
+			}
+			created = true
+		} else if err != nil {
+			return err
+		} else {
+			// 快照以最新一次为准
+			notification.NotificationTarget = target
+		}
+
+		actor := models.NotificationActor{
+			NotificationID: notification.ID,
+			ActorID:        initiatorID,
+			CreatedAt:      now,
+		}
+		if err := tx.Create(&actor).Error; err != nil {
+			return err
+		}
+		_, err = refreshAggregatedNotification(tx, &notification)
+		return err
+	})
+	if err != nil {
 		return err
 	}
 
-	// 更新未读消息计数
-	if err := global.Db.Model(&models.User{}).
+	if notification.Delivery != models.NotifyModeDigest {
+		realtime.Publish(recipientID, realtime.EventNotification, notification)
+	}
+	if created {
+		pushUnreadCount(recipientID)
+		if notification.PushStatus == push.StatusPending {
+			push.Notify(notification.ID)
+		}
+	}
+	return nil
+}
+
+// lockNotificationRecipient 锁住接收者的用户行，作为其聚合通知的互斥锁
+func lockNotificationRecipient(tx *gorm.DB, recipientID uint) error {
+	var user models.User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("user_id").
 		Where("user_id = ?", recipientID).
-		Update("unread_noti_count", gorm.Expr("unread_noti_count + ?", 1)).Error; err != nil {
+		Limit(1).
+		Find(&user).Error
+}
+
+// refreshAggregatedNotification 根据参与者记录重新计算聚合人数、最近参与者和最新发起人，返回剩余人数
+func refreshAggregatedNotification(tx *gorm.DB, notification *models.Notification) (int64, error) {
+	var count int64
+	if err := tx.Model(&models.NotificationActor{}).
+		Where("notification_id = ?", notification.ID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	var actors []models.NotificationActor
+	if err := tx.Where("notification_id = ?", notification.ID).
+		Order("id DESC").
+		Limit(notificationRecentActorLimit()).
+		Find(&actors).Error; err != nil {
+		return 0, err
+	}
+
+	actorIDs := make([]uint, 0, len(actors))
+	for _, actor := range actors {
+		actorIDs = append(actorIDs, actor.ActorID)
+	}
+	recentActors, _ := json.Marshal(actorIDs)
+
+	notification.AggregateCount = uint(count)
+	notification.RecentActors = string(recentActors)
+	notification.InitiatorID = actors[0].ActorID
+	notification.InitiatedAt = actors[0].CreatedAt
+	return count, tx.Model(&models.Notification{}).
+		Where("id = ?", notification.ID).
+		Updates(map[string]interface{}{
+			"aggregate_count": notification.AggregateCount,
+			"recent_actors":   notification.RecentActors,
+			"initiator_id":    notification.InitiatorID,
+			"initiated_at":    notification.InitiatedAt,
+			"target_type":     notification.TargetType,
+			"target_id":       notification.TargetID,
+			"note_id":         notification.NoteID,
+			"title_excerpt":   notification.TitleExcerpt,
+			"comment_excerpt": notification.CommentExcerpt,
+			"thumbnail":       notification.Thumbnail,
+		}).Error
+}
+
+// RetractNotification 撤回某人在聚合通知中的参与（取消点赞/取消收藏/取消关注），
+// 参与人数归零时删除整条通知，若通知未读则同步扣减未读数
+func RetractNotification(initiatorID uint, recipientID uint, notifType string, target models.NotificationTarget) error {
+	var notification models.Notification
+	removed := false
+	found := false
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		// 与 addAggregatedNotification 相同的加锁顺序：先用户行，再通知
+		if err := lockNotificationRecipient(tx, recipientID); err != nil {
+			return err
+		}
+		var actor models.NotificationActor
+		err := tx.Joins("JOIN notifications ON notifications.id = notification_actors.notification_id").
+			Where("notification_actors.actor_id = ? AND notifications.recipient_id = ? AND notifications.type = ? AND notifications.target_type = ? AND notifications.target_id = ?",
+				initiatorID, recipientID, notifType, target.TargetType, target.TargetID).
+			Order("notification_actors.id DESC").
+			First(&actor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		found = true
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&notification, "id = ?", actor.NotificationID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&actor).Error; err != nil {
+			return err
+		}
+
+		remaining, err := refreshAggregatedNotification(tx, &notification)
+		if err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return nil
+		}
+
+		removed = true
+		if err := tx.Delete(&models.Notification{}, notification.ID).Error; err != nil {
+			return err
+		}
+		if !notification.IsRead {
+			return decrementUnreadCount(tx, recipientID, 1)
+		}
+		return nil
+	})
+	if err != nil || !found {
 		return err
 	}
 
+	if removed {
+		realtime.Publish(recipientID, realtime.EventNotificationRemoved, gin.H{"id": notification.ID})
+		if !notification.IsRead {
+			pushUnreadCount(recipientID)
+		}
+	} else {
+		realtime.Publish(recipientID, realtime.EventNotification, notification)
+	}
 	return nil
 }
 
+// pushUnreadCount 向用户推送最新的未读消息数
+func pushUnreadCount(userID uint) {
+	var user models.User
+	if err := global.Db.Select("unread_noti_count").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return
+	}
+	realtime.Publish(userID, realtime.EventUnreadCount, gin.H{
+		"unread_noti_count": user.UnreadNotiCount,
+	})
+}
+
 // GetUnreadNotificationCount 获取用户未读消息数量
 func GetUnreadNotificationCount(ctx *gin.Context) {
 	uid := ctx.Query("uid")
@@ -129,34 +423,6 @@ func GetUnreadCommentNotifications(ctx *gin.Context) {
 		return
 	}
 
-	// 标记消息为已读
-	if len(notifications) > 0 {
-		ids := make([]uint, len(notifications))
-		for i, notification := range notifications {
-			ids[i] = notification.ID
-		}
-		// 更新用户表中的未读消息计数
-		if err := global.Db.Model(&models.User{}).
-			Where("user_id = ?", recipientIDUint).
-			Update("unread_noti_count", gorm.Expr("unread_noti_count - ?", len(ids))).Error; err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"status": "失败",
-				"code":   500,
-				"error":  "更新用户未读消息计数失败：" + err.Error(),
-			})
-			return
-		}
-		// 批量更新
-		if err := global.Db.Model(&models.Notification{}).Where("id IN ?", ids).Update("is_read", true).Error; err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"status": "失败",
-				"code":   500,
-				"error":  "更新消息状态失败：" + err.Error(),
-			})
-			return
-		}
-	}
-
 	// 获取下一页游标
 	nextCursor := ""
 	if len(notifications) > 0 {
@@ -204,7 +470,7 @@ func GetUnreadLikeAndCollectNotifications(ctx *gin.Context) {
 	}
 
 	// 构造查询
-	query := global.Db.Table("notifications").Where("recipient_id = ? AND type IN (?, ?) AND is_read = ?", recipientIDUint, "like", "collect", false)
+	query := global.Db.Table("notifications").Where("recipient_id = ? AND type IN (?, ?, ?) AND is_read = ?", recipientIDUint, "like", "collect", "comment_like", false)
 
 	// 如果提供了游标，则查询小于游标的消息
 	if cursor != "" {
@@ -224,34 +490,6 @@ func GetUnreadLikeAndCollectNotifications(ctx *gin.Context) {
 		return
 	}
 
-	// 标记消息为已读
-	if len(notifications) > 0 {
-		ids := make([]uint, len(notifications))
-		for i, notification := range notifications {
-			ids[i] = notification.ID
-		}
-		// 更新用户表中的未读消息计数
-		if err := global.Db.Model(&models.User{}).
-			Where("user_id = ?", recipientIDUint).
-			Update("unread_noti_count", gorm.Expr("unread_noti_count - ?", len(ids))).Error; err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"status": "失败",
-				"code":   500,
-				"error":  "更新用户未读消息计数失败：" + err.Error(),
-			})
-			return
-		}
-		// 批量更新
-		if err := global.Db.Model(&models.Notification{}).Where("id IN ?", ids).Update("is_read", true).Error; err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"status": "失败",
-				"code":   500,
-				"error":  "更新消息状态失败：" + err.Error(),
-			})
-			return
-		}
-	}
-
 	// 获取下一页游标
 	nextCursor := ""
 	if len(notifications) > 0 {
@@ -319,34 +557,6 @@ func GetNewFollowNotifications(ctx *gin.Context) {
 		return
 	}
 
-	// 标记消息为已读
-	if len(notifications) > 0 {
-		ids := make([]uint, len(notifications))
-		for i, notification := range notifications {
-			ids[i] = notification.ID
-		}
-		// 更新用户表中的未读消息计数
-		if err := global.Db.Model(&models.User{}).
-			Where("user_id = ?", recipientIDUint).
-			Update("unread_noti_count", gorm.Expr("unread_noti_count - ?", len(ids))).Error; err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"status": "失败",
-				"code":   500,
-				"error":  "更新用户未读消息计数失败：" + err.Error(),
-			})
-			return
-		}
-		// 批量更新
-		if err := global.Db.Model(&models.Notification{}).Where("id IN ?", ids).Update("is_read", true).Error; err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"status": "失败",
-				"code":   500,
-				"error":  "更新消息状态失败：" + err.Error(),
-			})
-			return
-		}
-	}
-
 	// 获取下一页游标
 	nextCursor := ""
 	if len(notifications) > 0 {
@@ -460,7 +670,7 @@ func GetReadLikeAndCollectNotifications(ctx *gin.Context) {
 	}
 
 	// 查询条件：获取已读的点赞和收藏消息
-	query := global.Db.Table("notifications").Where("recipient_id = ? AND type IN (?, ?) AND is_read = ?", recipientIDUint, "like", "collect", true)
+	query := global.Db.Table("notifications").Where("recipient_id = ? AND type IN (?, ?, ?) AND is_read = ?", recipientIDUint, "like", "collect", "comment_like", true)
 
 	if cursor != "" {
 		cursorID, err := strconv.Atoi(cursor)
@@ -560,3 +770,386 @@ func GetReadFollowNotifications(ctx *gin.Context) {
 		},
 	})
 }
+
+// notificationCategories 通知分类与通知类型的对应关系
+var notificationCategories = map[string][]string{
+	"comment":      {"comment"},
+	"like_collect": {"like", "collect", "comment_like"},
+	"follow":       {"follow"},
+	"mention":      {"mention", "chat_mention"},
+}
+
+// notificationTypeCategory 返回通知类型所属的分类
+func notificationTypeCategory(notifType string) string {
+	for category, types := range notificationCategories {
+		for _, t := range types {
+			if t == notifType {
+				return category
+			}
+		}
+	}
+	return "other"
+}
+
+// MarkNotificationsReadRequest 标记已读请求结构
+type MarkNotificationsReadRequest struct {
+	Uid uint   `json:"uid" binding:"required"` // 当前用户 ID
+	IDs []uint `json:"ids" binding:"required"` // 要标记的通知 ID 列表（单条时传一个即可）
+}
+
+// MarkAllNotificationsReadRequest 全部标记已读请求结构
+type MarkAllNotificationsReadRequest struct {
+	Uid      uint   `json:"uid" binding:"required"` // 当前用户 ID
+	Category string `json:"category"`               // 可选，只标记某一分类：comment/like_collect/follow/mention
+}
+
+// DeleteNotificationsRequest 删除通知请求结构
+type DeleteNotificationsRequest struct {
+	Uid uint   `json:"uid" binding:"required"` // 当前用户 ID
+	IDs []uint `json:"ids" binding:"required"` // 要删除的通知 ID 列表
+}
+
+// NotificationStateResponse 通知状态变更响应结构
+type NotificationStateResponse struct {
+	Status          string `json:"status"`
+	Code            int    `json:"code"`
+	Affected        int64  `json:"affected"`          // 实际变更的通知条数
+	UnreadNotiCount uint64 `json:"unread_noti_count"` // 变更后的未读数
+	Error           string `json:"error,omitempty"`
+}
+
+// decrementUnreadCount 在事务内减少用户未读数，不会减到 0 以下
+func decrementUnreadCount(tx *gorm.DB, userID uint, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	return tx.Model(&models.User{}).
+		Where("user_id = ?", userID).
+		Update("unread_noti_count", gorm.Expr("GREATEST(unread_noti_count, ?) - ?", n, n)).Error
+}
+
+// markNotificationsRead 将满足条件的未读通知标记为已读，并在同一事务中扣减未读数
+func markNotificationsRead(userID uint, scope func(*gorm.DB) *gorm.DB) (int64, error) {
+	var affected int64
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		result := scope(tx.Model(&models.Notification{}).
+			Where("recipient_id = ? AND is_read = ?", userID, false)).
+			Update("is_read", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		return decrementUnreadCount(tx, userID, affected)
+	})
+	return affected, err
+}
+
+// respondNotificationState 返回变更结果并推送最新未读数
+func respondNotificationState(ctx *gin.Context, userID uint, status string, affected int64) {
+	var user models.User
+	global.Db.Select("unread_noti_count").Where("user_id = ?", userID).First(&user)
+	if affected > 0 {
+		pushUnreadCount(userID)
+	}
+
+	ctx.JSON(http.StatusOK, NotificationStateResponse{
+		Status:          status,
+		Code:            200,
+		Affected:        affected,
+		UnreadNotiCount: user.UnreadNotiCount,
+	})
+}
+
+// MarkNotificationsRead 标记一条或多条通知为已读
+func MarkNotificationsRead(ctx *gin.Context) {
+	var req MarkNotificationsReadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, NotificationStateResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+	if len(req.IDs) == 0 {
+		ctx.JSON(http.StatusBadRequest, NotificationStateResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "ids 不能为空",
+		})
+		return
+	}
+
+	affected, err := markNotificationsRead(req.Uid, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN ?", req.IDs)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, NotificationStateResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "标记已读失败：" + err.Error(),
+		})
+		return
+	}
+
+	respondNotificationState(ctx, req.Uid, "成功", affected)
+}
+
+// MarkAllNotificationsRead 标记全部（或某一分类的）通知为已读
+func MarkAllNotificationsRead(ctx *gin.Context) {
+	var req MarkAllNotificationsReadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, NotificationStateResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	var types []string
+	if req.Category != "" {
+		var ok bool
+		if types, ok = notificationCategories[req.Category]; !ok {
+			ctx.JSON(http.StatusBadRequest, NotificationStateResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  "未知的通知分类",
+			})
+			return
+		}
+	}
+
+	affected, err := markNotificationsRead(req.Uid, func(db *gorm.DB) *gorm.DB {
+		if len(types) > 0 {
+			return db.Where("type IN ?", types)
+		}
+		return db
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, NotificationStateResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "标记已读失败：" + err.Error(),
+		})
+		return
+	}
+
+	respondNotificationState(ctx, req.Uid, "成功", affected)
+}
+
+// DeleteNotifications 删除通知，删除未读通知时同步扣减未读数
+func DeleteNotifications(ctx *gin.Context) {
+	var req DeleteNotificationsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, NotificationStateResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+	if len(req.IDs) == 0 {
+		ctx.JSON(http.StatusBadRequest, NotificationStateResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "ids 不能为空",
+		})
+		return
+	}
+
+	var affected int64
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		// 先统计要删除的未读通知数
+		var unread int64
+		if err := tx.Model(&models.Notification{}).
+			Where("recipient_id = ? AND id IN ? AND is_read = ?", req.Uid, req.IDs, false).
+			Count(&unread).Error; err != nil {
+			return err
+		}
+		result := tx.Where("recipient_id = ? AND id IN ?", req.Uid, req.IDs).Delete(&models.Notification{})
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		// 清理聚合通知的参与者记录（只会命中刚删掉的、属于当前用户的通知）
+		if err := tx.Where("notification_id IN ? AND notification_id NOT IN (?)", req.IDs,
+			tx.Model(&models.Notification{}).Select("id").Where("id IN ?", req.IDs)).
+			Delete(&models.NotificationActor{}).Error; err != nil {
+			return err
+		}
+		return decrementUnreadCount(tx, req.Uid, unread)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, NotificationStateResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "删除通知失败：" + err.Error(),
+		})
+		return
+	}
+
+	respondNotificationState(ctx, req.Uid, "删除成功", affected)
+}
+
+// GetNotificationInbox 统一消息收件箱，支持按分类/类型/已读状态过滤，按 ID 倒序游标分页
+func GetNotificationInbox(ctx *gin.Context) {
+	recipientID := ctx.Query("recipient_id")
+	category := ctx.Query("category")               // 可选，通知分类
+	typeFilter := ctx.Query("types")                // 可选，逗号分隔的通知类型，与 category 同时传入时取 types
+	readStatus := ctx.DefaultQuery("status", "all") // unread/read/all
+	cursor := ctx.Query("cursor")
+	num := ctx.DefaultQuery("num", "10")
+
+	if recipientID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "缺少 recipient_id 参数",
+		})
+		return
+	}
+
+	recipientIDUint, err := strconv.ParseUint(recipientID, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "recipient_id 参数格式错误",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(num)
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	query := global.Db.Model(&models.Notification{}).Where("recipient_id = ?", recipientIDUint)
+
+	var types []string
+	if typeFilter != "" {
+		for _, t := range strings.Split(typeFilter, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+	} else if category != "" {
+		var ok bool
+		if types, ok = notificationCategories[category]; !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"status": "失败",
+				"code":   400,
+				"error":  "未知的通知分类",
+			})
+			return
+		}
+	}
+	if len(types) > 0 {
+		query = query.Where("type IN ?", types)
+	}
+
+	switch readStatus {
+	case "unread":
+		query = query.Where("is_read = ?", false)
+	case "read":
+		query = query.Where("is_read = ?", true)
+	case "all":
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "status 参数只能是 unread/read/all",
+		})
+		return
+	}
+
+	if cursor != "" {
+		if cursorID, err := strconv.Atoi(cursor); err == nil {
+			query = query.Where("id < ?", cursorID)
+		}
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("id DESC").Limit(limit).Find(&notifications).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "查询消息失败：" + err.Error(),
+		})
+		return
+	}
+
+	nextCursor := ""
+	if len(notifications) == limit {
+		nextCursor = strconv.Itoa(int(notifications[len(notifications)-1].ID))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"notifications": notifications,
+			"next_cursor":   nextCursor,
+		},
+	})
+}
+
+// GetUnreadNotificationBreakdown 按分类统计未读消息数
+func GetUnreadNotificationBreakdown(ctx *gin.Context) {
+	uid := ctx.Query("uid")
+	if uid == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "缺少用户 ID",
+		})
+		return
+	}
+
+	userID, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "用户 ID 格式错误",
+		})
+		return
+	}
+
+	var rows []struct {
+		Type  string
+		Count int64
+	}
+	if err := global.Db.Model(&models.Notification{}).
+		Select("type, COUNT(*) AS count").
+		Where("recipient_id = ? AND is_read = ?", userID, false).
+		Group("type").
+		Scan(&rows).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "统计未读消息失败：" + err.Error(),
+		})
+		return
+	}
+
+	breakdown := make(map[string]int64)
+	for category := range notificationCategories {
+		breakdown[category] = 0
+	}
+	var total int64
+	for _, row := range rows {
+		breakdown[notificationTypeCategory(row.Type)] += row.Count
+		total += row.Count
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"total":      total,
+			"categories": breakdown,
+		},
+	})
+}
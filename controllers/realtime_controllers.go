@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/realtime"
+	"travel-from-sysu-backend/utils"
+)
+
+// authenticateRealtimeUser 从 Authorization 头或 token 查询参数（浏览器建立 WebSocket 时无法自定义请求头）解析当前用户
+func authenticateRealtimeUser(ctx *gin.Context) (uint, error) {
+	token := ctx.GetHeader("Authorization")
+	if token == "" {
+		token = ctx.Query("token")
+	}
+	if token == "" {
+		return 0, errors.New("缺少令牌")
+	}
+	return userIDFromToken(token)
+}
+
+// userIDFromToken 校验登录令牌并返回对应的用户 ID
+func userIDFromToken(token string) (uint, error) {
+	username, err := utils.ParseJWT(token)
+	if err != nil {
+		return 0, errors.New("令牌无效或已过期")
+	}
+
+	var user models.User
+	if err := global.Db.Select("user_id").Where("username = ?", username).First(&user).Error; err != nil {
+		return 0, errors.New("用户不存在")
+	}
+	return user.UserId, nil
+}
+
+// ServeRealtimeWebSocket 建立 WebSocket 推送连接，推送新通知、未读数变化和群聊消息
+func ServeRealtimeWebSocket(ctx *gin.Context) {
+	if realtime.DefaultHub == nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Status: "失败",
+			Code:   503,
+			Error:  "推送服务未启动",
+		})
+		return
+	}
+
+	userID, err := authenticateRealtimeUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Status: "失败",
+			Code:   401,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	// 升级失败时 upgrader 已经写回了错误响应
+	if err := realtime.DefaultHub.ServeWebSocket(ctx.Writer, ctx.Request, userID); err != nil {
+		log.Printf("WebSocket 升级失败: %v", err)
+	}
+}
+
+// ServeRealtimeSSE 建立 SSE 推送连接，作为 WebSocket 的降级方案
+func ServeRealtimeSSE(ctx *gin.Context) {
+	if realtime.DefaultHub == nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Status: "失败",
+			Code:   503,
+			Error:  "推送服务未启动",
+		})
+		return
+	}
+
+	userID, err := authenticateRealtimeUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Status: "失败",
+			Code:   401,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	if err := realtime.DefaultHub.ServeSSE(ctx.Writer, ctx.Request, userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  err.Error(),
+		})
+	}
+}
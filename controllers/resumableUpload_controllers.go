@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/resumable"
+)
+
+// tusVersion 兼容的 tus 协议版本
+const tusVersion = "1.0.0"
+
+// CreateVideoUploadRequest 创建视频断点续传会话的请求
+type CreateVideoUploadRequest struct {
+	Uid      uint   `json:"uid" binding:"required"`      // 上传者 ID
+	Filename string `json:"filename" binding:"required"` // 原始文件名，用于判断格式
+	Size     int64  `json:"size" binding:"required"`     // 文件总大小（字节）
+	Checksum string `json:"checksum"`                    // 可选，整个文件的 SHA-256（十六进制）
+}
+
+// uploadProgress 会话进度
+func uploadProgress(session *models.UploadSession) gin.H {
+	return gin.H{
+		"id":         session.ID,
+		"filename":   session.Filename,
+		"size":       session.Size,
+		"offset":     session.Offset,
+		"progress":   float64(session.Offset) / float64(session.Size),
+		"status":     session.Status,
+		"url":        session.URL,
+		"error":      session.Error,
+		"expires_at": session.ExpiresAt,
+	}
+}
+
+// setUploadHeaders 设置 tus 风格的进度响应头
+func setUploadHeaders(ctx *gin.Context, session *models.UploadSession) {
+	ctx.Header("Tus-Resumable", tusVersion)
+	ctx.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	ctx.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	ctx.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	ctx.Header("Cache-Control", "no-store")
+}
+
+// uploadErrorStatus 断点续传错误对应的 HTTP 状态码
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, resumable.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, resumable.ErrExpired):
+		return http.StatusGone
+	case errors.Is(err, resumable.ErrOffsetMismatch), errors.Is(err, resumable.ErrCompleted),
+		errors.Is(err, resumable.ErrAssembling), errors.Is(err, resumable.ErrFailed):
+		return http.StatusConflict
+	case errors.Is(err, resumable.ErrChecksumMismatch):
+		return 460 // tus 约定的 Checksum Mismatch
+	case errors.Is(err, resumable.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, resumable.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, resumable.ErrChecksumAlgorithm), errors.Is(err, resumable.ErrInvalidVideo),
+		errors.Is(err, resumable.ErrFileChecksum):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// CreateVideoUpload 创建视频断点续传会话，返回会话 ID 和上传地址
+func CreateVideoUpload(ctx *gin.Context) {
+	var req CreateVideoUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	var count int64
+	if err := global.Db.Model(&models.User{}).Where("user_id = ?", req.Uid).Count(&count).Error; err != nil || count == 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "用户不存在",
+		})
+		return
+	}
+
+	session, err := resumable.Create(req.Uid, req.Filename, req.Size, req.Checksum)
+	if err != nil {
+		status := uploadErrorStatus(err)
+		msg := err.Error()
+		if status == http.StatusInternalServerError {
+			msg = "创建上传会话失败"
+		}
+		ctx.JSON(status, ErrorResponse{
+			Status: "失败",
+			Code:   status,
+			Error:  msg,
+		})
+		return
+	}
+
+	location := "/api/upload/video/" + session.ID
+	setUploadHeaders(ctx, session)
+	ctx.Header("Location", location)
+	ctx.JSON(http.StatusCreated, gin.H{
+		"status": "成功",
+		"code":   201,
+		"data": gin.H{
+			"upload_url": location,
+			"session":    uploadProgress(session),
+			"chunk_size": 8 << 20, // 建议的分片大小，弱网下可以更小
+		},
+	})
+}
+
+// HeadVideoUpload 断线后查询已接收的字节数（tus HEAD）
+func HeadVideoUpload(ctx *gin.Context) {
+	session, err := resumable.Get(ctx.Param("id"))
+	if err != nil {
+		ctx.Header("Tus-Resumable", tusVersion)
+		ctx.Status(uploadErrorStatus(err))
+		return
+	}
+	setUploadHeaders(ctx, session)
+	ctx.Status(http.StatusOK)
+}
+
+// GetVideoUpload 查询上传进度
+func GetVideoUpload(ctx *gin.Context) {
+	session, err := resumable.Get(ctx.Param("id"))
+	if err != nil {
+		status := uploadErrorStatus(err)
+		ctx.JSON(status, ErrorResponse{
+			Status: "失败",
+			Code:   status,
+			Error:  err.Error(),
+		})
+		return
+	}
+	setUploadHeaders(ctx, session)
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data":   uploadProgress(session),
+	})
+}
+
+// PatchVideoUpload 从 Upload-Offset 处追加一个分片，可用 Upload-Checksum: sha256 <base64> 校验分片；
+// 最后一个分片写入后返回 202，文件在后台合并到对象存储，客户端轮询进度直到 status 为 completed 后取视频 URL；
+// status 为 failed 时需重新创建上传，合并写入存储失败时 status 退回 uploading，带着末尾偏移量重新 PATCH 即可重试
+func PatchVideoUpload(ctx *gin.Context) {
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "Upload-Offset 请求头格式不正确",
+		})
+		return
+	}
+
+	session, err := resumable.WriteChunk(ctx.Param("id"), offset, ctx.Request.Body, ctx.GetHeader("Upload-Checksum"))
+	if session != nil {
+		setUploadHeaders(ctx, session)
+	}
+	if err != nil {
+		status := uploadErrorStatus(err)
+		resp := gin.H{
+			"status": "失败",
+			"code":   status,
+			"error":  err.Error(),
+		}
+		if status == http.StatusInternalServerError {
+			resp["error"] = "分片写入失败，请查询进度后重试"
+		}
+		if session != nil {
+			resp["data"] = uploadProgress(session)
+		}
+		ctx.JSON(status, resp)
+		return
+	}
+
+	if session.Status == models.UploadStatusAssembling {
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"status": "成功",
+			"code":   202,
+			"data":   uploadProgress(session),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data":   uploadProgress(session),
+	})
+}
+
+// DeleteVideoUpload 取消上传并删除已接收的数据
+func DeleteVideoUpload(ctx *gin.Context) {
+	uid, err := strconv.ParseUint(ctx.Query("uid"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "uid 参数格式不正确",
+		})
+		return
+	}
+	if err := resumable.Abort(ctx.Param("id"), uint(uid)); err != nil {
+		status := uploadErrorStatus(err)
+		ctx.JSON(status, ErrorResponse{
+			Status: "失败",
+			Code:   status,
+			Error:  err.Error(),
+		})
+		return
+	}
+	ctx.Header("Tus-Resumable", tusVersion)
+	ctx.JSON(http.StatusOK, gin.H{
+		"status":  "成功",
+		"code":    200,
+		"message": "上传已取消",
+	})
+}
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"travel-from-sysu-backend/search"
+)
+
+// maxSearchRadiusKm 位置过滤允许的最大半径（千米）
+const maxSearchRadiusKm = 500
+
+// parseSearchRequest 解析检索参数：关键词、过滤条件和排序方式
+//
+//	note_type          笔记类型
+//	buddy_only=1       只看找旅伴帖子
+//	tags=a,b           需同时包含的标签
+//	author_id          作者
+//	from/to            更新时间范围（Unix 时间戳，含边界）
+//	lat/lng/radius_km  位置半径
+//	min_likes          最少点赞数
+//	sort               relevance/newest/likes/collects/hot
+func parseSearchRequest(ctx *gin.Context) (search.Request, error) {
+	req := search.Request{
+		Query: strings.TrimSpace(ctx.Query("keyword")),
+		Sort:  ctx.DefaultQuery("sort", search.SortRelevance),
+		Filter: search.Filter{
+			NoteType:  ctx.Query("note_type"),
+			BuddyOnly: ctx.Query("buddy_only") == "1" || ctx.Query("buddy_only") == "true",
+		},
+	}
+
+	validSort := false
+	for _, mode := range search.SortModes {
+		if req.Sort == mode {
+			validSort = true
+			break
+		}
+	}
+	if !validSort {
+		return req, errors.New("sort 参数无效")
+	}
+
+	if tags := ctx.Query("tags"); tags != "" {
+		req.Filter.Tags = strings.Split(tags, ",")
+	}
+
+	if author := ctx.Query("author_id"); author != "" {
+		id, err := strconv.ParseUint(author, 10, 64)
+		if err != nil {
+			return req, errors.New("author_id 参数格式不正确")
+		}
+		req.Filter.AuthorID = uint(id)
+	}
+
+	var err error
+	if req.Filter.From, err = parseOptionalInt64(ctx.Query("from")); err != nil {
+		return req, errors.New("from 参数格式不正确")
+	}
+	if req.Filter.To, err = parseOptionalInt64(ctx.Query("to")); err != nil {
+		return req, errors.New("to 参数格式不正确")
+	}
+	if req.Filter.From != 0 && req.Filter.To != 0 && req.Filter.From > req.Filter.To {
+		return req, errors.New("from 不能晚于 to")
+	}
+
+	if minLikes := ctx.Query("min_likes"); minLikes != "" {
+		n, err := strconv.Atoi(minLikes)
+		if err != nil || n < 0 {
+			return req, errors.New("min_likes 参数格式不正确")
+		}
+		req.Filter.MinLikes = n
+	}
+
+	lat, lng, radius := ctx.Query("lat"), ctx.Query("lng"), ctx.Query("radius_km")
+	if lat != "" || lng != "" || radius != "" {
+		point, err := parseGeoPoint(lat, lng)
+		if err != nil {
+			return req, err
+		}
+		r, err := strconv.ParseFloat(radius, 64)
+		if err != nil || r <= 0 || r > maxSearchRadiusKm {
+			return req, errors.New("radius_km 参数无效")
+		}
+		req.Filter.Near = &point
+		req.Filter.RadiusKm = r
+	}
+	return req, nil
+}
+
+// parseOptionalInt64 解析可选的整数参数，为空时返回 0
+func parseOptionalInt64(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// parseGeoPoint 解析并校验经纬度
+func parseGeoPoint(lat, lng string) (search.GeoPoint, error) {
+	latitude, err := strconv.ParseFloat(lat, 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		return search.GeoPoint{}, errors.New("纬度参数无效")
+	}
+	longitude, err := strconv.ParseFloat(lng, 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		return search.GeoPoint{}, errors.New("经度参数无效")
+	}
+	return search.GeoPoint{Lat: latitude, Lng: longitude}, nil
+}
+
+// hasSearchFilter 是否设置了任一过滤条件
+func hasSearchFilter(filter search.Filter) bool {
+	return filter.NoteType != "" || filter.BuddyOnly || len(filter.Tags) > 0 || filter.AuthorID != 0 ||
+		filter.From != 0 || filter.To != 0 || filter.Near != nil || filter.MinLikes > 0
+}
+
+// trendingWindows 热搜支持的统计窗口
+var trendingWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// DeleteSearchHistoryRequest 删除搜索历史请求，query 为空时清空全部
+type DeleteSearchHistoryRequest struct {
+	Uid   uint   `json:"uid" binding:"required"` // 当前用户 ID
+	Query string `json:"query"`                  // 要删除的查询
+}
+
+// UpdateSearchSettingRequest 搜索历史开关请求
+type UpdateSearchSettingRequest struct {
+	Uid            uint  `json:"uid" binding:"required"`             // 当前用户 ID
+	HistoryEnabled *bool `json:"history_enabled" binding:"required"` // 是否记录搜索历史，关闭时清空已有记录
+}
+
+// GetSearchHistory 获取最近搜索
+func GetSearchHistory(ctx *gin.Context) {
+	uid := ctx.Query("user_id")
+	num := ctx.DefaultQuery("num", "10")
+
+	userID, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "user_id 参数格式不正确",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(num)
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	histories, err := search.RecentSearches(uint(userID), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询搜索历史失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"history":         histories,
+			"history_enabled": search.HistoryEnabled(uint(userID)),
+		},
+	})
+}
+
+// DeleteSearchHistory 删除一条或全部搜索历史
+func DeleteSearchHistory(ctx *gin.Context) {
+	var req DeleteSearchHistoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	if err := search.DeleteHistory(req.Uid, req.Query); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "删除搜索历史失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+	})
+}
+
+// UpdateSearchSetting 开启或关闭搜索历史记录
+func UpdateSearchSetting(ctx *gin.Context) {
+	var req UpdateSearchSettingRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	if err := search.SetHistoryEnabled(req.Uid, *req.HistoryEnabled); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "更新搜索设置失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"history_enabled": *req.HistoryEnabled,
+		},
+	})
+}
+
+// SuggestSearchQueries 搜索框输入联想
+func SuggestSearchQueries(ctx *gin.Context) {
+	keyword := ctx.Query("keyword")
+	num := ctx.DefaultQuery("num", "10")
+	userID, _ := strconv.ParseUint(ctx.Query("user_id"), 10, 64)
+
+	limit, err := strconv.Atoi(num)
+	if err != nil || limit <= 0 || limit > 20 {
+		limit = 10
+	}
+
+	suggestions, err := search.Suggest(keyword, uint(userID), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询联想词失败：" + err.Error(),
+		})
+		return
+	}
+	if suggestions == nil {
+		suggestions = []search.Suggestion{}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"suggestions": suggestions,
+		},
+	})
+}
+
+// GetTrendingSearches 热搜榜，window 可选 1h/24h/7d
+func GetTrendingSearches(ctx *gin.Context) {
+	windowName := ctx.DefaultQuery("window", "24h")
+	num := ctx.DefaultQuery("num", "10")
+
+	window, ok := trendingWindows[windowName]
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "window 参数无效，可选 1h/24h/7d",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(num)
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	trending, err := search.Trending(window, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "统计热搜失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"window":   windowName,
+			"trending": trending,
+		},
+	})
+}
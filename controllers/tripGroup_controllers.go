@@ -0,0 +1,481 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/utils"
+)
+
+// CreateTripGroupRequest 创建旅伴群组请求结构
+type CreateTripGroupRequest struct {
+	NoteID    uint   `json:"note_id" binding:"required"` // 找旅伴帖子 ID
+	Uid       uint   `json:"uid" binding:"required"`     // 当前用户 ID（必须是帖子作者）
+	GroupName string `json:"group_name"`                 // 群组名称，默认使用帖子标题
+}
+
+// TripGroupMemberRequest 加入/退出旅伴群组请求结构
+type TripGroupMemberRequest struct {
+	GroupID uint `json:"group_id" binding:"required"` // 群组 ID
+	Uid     uint `json:"uid" binding:"required"`      // 当前用户 ID
+}
+
+// TripGroupResponse 群组操作响应结构
+type TripGroupResponse struct {
+	Status  string `json:"status"`
+	Code    int    `json:"code"`
+	GroupID uint   `json:"group_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// addGroupSystemMessage 在群聊中插入一条系统消息（成员变动等）
+func addGroupSystemMessage(tx *gorm.DB, groupID uint, content string) (*models.GroupMessage, error) {
+	message := models.GroupMessage{
+		GroupID:   groupID,
+		SenderID:  0,
+		MsgType:   "system",
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+	if err := tx.Create(&message).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// getGroupMember 查询用户在群组中的成员记录，不是成员时返回 gorm.ErrRecordNotFound
+func getGroupMember(groupID uint, uid uint) (*models.TripGroupMember, error) {
+	var member models.TripGroupMember
+	if err := global.Db.Where("group_id = ? AND uid = ?", groupID, uid).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// CreateTripGroup 为找旅伴帖子创建旅伴群组，帖子作者自动成为群主
+func CreateTripGroup(ctx *gin.Context) {
+	var req CreateTripGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, TripGroupResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	// 检查帖子是否存在且为找旅伴帖子
+	var note models.Note
+	if err := global.Db.First(&note, "note_id = ?", req.NoteID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, TripGroupResponse{
+			Status: "失败",
+			Code:   404,
+			Error:  "笔记不存在",
+		})
+		return
+	}
+	if note.IsFindingBuddy != 1 {
+		ctx.JSON(http.StatusBadRequest, TripGroupResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "只有找旅伴帖子可以创建群组",
+		})
+		return
+	}
+	if note.NoteCreatorID != req.Uid {
+		ctx.JSON(http.StatusForbidden, TripGroupResponse{
+			Status: "失败",
+			Code:   403,
+			Error:  "只有帖子作者可以创建群组",
+		})
+		return
+	}
+
+	// 一个帖子只能创建一个群组
+	var existingGroup models.TripGroup
+	if err := global.Db.Where("note_id = ?", req.NoteID).First(&existingGroup).Error; err == nil {
+		ctx.JSON(http.StatusBadRequest, TripGroupResponse{
+			Status:  "失败",
+			Code:    400,
+			GroupID: existingGroup.GroupID,
+			Error:   "该帖子已创建群组",
+		})
+		return
+	}
+
+	var owner models.User
+	if err := global.Db.First(&owner, "user_id = ?", req.Uid).Error; err != nil {
+		ctx.JSON(http.StatusBadRequest, TripGroupResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "用户不存在",
+		})
+		return
+	}
+
+	groupName := req.GroupName
+	if groupName == "" {
+		groupName = note.NoteTitle
+	}
+
+	group := models.TripGroup{
+		NoteID:      req.NoteID,
+		OwnerID:     req.Uid,
+		GroupName:   groupName,
+		MemberCount: 1,
+	}
+
+	// 创建群组、系统消息与群主成员记录放在同一事务中
+	var message *models.GroupMessage
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		var err error
+		message, err = addGroupSystemMessage(tx, group.GroupID, owner.Username+" 创建了群聊")
+		if err != nil {
+			return err
+		}
+		member := models.TripGroupMember{
+			GroupID:   group.GroupID,
+			Uid:       req.Uid,
+			JoinMsgID: message.MessageID,
+			JoinedAt:  time.Now(),
+		}
+		return tx.Create(&member).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, TripGroupResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "创建群组失败：" + err.Error(),
+		})
+		return
+	}
+
+	publishGroupMessage(message)
+
+	ctx.JSON(http.StatusOK, TripGroupResponse{
+		Status:  "创建成功",
+		Code:    200,
+		GroupID: group.GroupID,
+	})
+}
+
+// JoinTripGroup 加入旅伴群组，加入后只能看到入群之后的聊天记录
+func JoinTripGroup(ctx *gin.Context) {
+	var req TripGroupMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, TripGroupResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	var group models.TripGroup
+	if err := global.Db.First(&group, "group_id = ?", req.GroupID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, TripGroupResponse{
+			Status: "失败",
+			Code:   404,
+			Error:  "群组不存在",
+		})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.First(&user, "user_id = ?", req.Uid).Error; err != nil {
+		ctx.JSON(http.StatusBadRequest, TripGroupResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "用户不存在",
+		})
+		return
+	}
+
+	if _, err := getGroupMember(req.GroupID, req.Uid); err == nil {
+		ctx.JSON(http.StatusOK, TripGroupResponse{
+			Status:  "已在群组中",
+			Code:    200,
+			GroupID: req.GroupID,
+		})
+		return
+	}
+
+	var message *models.GroupMessage
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		message, err = addGroupSystemMessage(tx, req.GroupID, user.Username+" 加入了群聊")
+		if err != nil {
+			return err
+		}
+		member := models.TripGroupMember{
+			GroupID:   req.GroupID,
+			Uid:       req.Uid,
+			JoinMsgID: message.MessageID,
+			JoinedAt:  time.Now(),
+		}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.TripGroup{}).
+			Where("group_id = ?", req.GroupID).
+			Update("member_count", gorm.Expr("member_count + ?", 1)).Error
+	})
+	if err != nil {
+		// 并发加入时另一个请求已经插入了成员记录
+		if utils.IsDuplicateKey(err) {
+			ctx.JSON(http.StatusOK, TripGroupResponse{
+				Status:  "已在群组中",
+				Code:    200,
+				GroupID: req.GroupID,
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, TripGroupResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "加入群组失败：" + err.Error(),
+		})
+		return
+	}
+
+	publishGroupMessage(message)
+
+	ctx.JSON(http.StatusOK, TripGroupResponse{
+		Status:  "加入成功",
+		Code:    200,
+		GroupID: req.GroupID,
+	})
+}
+
+// LeaveTripGroup 退出旅伴群组，群主不能退出
+func LeaveTripGroup(ctx *gin.Context) {
+	var req TripGroupMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, TripGroupResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	var group models.TripGroup
+	if err := global.Db.First(&group, "group_id = ?", req.GroupID).Error; err != nil {
+		ctx.JSON(http.StatusNotFound, TripGroupResponse{
+			Status: "失败",
+			Code:   404,
+			Error:  "群组不存在",
+		})
+		return
+	}
+	if group.OwnerID == req.Uid {
+		ctx.JSON(http.StatusBadRequest, TripGroupResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "群主不能退出群组",
+		})
+		return
+	}
+
+	member, err := getGroupMember(req.GroupID, req.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, TripGroupResponse{
+			Status: "失败",
+			Code:   404,
+			Error:  "不在该群组中",
+		})
+		return
+	}
+
+	var user models.User
+	global.Db.Select("username").First(&user, "user_id = ?", req.Uid)
+
+	var message *models.GroupMessage
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		// 并发的两次退出只有删到成员记录的一方继续，避免人数重复扣减和重复的系统消息
+		result := tx.Delete(member)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.TripGroup{}).
+			Where("group_id = ?", req.GroupID).
+			Update("member_count", gorm.Expr("member_count - ?", 1)).Error; err != nil {
+			return err
+		}
+		var err error
+		message, err = addGroupSystemMessage(tx, req.GroupID, user.Username+" 退出了群聊")
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, TripGroupResponse{
+			Status: "失败",
+			Code:   404,
+			Error:  "不在该群组中",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, TripGroupResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "退出群组失败：" + err.Error(),
+		})
+		return
+	}
+
+	publishGroupMessage(message)
+
+	ctx.JSON(http.StatusOK, TripGroupResponse{
+		Status:  "退出成功",
+		Code:    200,
+		GroupID: req.GroupID,
+	})
+}
+
+// GetTripGroupInfo 获取群组信息，可通过 group_id 或 note_id 查询
+func GetTripGroupInfo(ctx *gin.Context) {
+	groupID := ctx.Query("group_id")
+	noteID := ctx.Query("note_id")
+
+	if groupID == "" && noteID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "缺少 group_id 或 note_id 参数",
+		})
+		return
+	}
+
+	query := global.Db.Model(&models.TripGroup{})
+	if groupID != "" {
+		query = query.Where("group_id = ?", groupID)
+	} else {
+		query = query.Where("note_id = ?", noteID)
+	}
+
+	var group models.TripGroup
+	if err := query.First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"status": "失败",
+				"code":   404,
+				"error":  "群组不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "查询群组失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data":   group,
+	})
+}
+
+// GetTripGroupMembers 获取群组成员列表，采用游标分页
+func GetTripGroupMembers(ctx *gin.Context) {
+	groupID := ctx.Query("group_id")
+	cursor := ctx.Query("cursor")
+	num := ctx.DefaultQuery("num", "30")
+
+	if groupID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"status": "失败",
+			"code":   400,
+			"error":  "缺少 group_id 参数",
+		})
+		return
+	}
+
+	limit := 30
+	if n, err := strconv.Atoi(num); err == nil && n > 0 && n < 30 {
+		limit = n
+	}
+
+	query := global.Db.Where("group_id = ?", groupID)
+	if cursor != "" {
+		if cursorID, err := strconv.Atoi(cursor); err == nil {
+			query = query.Where("id > ?", cursorID)
+		}
+	}
+
+	var members []models.TripGroupMember
+	if err := query.Order("id ASC").Limit(limit).Find(&members).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"status": "失败",
+			"code":   500,
+			"error":  "查询群组成员失败：" + err.Error(),
+		})
+		return
+	}
+
+	var userIDs []uint
+	for _, member := range members {
+		userIDs = append(userIDs, member.Uid)
+	}
+
+	var users []models.User
+	if len(userIDs) > 0 {
+		if err := global.Db.Where("user_id IN ?", userIDs).Find(&users).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"status": "失败",
+				"code":   500,
+				"error":  "查询用户信息失败：" + err.Error(),
+			})
+			return
+		}
+	}
+
+	// 按入群顺序映射为简化的用户信息
+	userMap := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		userMap[user.UserId] = user
+	}
+	var simplifiedUsers []SimplifiedUser
+	for _, member := range members {
+		user, ok := userMap[member.Uid]
+		if !ok {
+			continue
+		}
+		simplifiedUsers = append(simplifiedUsers, SimplifiedUser{
+			UserID:        user.UserId,
+			Name:          user.Username,
+			Description:   user.Description,
+			FanCount:      user.FanCount,
+			FollowerCount: user.FollowerCount,
+			Gender:        user.Gender,
+			Avatar:        user.Avatar,
+		})
+	}
+
+	nextCursor := ""
+	if len(members) > 0 {
+		nextCursor = strconv.Itoa(int(members[len(members)-1].ID))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"members":     simplifiedUsers,
+			"next_cursor": nextCursor,
+		},
+	})
+}
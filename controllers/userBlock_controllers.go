@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/timeline"
+)
+
+// BlockRequest 拉黑/取消拉黑请求结构
+type BlockRequest struct {
+	CurrentUserID uint `json:"current_user_id" binding:"required"` // 当前用户ID
+	TargetUserID  uint `json:"target_user_id" binding:"required"`  // 目标用户ID
+}
+
+// blockedUserIDs 与用户存在拉黑关系（任一方向）的用户 ID
+func blockedUserIDs(uid uint) []uint {
+	var blocks []models.UserBlock
+	global.Db.Where("uid = ? OR blocked_uid = ?", uid, uid).Find(&blocks)
+	ids := make([]uint, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Uid == uid {
+			ids = append(ids, b.BlockedUid)
+		} else {
+			ids = append(ids, b.Uid)
+		}
+	}
+	return ids
+}
+
+// isBlockedBetween 两个用户之间是否存在拉黑关系（任一方向）
+func isBlockedBetween(a, b uint) bool {
+	var count int64
+	global.Db.Model(&models.UserBlock{}).
+		Where("(uid = ? AND blocked_uid = ?) OR (uid = ? AND blocked_uid = ?)", a, b, b, a).
+		Count(&count)
+	return count > 0
+}
+
+// removeFollow 在事务中删除一条关注关系并更新双方计数，返回是否确实删除
+func removeFollow(tx *gorm.DB, uid, fid uint) (bool, error) {
+	result := tx.Where("uid = ? AND fid = ?", uid, fid).Delete(&models.Follower{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	if err := tx.Model(&models.User{}).Where("user_id = ?", fid).
+		Update("fan_count", gorm.Expr("fan_count - ?", result.RowsAffected)).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.User{}).Where("user_id = ?", uid).
+		Update("follower_count", gorm.Expr("follower_count - ?", result.RowsAffected)).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BlockUser 拉黑用户，同时解除双方的关注关系
+func BlockUser(ctx *gin.Context) {
+	var req BlockRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+	if req.CurrentUserID == req.TargetUserID {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "不能拉黑自己",
+		})
+		return
+	}
+
+	var unfollowed, unfollowedBy bool
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		block := models.UserBlock{Uid: req.CurrentUserID, BlockedUid: req.TargetUserID, CreatedAt: time.Now()}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error; err != nil {
+			return err
+		}
+		var err error
+		if unfollowed, err = removeFollow(tx, req.CurrentUserID, req.TargetUserID); err != nil {
+			return err
+		}
+		unfollowedBy, err = removeFollow(tx, req.TargetUserID, req.CurrentUserID)
+		return err
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "拉黑失败：" + err.Error(),
+		})
+		return
+	}
+
+	// 双方的时间线中不再出现对方的笔记
+	timeline.RemoveAuthor(req.CurrentUserID, req.TargetUserID)
+	timeline.RemoveAuthor(req.TargetUserID, req.CurrentUserID)
+
+	// 撤回已解除关注对应的关注通知，失败不影响拉黑
+	if unfollowed {
+		if err := RetractNotification(req.CurrentUserID, req.TargetUserID, "follow", UserNotificationTarget(req.TargetUserID)); err != nil {
+			log.Printf("撤回关注通知失败: %v", err)
+		}
+	}
+	if unfollowedBy {
+		if err := RetractNotification(req.TargetUserID, req.CurrentUserID, "follow", UserNotificationTarget(req.CurrentUserID)); err != nil {
+			log.Printf("撤回关注通知失败: %v", err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+	})
+}
+
+// UnblockUser 取消拉黑，不会恢复之前解除的关注关系
+func UnblockUser(ctx *gin.Context) {
+	var req BlockRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	if err := global.Db.Where("uid = ? AND blocked_uid = ?", req.CurrentUserID, req.TargetUserID).
+		Delete(&models.UserBlock{}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "取消拉黑失败：" + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+	})
+}
+
+// GetBlockedUsers 获取自己拉黑的用户列表
+func GetBlockedUsers(ctx *gin.Context) {
+	userID, err := strconv.ParseUint(ctx.Query("user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "user_id 参数格式不正确",
+		})
+		return
+	}
+
+	var blockedIDs []uint
+	global.Db.Model(&models.UserBlock{}).Where("uid = ?", userID).Order("id DESC").Pluck("blocked_uid", &blockedIDs)
+
+	simplifiedUsers := make([]SimplifiedUser, 0, len(blockedIDs))
+	if len(blockedIDs) > 0 {
+		var users []models.User
+		global.Db.Where("user_id IN ?", blockedIDs).Find(&users)
+		for _, user := range users {
+			simplifiedUsers = append(simplifiedUsers, simplifyUser(user))
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"users": simplifiedUsers,
+		},
+	})
+}
@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/utils"
+)
+
+// 用户搜索与推荐的候选集上限
+const (
+	userSearchCandidates  = 300
+	suggestionCandidates  = 200
+	maxFuzzyKeywordLength = 20 // 超过该长度的关键词不做模糊（子序列）匹配
+)
+
+// 可能认识的人的打分权重
+const (
+	weightMutualFollow = 3.0 // 每个共同关注
+	weightSharedTag    = 1.5 // 每个共同使用的标签
+	weightSharedTrip   = 4.0 // 每个共同参加的旅伴群组
+)
+
+// UserSearchResult 用户搜索结果
+type UserSearchResult struct {
+	SimplifiedUser
+	MutualCount int64 `json:"mutual_count"` // 我关注的人中有多少关注了 TA
+	IsFollow    int   `json:"is_follow"`
+}
+
+// SuggestionReasons 推荐理由
+type SuggestionReasons struct {
+	MutualFollows int64 `json:"mutual_follows"` // 我关注的人中关注了 TA 的人数
+	SharedTags    int64 `json:"shared_tags"`    // 双方笔记共同使用的标签数
+	SharedTrips   int64 `json:"shared_trips"`   // 共同参加的旅伴群组数
+}
+
+// UserSuggestion 可能认识的人
+type UserSuggestion struct {
+	SimplifiedUser
+	Reasons SuggestionReasons `json:"reasons"`
+	score   float64
+}
+
+// idCount 按用户 ID 分组计数的查询结果
+type idCount struct {
+	ID    uint
+	Count int64
+}
+
+// toCountMap 转为 ID -> 计数
+func toCountMap(rows []idCount) map[uint]int64 {
+	result := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		result[row.ID] = row.Count
+	}
+	return result
+}
+
+// followedUserIDs 用户关注的人
+func followedUserIDs(uid uint) []uint {
+	var ids []uint
+	global.Db.Model(&models.Follower{}).Where("uid = ?", uid).Pluck("fid", &ids)
+	return ids
+}
+
+// mutualFollowCounts 对每个候选用户，统计 viewer 关注的人中有多少关注了 TA
+func mutualFollowCounts(viewer uint, candidateIDs []uint) map[uint]int64 {
+	if viewer == 0 || len(candidateIDs) == 0 {
+		return map[uint]int64{}
+	}
+	var rows []idCount
+	global.Db.Table("followers AS f1").
+		Select("f2.fid AS id, COUNT(*) AS count").
+		Joins("JOIN followers AS f2 ON f2.uid = f1.fid").
+		Where("f1.uid = ? AND f2.fid IN ?", viewer, candidateIDs).
+		Group("f2.fid").
+		Scan(&rows)
+	return toCountMap(rows)
+}
+
+// usernameMatchScore 用户名与关键词的匹配程度（均已转小写）
+func usernameMatchScore(username, keyword string) float64 {
+	switch {
+	case username == keyword:
+		return 100
+	case strings.HasPrefix(username, keyword):
+		return 60
+	case strings.Contains(username, keyword):
+		return 40
+	}
+	// 模糊匹配：编辑距离越小得分越高
+	distance := utils.EditDistance(username, keyword)
+	length := max(len([]rune(username)), len([]rune(keyword)))
+	similarity := 1 - float64(distance)/float64(length)
+	if similarity <= 0 {
+		return 0
+	}
+	return 30 * similarity
+}
+
+// SearchUsers 按用户名和简介模糊搜索用户，按匹配程度、粉丝数和共同关注排序
+func SearchUsers(ctx *gin.Context) {
+	keyword := strings.TrimSpace(ctx.Query("keyword"))
+	uid := ctx.Query("user_id")
+	num := ctx.DefaultQuery("num", "20")
+	cursor := ctx.Query("cursor") // 游标为已返回的结果数
+
+	if keyword == "" || uid == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "keyword/user_id 参数缺失",
+		})
+		return
+	}
+	userID, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "user_id 参数格式不正确",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(num)
+	if err != nil || limit <= 0 || limit > 30 {
+		limit = 20
+	}
+	offset := 0
+	if cursor != "" {
+		if offset, err = strconv.Atoi(cursor); err != nil || offset < 0 {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: "失败",
+				Code:   400,
+				Error:  "无效的游标参数",
+			})
+			return
+		}
+	}
+
+	lowerKeyword := strings.ToLower(keyword)
+	likeReplacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	contains := "%" + likeReplacer.Replace(keyword) + "%"
+
+	query := global.Db.Model(&models.User{}).Where("deleted_at IS NULL")
+	if blocked := blockedUserIDs(uint(userID)); len(blocked) > 0 {
+		query = query.Where("user_id NOT IN ?", blocked)
+	}
+	matchSQL := "username LIKE ? OR description LIKE ?"
+	matchArgs := []interface{}{contains, contains}
+	if runes := []rune(keyword); len(runes) > 1 && len(runes) <= maxFuzzyKeywordLength {
+		// 子序列匹配，容忍漏字和多字，如 "zhsan" 可以匹配 "zhangsan"
+		parts := make([]string, 0, len(runes))
+		for _, r := range runes {
+			parts = append(parts, likeReplacer.Replace(string(r)))
+		}
+		matchSQL += " OR username LIKE ?"
+		matchArgs = append(matchArgs, "%"+strings.Join(parts, "%")+"%")
+	}
+
+	var users []models.User
+	if err := query.Where(matchSQL, matchArgs...).
+		Order("fan_count DESC").
+		Limit(userSearchCandidates).
+		Find(&users).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询用户失败：" + err.Error(),
+		})
+		return
+	}
+
+	candidateIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		candidateIDs = append(candidateIDs, user.UserId)
+	}
+	mutual := mutualFollowCounts(uint(userID), candidateIDs)
+	followed := make(map[uint]bool)
+	for _, id := range followedUserIDs(uint(userID)) {
+		followed[id] = true
+	}
+
+	type scoredUser struct {
+		user  models.User
+		score float64
+	}
+	scored := make([]scoredUser, 0, len(users))
+	for _, user := range users {
+		score := usernameMatchScore(strings.ToLower(user.Username), lowerKeyword)
+		if strings.Contains(strings.ToLower(user.Description), lowerKeyword) {
+			score += 15
+		}
+		score += 10*math.Log10(1+float64(user.FanCount)) + 8*math.Log2(1+float64(mutual[user.UserId]))
+		scored = append(scored, scoredUser{user: user, score: score})
+	}
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		if scored[i].user.FanCount != scored[j].user.FanCount {
+			return scored[i].user.FanCount > scored[j].user.FanCount
+		}
+		return scored[i].user.UserId < scored[j].user.UserId
+	})
+
+	results := make([]UserSearchResult, 0, limit)
+	for i := offset; i < len(scored) && len(results) < limit; i++ {
+		user := scored[i].user
+		isFollow := 0
+		if followed[user.UserId] {
+			isFollow = 1
+		}
+		results = append(results, UserSearchResult{
+			SimplifiedUser: simplifyUser(user),
+			MutualCount:    mutual[user.UserId],
+			IsFollow:       isFollow,
+		})
+	}
+	var nextCursor string
+	if offset+len(results) < len(scored) {
+		nextCursor = strconv.Itoa(offset + len(results))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"users":      results,
+			"nextCursor": nextCursor,
+		},
+	})
+}
+
+// GetUserSuggestions 可能认识的人：来自关注的人的关注、笔记标签相同和一起参加过旅伴群组的用户，
+// 排除自己、已关注和存在拉黑关系的用户；没有候选时用粉丝多的用户补足
+func GetUserSuggestions(ctx *gin.Context) {
+	uid := ctx.Query("user_id")
+	num := ctx.DefaultQuery("num", "10")
+
+	userID, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "user_id 参数格式不正确",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(num)
+	if err != nil || limit <= 0 || limit > 30 {
+		limit = 10
+	}
+	viewer := uint(userID)
+
+	excluded := map[uint]bool{viewer: true}
+	for _, id := range followedUserIDs(viewer) {
+		excluded[id] = true
+	}
+	blocked := blockedUserIDs(viewer)
+	for _, id := range blocked {
+		excluded[id] = true
+	}
+	// 已关注和存在拉黑关系的用户在 LIMIT 之前排除，否则关注多的用户取到的候选大多是已关注的人
+	exclude := func(query *gorm.DB, column string) *gorm.DB {
+		query = query.Where(column+" NOT IN (?)", global.Db.Model(&models.Follower{}).Select("fid").Where("uid = ?", viewer))
+		if len(blocked) > 0 {
+			query = query.Where(column+" NOT IN ?", blocked)
+		}
+		return query
+	}
+
+	// 关注的人的关注
+	var mutualRows []idCount
+	exclude(global.Db.Table("followers AS f1"), "f2.fid").
+		Select("f2.fid AS id, COUNT(*) AS count").
+		Joins("JOIN followers AS f2 ON f2.uid = f1.fid").
+		Where("f1.uid = ? AND f2.fid <> ?", viewer, viewer).
+		Group("f2.fid").
+		Order("count DESC").
+		Limit(suggestionCandidates).
+		Scan(&mutualRows)
+
+	// 笔记使用了相同标签的作者
+	var tagRows []idCount
+	exclude(global.Db.Model(&models.TagNoteRelation{}), "creator_id").
+		Select("creator_id AS id, COUNT(DISTINCT t_id) AS count").
+		Where("t_id IN (?)", global.Db.Model(&models.TagNoteRelation{}).Select("t_id").Where("creator_id = ?", viewer)).
+		Where("creator_id <> ?", viewer).
+		Group("creator_id").
+		Order("count DESC").
+		Limit(suggestionCandidates).
+		Scan(&tagRows)
+
+	// 一起参加过旅伴群组的成员
+	var tripRows []idCount
+	exclude(global.Db.Table("trip_group_members AS m1"), "m2.uid").
+		Select("m2.uid AS id, COUNT(*) AS count").
+		Joins("JOIN trip_group_members AS m2 ON m2.group_id = m1.group_id").
+		Where("m1.uid = ? AND m2.uid <> ?", viewer, viewer).
+		Group("m2.uid").
+		Order("count DESC").
+		Limit(suggestionCandidates).
+		Scan(&tripRows)
+
+	mutual, tags, trips := toCountMap(mutualRows), toCountMap(tagRows), toCountMap(tripRows)
+	candidateSet := make(map[uint]bool)
+	for _, m := range []map[uint]int64{mutual, tags, trips} {
+		for id := range m {
+			if !excluded[id] {
+				candidateSet[id] = true
+			}
+		}
+	}
+	candidateIDs := make([]uint, 0, len(candidateSet))
+	for id := range candidateSet {
+		candidateIDs = append(candidateIDs, id)
+	}
+
+	var users []models.User
+	if len(candidateIDs) > 0 {
+		global.Db.Where("user_id IN ? AND deleted_at IS NULL", candidateIDs).Find(&users)
+	}
+
+	suggestions := make([]UserSuggestion, 0, len(users))
+	for _, user := range users {
+		reasons := SuggestionReasons{
+			MutualFollows: mutual[user.UserId],
+			SharedTags:    tags[user.UserId],
+			SharedTrips:   trips[user.UserId],
+		}
+		suggestions = append(suggestions, UserSuggestion{
+			SimplifiedUser: simplifyUser(user),
+			Reasons:        reasons,
+			score: weightMutualFollow*float64(reasons.MutualFollows) +
+				weightSharedTag*float64(reasons.SharedTags) +
+				weightSharedTrip*float64(reasons.SharedTrips) +
+				math.Log10(1+float64(user.FanCount)),
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].score != suggestions[j].score {
+			return suggestions[i].score > suggestions[j].score
+		}
+		return suggestions[i].UserID < suggestions[j].UserID
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	// 冷启动：候选不足时按粉丝数补足
+	if len(suggestions) < limit {
+		for _, s := range suggestions {
+			excluded[s.UserID] = true
+		}
+		excludedIDs := make([]uint, 0, len(excluded))
+		for id := range excluded {
+			excludedIDs = append(excludedIDs, id)
+		}
+		var popular []models.User
+		global.Db.Where("deleted_at IS NULL AND user_id NOT IN ?", excludedIDs).
+			Order("fan_count DESC, user_id").
+			Limit(limit - len(suggestions)).
+			Find(&popular)
+		for _, user := range popular {
+			suggestions = append(suggestions, UserSuggestion{SimplifiedUser: simplifyUser(user)})
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"users": suggestions,
+		},
+	})
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/views"
+)
+
+// impressionLimit 单次曝光上报的最大笔记数
+const impressionLimit = 50
+
+// NoteImpressionRequest 信息流卡片曝光上报请求
+type NoteImpressionRequest struct {
+	Uid      uint   `json:"uid"`                         // 已不再使用，浏览者以 Authorization 令牌为准
+	DeviceID string `json:"device_id"`                   // 设备标识，未登录时用于去重
+	NoteIDs  []uint `json:"note_ids" binding:"required"` // 展示给用户的笔记 ID
+}
+
+// viewerFromRequest 从请求中取浏览者标识，设备标识优先取 X-Device-ID 请求头。
+// 请求参数里的 uid 可以随意填写，只有 Authorization 令牌校验通过时才按登录用户计数，其余按未登录处理并受每个 IP 的设备数限制
+func viewerFromRequest(ctx *gin.Context, deviceID string) views.Viewer {
+	if header := ctx.GetHeader("X-Device-ID"); header != "" {
+		deviceID = header
+	}
+	viewer := views.Viewer{DeviceID: deviceID, IP: ctx.ClientIP()}
+	if token := ctx.GetHeader("Authorization"); token != "" {
+		if userID, err := userIDFromToken(token); err == nil {
+			viewer.UserID = userID
+		}
+	}
+	return viewer
+}
+
+// RecordNoteImpressions 信息流卡片直接展示内容（如视频自动播放）时上报浏览，规则与查看笔记详情一致
+func RecordNoteImpressions(ctx *gin.Context) {
+	var req NoteImpressionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  err.Error(),
+		})
+		return
+	}
+	if len(req.NoteIDs) == 0 || len(req.NoteIDs) > impressionLimit {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: "失败",
+			Code:   400,
+			Error:  "note_ids 数量需要在 1~50 之间",
+		})
+		return
+	}
+
+	var notes []models.Note
+	if err := global.Db.Select("note_id", "note_creator_id").Where("note_id IN ?", req.NoteIDs).Find(&notes).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status: "失败",
+			Code:   500,
+			Error:  "查询笔记失败",
+		})
+		return
+	}
+
+	viewer := viewerFromRequest(ctx, req.DeviceID)
+	counted := 0
+	for _, note := range notes {
+		if views.Record(note.NoteID, note.NoteCreatorID, viewer) {
+			counted++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "成功",
+		"code":   200,
+		"data": gin.H{
+			"counted": counted,
+		},
+	})
+}
@@ -0,0 +1,158 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"travel-from-sysu-backend/config"
+	"travel-from-sysu-backend/models"
+)
+
+// typeLabels 通知类型在邮件中的分组标题，按此顺序展示
+var typeLabels = []struct {
+	Type  string
+	Label string
+}{
+	{"comment", "评论和回复"},
+	{"mention", "提到了你"},
+	{"chat_mention", "群聊中 @ 了你"},
+	{"follow", "新的关注"},
+	{"like", "赞了你的笔记"},
+	{"collect", "收藏了你的笔记"},
+	{"comment_like", "赞了你的评论"},
+}
+
+// Item 邮件中的一条通知
+type Item struct {
+	Actor   string // 发起人（聚合通知为"某某等 N 人"）
+	Title   string // 笔记标题摘要
+	Excerpt string // 评论/消息摘要
+	Link    string
+}
+
+// Group 同一类型的通知
+type Group struct {
+	Label string
+	Items []Item
+}
+
+// Content 一封摘要邮件的内容
+type Content struct {
+	Username       string
+	Period         string // 每日/每周
+	Total          int
+	Omitted        int // 超过上限未列出的条数
+	Groups         []Group
+	InboxURL       string
+	UnsubscribeURL string
+}
+
+const htmlBody = `<!DOCTYPE html>
+<html><body style="font-family:sans-serif;color:#333;max-width:600px;margin:auto">
+<h2>{{.Username}}，你有 {{.Total}} 条未读消息</h2>
+<p>以下是你的{{.Period}}消息摘要。</p>
+{{range .Groups}}<h3 style="border-bottom:1px solid #eee;padding-bottom:4px">{{.Label}}</h3>
+<ul>{{range .Items}}<li style="margin-bottom:6px"><b>{{.Actor}}</b>{{if .Title}} ·《{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}》{{else if .Link}} · <a href="{{.Link}}">查看</a>{{end}}{{if .Excerpt}}<br><span style="color:#888">{{.Excerpt}}</span>{{end}}</li>
+{{end}}</ul>
+{{end}}{{if .Omitted}}<p>还有 {{.Omitted}} 条消息未列出。</p>{{end}}
+<p><a href="{{.InboxURL}}">打开消息中心</a></p>
+<p style="font-size:12px;color:#aaa">不想再收到此类邮件？<a href="{{.UnsubscribeURL}}">一键退订</a></p>
+</body></html>
+`
+
+const textBody = `{{.Username}}，你有 {{.Total}} 条未读消息
+
+以下是你的{{.Period}}消息摘要。
+{{range .Groups}}
+【{{.Label}}】
+{{range .Items}}- {{.Actor}}{{if .Title}} 《{{.Title}}》{{end}}{{if .Excerpt}}：{{.Excerpt}}{{end}}{{if .Link}}
+  {{.Link}}{{end}}
+{{end}}{{end}}{{if .Omitted}}
+还有 {{.Omitted}} 条消息未列出。
+{{end}}
+打开消息中心：{{.InboxURL}}
+
+退订摘要邮件：{{.UnsubscribeURL}}
+`
+
+var (
+	htmlTmpl = htmltemplate.Must(htmltemplate.New("digest_html").Parse(htmlBody))
+	textTmpl = texttemplate.Must(texttemplate.New("digest_text").Parse(textBody))
+)
+
+// siteLink 拼接站点链接
+func siteLink(path string) string {
+	return strings.TrimRight(config.AppCongfig.Digest.SiteURL, "/") + path
+}
+
+// notificationLink 通知在站点上的跳转链接
+func notificationLink(n models.Notification) string {
+	switch {
+	case n.NoteID != 0:
+		return siteLink("/note/" + strconv.FormatUint(uint64(n.NoteID), 10))
+	case n.TargetType == "user":
+		return siteLink("/user/" + strconv.FormatUint(uint64(n.InitiatorID), 10))
+	case n.TargetType == "group":
+		return siteLink("/group/" + strconv.FormatUint(uint64(n.TargetID), 10))
+	}
+	return ""
+}
+
+// buildContent 把通知按类型分组，usernames 为发起人 ID -> 用户名
+func buildContent(user models.User, period string, notifications []models.Notification, total int, usernames map[uint]string) Content {
+	byType := make(map[string][]Item)
+	for _, n := range notifications {
+		actor := usernames[n.InitiatorID]
+		if actor == "" {
+			actor = "有人"
+		}
+		if n.AggregateCount > 1 {
+			actor = fmt.Sprintf("%s 等 %d 人", actor, n.AggregateCount)
+		}
+		byType[n.Type] = append(byType[n.Type], Item{
+			Actor:   actor,
+			Title:   n.TitleExcerpt,
+			Excerpt: n.CommentExcerpt,
+			Link:    notificationLink(n),
+		})
+	}
+
+	content := Content{
+		Username:       user.Username,
+		Period:         period,
+		Total:          total,
+		Omitted:        total - len(notifications),
+		InboxURL:       siteLink("/notifications"),
+		UnsubscribeURL: UnsubscribeURL(user.UserId),
+	}
+	for _, tl := range typeLabels {
+		if items := byType[tl.Type]; len(items) > 0 {
+			content.Groups = append(content.Groups, Group{Label: tl.Label, Items: items})
+			delete(byType, tl.Type)
+		}
+	}
+	// 未登记标题的类型统一放在最后
+	var others []Item
+	for _, items := range byType {
+		others = append(others, items...)
+	}
+	if len(others) > 0 {
+		content.Groups = append(content.Groups, Group{Label: "其他消息", Items: others})
+	}
+	return content
+}
+
+// render 渲染 HTML 和纯文本正文
+func render(content Content) (string, string, error) {
+	var htmlBuf, textBuf bytes.Buffer
+	if err := htmlTmpl.Execute(&htmlBuf, content); err != nil {
+		return "", "", err
+	}
+	if err := textTmpl.Execute(&textBuf, content); err != nil {
+		return "", "", err
+	}
+	return htmlBuf.String(), textBuf.String(), nil
+}
@@ -0,0 +1,191 @@
+package digest
+
+//未读通知摘要邮件：定时扫描开启了摘要的用户，在用户本地时间的发送时刻之后，每个周期发送一封
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"travel-from-sysu-backend/config"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/mail"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// Run 定时发送摘要邮件，阻塞运行，需在 InitConfig 之后以 goroutine 启动
+func Run() {
+	cfg := config.AppCongfig.Digest
+	if !cfg.Enabled {
+		return
+	}
+	if !mail.Enabled() {
+		log.Printf("未配置邮件通道，摘要邮件未启动")
+		return
+	}
+	interval := time.Duration(cfg.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		SendDue(time.Now())
+	}
+}
+
+// sendingTimeout 占位超过该时间仍未发送完成，视为发送进程已中断，可以重新发送
+const sendingTimeout = 10 * time.Minute
+
+// periodKey 用户本地时间下的周期标识
+func periodKey(frequency string, local time.Time) string {
+	if frequency == models.DigestWeekly {
+		year, week := local.ISOWeek()
+		return fmt.Sprintf("weekly:%d-W%02d", year, week)
+	}
+	return "daily:" + local.Format("2006-01-02")
+}
+
+// SendDue 给到了发送时刻、本周期还没发送过的用户发送摘要，返回发送成功的封数
+func SendDue(now time.Time) int {
+	var settings []models.NotificationSetting
+	if err := global.Db.Where("digest_frequency IN ?", []string{models.DigestDaily, models.DigestWeekly}).
+		Find(&settings).Error; err != nil {
+		log.Printf("查询摘要订阅失败: %v", err)
+		return 0
+	}
+
+	sent := 0
+	for _, setting := range settings {
+		loc, err := time.LoadLocation(setting.TimeZone)
+		if err != nil {
+			loc = time.Local
+		}
+		local := now.In(loc)
+		if local.Hour() < config.AppCongfig.Digest.SendHour {
+			continue
+		}
+		// 免打扰时段内不发，等下一次扫描
+		if setting.QuietHoursEnabled {
+			if _, quiet := utils.QuietHoursEnd(now, setting.TimeZone, setting.QuietStart, setting.QuietEnd); quiet {
+				continue
+			}
+		}
+
+		ok, err := sendToUser(setting, periodKey(setting.DigestFrequency, local), now)
+		if err != nil {
+			log.Printf("用户 %d 的摘要邮件发送失败: %v", setting.UserID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent
+}
+
+// sendToUser 给单个用户发送本周期的摘要，没有新的未读通知时不发送
+// 先以 (用户, 周期) 唯一索引占位再发送，保证多次扫描或多实例同时运行时不会重复发送
+func sendToUser(setting models.NotificationSetting, key string, now time.Time) (bool, error) {
+	// 进程在占位后崩溃会留下 sending 记录，超时后删除，让本周期可以重新发送
+	if err := global.Db.Where("user_id = ? AND period_key = ? AND status = ? AND created_at < ?",
+		setting.UserID, key, "sending", time.Now().Add(-sendingTimeout)).
+		Delete(&models.DigestLog{}).Error; err != nil {
+		return false, err
+	}
+
+	var existing int64
+	global.Db.Model(&models.DigestLog{}).Where("user_id = ? AND period_key = ?", setting.UserID, key).Count(&existing)
+	if existing > 0 {
+		return false, nil
+	}
+
+	var user models.User
+	if err := global.Db.Select("user_id", "username", "email").First(&user, "user_id = ?", setting.UserID).Error; err != nil {
+		return false, err
+	}
+	if user.Email == "" {
+		return false, nil
+	}
+
+	// 只取上次摘要之后的、已过免打扰顺延时间的未读通知
+	var last models.DigestLog
+	global.Db.Where("user_id = ? AND status = ?", setting.UserID, "sent").Order("last_notification_id DESC").Limit(1).Find(&last)
+
+	query := global.Db.Model(&models.Notification{}).
+		Where("recipient_id = ? AND is_read = ? AND id > ? AND deliver_at <= ?", setting.UserID, false, last.LastNotificationID, now).
+		Session(&gorm.Session{})
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return false, err
+	}
+	if total == 0 {
+		return false, nil
+	}
+
+	maxItems := config.AppCongfig.Digest.MaxItems
+	if maxItems <= 0 {
+		maxItems = 50
+	}
+	var notifications []models.Notification
+	if err := query.Order("id DESC").Limit(maxItems).Find(&notifications).Error; err != nil {
+		return false, err
+	}
+
+	var lastID uint
+	var initiatorIDs []uint
+	for _, n := range notifications {
+		if n.ID > lastID {
+			lastID = n.ID
+		}
+		initiatorIDs = append(initiatorIDs, n.InitiatorID)
+	}
+	var initiators []models.User
+	global.Db.Select("user_id", "username").Where("user_id IN ?", initiatorIDs).Find(&initiators)
+	usernames := make(map[uint]string, len(initiators))
+	for _, u := range initiators {
+		usernames[u.UserId] = u.Username
+	}
+
+	// 占位，唯一索引冲突说明其他进程已经在发送
+	record := models.DigestLog{UserID: setting.UserID, PeriodKey: key, Status: "sending"}
+	if err := global.Db.Create(&record).Error; err != nil {
+		return false, nil
+	}
+
+	period := "每日"
+	if setting.DigestFrequency == models.DigestWeekly {
+		period = "每周"
+	}
+	content := buildContent(user, period, notifications, int(total), usernames)
+	htmlPart, textPart, err := render(content)
+	if err == nil {
+		err = mail.Send(&mail.Message{
+			From:    config.AppCongfig.Mail.From,
+			To:      user.Email,
+			Subject: fmt.Sprintf("你有 %d 条未读消息 - %s消息摘要", total, period),
+			HTML:    htmlPart,
+			Text:    textPart,
+			Headers: map[string]string{
+				"List-Unsubscribe":      "<" + content.UnsubscribeURL + ">",
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			},
+		})
+	}
+	if err != nil {
+		// 发送失败删除占位，下次扫描重试
+		global.Db.Delete(&record)
+		return false, err
+	}
+
+	global.Db.Model(&record).Updates(map[string]interface{}{
+		"status":               "sent",
+		"notification_count":   total,
+		"last_notification_id": lastID,
+		"sent_at":              now,
+	})
+	return true, nil
+}
@@ -0,0 +1,199 @@
+package digest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"travel-from-sysu-backend/config"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/mail"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/testutil"
+)
+
+// failingTransport 总是发送失败的邮件通道
+type failingTransport struct{}
+
+func (failingTransport) Send(*mail.Message) error { return errors.New("smtp unavailable") }
+
+// setup 准备配置、内存邮件通道和一个开启每日摘要的用户
+func setup(t *testing.T, setting models.NotificationSetting) *mail.MemoryTransport {
+	t.Helper()
+	previousConfig, previousTransport := config.AppCongfig, mail.DefaultTransport
+	cfg := &config.Config{}
+	cfg.Digest.SendHour = 9
+	cfg.Digest.MaxItems = 50
+	cfg.Digest.Secret = "test-secret"
+	cfg.Digest.SiteURL = "https://example.com"
+	cfg.Digest.APIURL = "https://api.example.com"
+	cfg.Mail.From = "noreply@example.com"
+	config.AppCongfig = cfg
+	transport := mail.NewMemoryTransport()
+	mail.DefaultTransport = transport
+	t.Cleanup(func() {
+		config.AppCongfig, mail.DefaultTransport = previousConfig, previousTransport
+	})
+
+	testutil.OpenDB(t, &models.User{}, &models.Notification{}, &models.NotificationSetting{}, &models.DigestLog{})
+	mustCreate(t, &models.User{UserId: 1, Username: "alice", Password: "x", Email: "alice@example.com"})
+	mustCreate(t, &models.User{UserId: 2, Username: "bob", Password: "x"})
+	setting.UserID = 1
+	mustCreate(t, &setting)
+	return transport
+}
+
+func mustCreate(t *testing.T, value interface{}) {
+	t.Helper()
+	if err := global.Db.Create(value).Error; err != nil {
+		t.Fatalf("写入测试数据失败: %v", err)
+	}
+}
+
+// notify 给用户 1 添加一条未读通知
+func notify(t *testing.T, at time.Time) {
+	t.Helper()
+	mustCreate(t, &models.Notification{
+		InitiatorID: 2,
+		RecipientID: 1,
+		Type:        "like",
+		InitiatedAt: at,
+		DeliverAt:   at,
+		Delivery:    models.NotifyModeDigest,
+		NotificationTarget: models.NotificationTarget{
+			TargetType:   "note",
+			TargetID:     100001,
+			NoteID:       100001,
+			TitleExcerpt: "周末去哪儿",
+		},
+	})
+}
+
+func shanghai(t *testing.T, value string) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	tm, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func TestSendDueOncePerPeriod(t *testing.T) {
+	transport := setup(t, models.NotificationSetting{TimeZone: "Asia/Shanghai", DigestFrequency: models.DigestDaily})
+	notify(t, shanghai(t, "2024-05-01 07:00"))
+
+	if n := SendDue(shanghai(t, "2024-05-01 08:30")); n != 0 {
+		t.Fatalf("发送时刻之前不应发送，实际发送 %d 封", n)
+	}
+	if n := SendDue(shanghai(t, "2024-05-01 09:10")); n != 1 {
+		t.Fatalf("到达发送时刻应发送 1 封，实际 %d", n)
+	}
+
+	// 同一周期内再次扫描，即使有新通知也不重复发送
+	notify(t, shanghai(t, "2024-05-01 12:00"))
+	if n := SendDue(shanghai(t, "2024-05-01 18:00")); n != 0 {
+		t.Fatalf("同一周期不应重复发送，实际发送 %d 封", n)
+	}
+
+	// 下一个周期只包含上次摘要之后的通知
+	if n := SendDue(shanghai(t, "2024-05-02 09:00")); n != 1 {
+		t.Fatalf("新周期应发送 1 封，实际 %d", n)
+	}
+	sent := transport.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("应共发送 2 封邮件，实际 %d", len(sent))
+	}
+	if !strings.Contains(sent[1].Subject, "1 条未读") {
+		t.Errorf("第二封只应包含新的 1 条通知，标题为 %q", sent[1].Subject)
+	}
+	if sent[0].To != "alice@example.com" || sent[0].Headers["List-Unsubscribe"] == "" {
+		t.Errorf("收件人或退订头不正确: %+v", sent[0])
+	}
+
+	var logs []models.DigestLog
+	global.Db.Order("id").Find(&logs)
+	if len(logs) != 2 || logs[0].PeriodKey != "daily:2024-05-01" || logs[1].PeriodKey != "daily:2024-05-02" || logs[1].Status != "sent" {
+		t.Errorf("发送记录不正确: %+v", logs)
+	}
+}
+
+func TestSendDueSkipsPeriodPlaceholder(t *testing.T) {
+	transport := setup(t, models.NotificationSetting{TimeZone: "Asia/Shanghai", DigestFrequency: models.DigestWeekly})
+	notify(t, shanghai(t, "2024-05-01 07:00"))
+
+	// 其他实例已经为本周期占位
+	mustCreate(t, &models.DigestLog{UserID: 1, PeriodKey: "weekly:2024-W18", Status: "sending"})
+	if n := SendDue(shanghai(t, "2024-05-01 10:00")); n != 0 || len(transport.Sent()) != 0 {
+		t.Fatalf("已占位的周期不应再发送，实际发送 %d 封", n)
+	}
+	if n := SendDue(shanghai(t, "2024-05-06 10:00")); n != 1 {
+		t.Fatalf("下一周应发送 1 封，实际 %d", n)
+	}
+}
+
+func TestSendDueReclaimsStalePlaceholder(t *testing.T) {
+	transport := setup(t, models.NotificationSetting{TimeZone: "Asia/Shanghai", DigestFrequency: models.DigestDaily})
+	notify(t, shanghai(t, "2024-05-01 07:00"))
+
+	// 发送进程在占位后崩溃，留下一条早已超时的 sending 记录
+	mustCreate(t, &models.DigestLog{UserID: 1, PeriodKey: "daily:2024-05-01", Status: "sending", CreatedAt: time.Now().Add(-time.Hour)})
+	if n := SendDue(shanghai(t, "2024-05-01 10:00")); n != 1 || len(transport.Sent()) != 1 {
+		t.Fatalf("超时的占位应被清理并重新发送，实际发送 %d 封", n)
+	}
+	var logs []models.DigestLog
+	global.Db.Find(&logs)
+	if len(logs) != 1 || logs[0].Status != "sent" {
+		t.Fatalf("应只剩一条已发送记录: %+v", logs)
+	}
+}
+
+func TestSendDueRetriesAfterFailure(t *testing.T) {
+	transport := setup(t, models.NotificationSetting{TimeZone: "Asia/Shanghai", DigestFrequency: models.DigestDaily})
+	notify(t, shanghai(t, "2024-05-01 07:00"))
+
+	mail.DefaultTransport = failingTransport{}
+	if n := SendDue(shanghai(t, "2024-05-01 09:30")); n != 0 {
+		t.Fatalf("发送失败不应计数，实际 %d", n)
+	}
+	var count int64
+	global.Db.Model(&models.DigestLog{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("发送失败后应删除占位，剩余 %d 条", count)
+	}
+
+	mail.DefaultTransport = transport
+	if n := SendDue(shanghai(t, "2024-05-01 10:00")); n != 1 {
+		t.Fatalf("下次扫描应重试成功，实际发送 %d 封", n)
+	}
+}
+
+func TestSendDueRespectsQuietHoursAcrossMidnight(t *testing.T) {
+	transport := setup(t, models.NotificationSetting{
+		TimeZone:          "Asia/Shanghai",
+		DigestFrequency:   models.DigestDaily,
+		QuietHoursEnabled: true,
+		QuietStart:        "21:00",
+		QuietEnd:          "10:00",
+	})
+	notify(t, shanghai(t, "2024-05-01 07:00"))
+
+	for _, at := range []string{"2024-05-01 09:30", "2024-05-01 23:30"} {
+		if n := SendDue(shanghai(t, at)); n != 0 {
+			t.Fatalf("%s 在免打扰时段内不应发送，实际发送 %d 封", at, n)
+		}
+	}
+	// 跨天后的凌晨仍在免打扰时段，属于新的一天但不发送
+	if n := SendDue(shanghai(t, "2024-05-02 09:59")); n != 0 {
+		t.Fatalf("次日 09:59 仍在免打扰时段，实际发送 %d 封", n)
+	}
+	if n := SendDue(shanghai(t, "2024-05-02 10:00")); n != 1 {
+		t.Fatalf("免打扰结束后应发送 1 封，实际 %d", n)
+	}
+	if len(transport.Sent()) != 1 {
+		t.Fatalf("应只发送 1 封，实际 %d", len(transport.Sent()))
+	}
+}
@@ -0,0 +1,34 @@
+package digest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"travel-from-sysu-backend/config"
+)
+
+// UnsubscribeToken 生成退订链接签名，绑定用户 ID，无需登录即可一键退订
+func UnsubscribeToken(userID uint) string {
+	mac := hmac.New(sha256.New, []byte(config.AppCongfig.Digest.Secret))
+	mac.Write([]byte("digest-unsubscribe:" + strconv.FormatUint(uint64(userID), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyUnsubscribeToken 校验退订链接签名
+func VerifyUnsubscribeToken(userID uint, token string) bool {
+	if config.AppCongfig == nil || config.AppCongfig.Digest.Secret == "" {
+		return false
+	}
+	return hmac.Equal([]byte(UnsubscribeToken(userID)), []byte(token))
+}
+
+// UnsubscribeURL 退订链接，同时支持浏览器 GET 和邮件客户端的 RFC 8058 一键退订 POST
+func UnsubscribeURL(userID uint) string {
+	query := url.Values{}
+	query.Set("uid", strconv.FormatUint(uint64(userID), 10))
+	query.Set("token", UnsubscribeToken(userID))
+	return strings.TrimRight(config.AppCongfig.Digest.APIURL, "/") + "/api/notification/digest/unsubscribe?" + query.Encode()
+}
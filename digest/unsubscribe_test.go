@@ -0,0 +1,51 @@
+package digest
+
+import (
+	"net/url"
+	"testing"
+	"travel-from-sysu-backend/config"
+)
+
+func TestVerifyUnsubscribeToken(t *testing.T) {
+	previous := config.AppCongfig
+	defer func() { config.AppCongfig = previous }()
+	cfg := &config.Config{}
+	cfg.Digest.Secret = "test-secret"
+	cfg.Digest.APIURL = "https://api.example.com/"
+	config.AppCongfig = cfg
+
+	token := UnsubscribeToken(100001)
+	if !VerifyUnsubscribeToken(100001, token) {
+		t.Fatal("正确的签名应校验通过")
+	}
+	if VerifyUnsubscribeToken(100002, token) {
+		t.Error("签名不能用于其他用户")
+	}
+	tampered := []byte(token)
+	tampered[0] ^= 1
+	if VerifyUnsubscribeToken(100001, string(tampered)) {
+		t.Error("篡改后的签名不应通过")
+	}
+	if VerifyUnsubscribeToken(100001, "") {
+		t.Error("空签名不应通过")
+	}
+
+	u, err := url.Parse(UnsubscribeURL(100001))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/api/notification/digest/unsubscribe" || u.Query().Get("uid") != "100001" || u.Query().Get("token") != token {
+		t.Errorf("退订链接不正确: %s", u)
+	}
+
+	// 更换密钥后旧链接失效
+	cfg.Digest.Secret = "rotated"
+	if VerifyUnsubscribeToken(100001, token) {
+		t.Error("更换密钥后旧签名不应通过")
+	}
+	// 未配置密钥时拒绝所有退订请求，避免使用可预测的空密钥签名
+	cfg.Digest.Secret = ""
+	if VerifyUnsubscribeToken(100001, UnsubscribeToken(100001)) {
+		t.Error("未配置密钥时不应校验通过")
+	}
+}
@@ -0,0 +1,148 @@
+package feed
+
+import (
+	"hash/fnv"
+	"log"
+	"strconv"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// Variant 实验分组，Weight 为组内流量占比的相对权重
+type Variant struct {
+	Name   string
+	Ranker string
+	Weight int
+}
+
+// Experiment 排序实验，Traffic 为进入该实验的用户百分比。
+// 多个实验按配置顺序切分同一份流量，互不重叠
+type Experiment struct {
+	Name     string
+	Traffic  int
+	Variants []Variant
+}
+
+// Assignment 用户命中的实验分组，未进入任何实验时 Experiment 为空、Variant 为 default
+type Assignment struct {
+	Experiment string `json:"experiment"`
+	Variant    string `json:"variant"`
+	Ranker     string `json:"ranker"`
+}
+
+// DefaultVariant 未进入实验的用户所在分组
+const DefaultVariant = "default"
+
+// validExperiments 过滤掉引用未注册策略的分组以及流量越界的实验
+func validExperiments(experiments []Experiment) []Experiment {
+	var valid []Experiment
+	traffic := 0
+	for _, e := range experiments {
+		var variants []Variant
+		for _, v := range e.Variants {
+			if _, ok := lookupRanker(v.Ranker); !ok {
+				log.Printf("推荐实验 %s 的分组 %s 使用了未注册的排序策略 %s，已忽略", e.Name, v.Name, v.Ranker)
+				continue
+			}
+			if v.Name != "" && v.Weight > 0 {
+				variants = append(variants, v)
+			}
+		}
+		if e.Name == "" || e.Traffic <= 0 || len(variants) == 0 {
+			continue
+		}
+		if traffic+e.Traffic > 100 {
+			log.Printf("推荐实验 %s 的流量超出 100%%，已忽略", e.Name)
+			continue
+		}
+		traffic += e.Traffic
+		e.Variants = variants
+		valid = append(valid, e)
+	}
+	return valid
+}
+
+// bucket 把 key 稳定地映射到 [0, n)
+func bucket(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// Assign 确定性地为用户分配实验分组：同一用户在配置不变时总是落在同一组
+func Assign(userID uint) Assignment {
+	uid := strconv.FormatUint(uint64(userID), 10)
+	slot := bucket("feed-layer:"+uid, 100)
+	for _, e := range opts.Experiments {
+		if slot >= e.Traffic {
+			slot -= e.Traffic
+			continue
+		}
+		total := 0
+		for _, v := range e.Variants {
+			total += v.Weight
+		}
+		pick := bucket(e.Name+":"+uid, total)
+		for _, v := range e.Variants {
+			if pick < v.Weight {
+				return Assignment{Experiment: e.Name, Variant: v.Name, Ranker: v.Ranker}
+			}
+			pick -= v.Weight
+		}
+	}
+	return Assignment{Variant: DefaultVariant, Ranker: opts.DefaultRanker}
+}
+
+// VariantStats 一个实验分组的效果统计
+type VariantStats struct {
+	Experiment  string  `json:"experiment"`
+	Variant     string  `json:"variant"`
+	Users       int64   `json:"users"`       // 曝光覆盖的用户数
+	Impressions int64   `json:"impressions"` // 曝光次数
+	Clicks      int64   `json:"clicks"`      // 曝光后点击的次数
+	Likes       int64   `json:"likes"`       // 曝光后点赞的次数
+	CTR         float64 `json:"ctr"`         // 点击率
+	LikeRate    float64 `json:"like_rate"`   // 点赞率
+}
+
+// RecordClick 记录用户点击了推荐流中的笔记，标记在最近一次未点击的曝光上
+func RecordClick(userID, noteID uint) error {
+	var impression models.FeedImpression
+	err := global.Db.Where("user_id = ? AND note_id = ? AND clicked_at IS NULL AND shown_at > ?",
+		userID, noteID, time.Now().AddDate(0, 0, -opts.SeenDays)).
+		Order("shown_at DESC").
+		Limit(1).
+		Find(&impression).Error
+	if err != nil || impression.ID == 0 {
+		return err
+	}
+	return global.Db.Model(&impression).Update("clicked_at", time.Now()).Error
+}
+
+// ExperimentStats 统计最近 days 天各实验分组的点击率和点赞率，experiment 为空时统计全部
+func ExperimentStats(experiment string, days int) ([]VariantStats, error) {
+	query := global.Db.Table("feed_impressions AS i").
+		Select(`i.experiment, i.variant,
+			COUNT(DISTINCT i.user_id) AS users,
+			COUNT(*) AS impressions,
+			COUNT(i.clicked_at) AS clicks,
+			SUM(EXISTS(SELECT 1 FROM likes l WHERE l.uid = i.user_id AND l.nid = i.note_id AND l.create_date >= i.shown_at)) AS likes`).
+		Where("i.shown_at > ?", time.Now().AddDate(0, 0, -days)).
+		Group("i.experiment, i.variant").
+		Order("i.experiment, i.variant")
+	if experiment != "" {
+		query = query.Where("i.experiment = ?", experiment)
+	}
+	var stats []VariantStats
+	if err := query.Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		if stats[i].Impressions > 0 {
+			stats[i].CTR = float64(stats[i].Clicks) / float64(stats[i].Impressions)
+			stats[i].LikeRate = float64(stats[i].Likes) / float64(stats[i].Impressions)
+		}
+	}
+	return stats, nil
+}
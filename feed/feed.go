@@ -0,0 +1,204 @@
+package feed
+
+//个性化推荐流：多路召回（关注的作者、关注的标签、兴趣标签、协同过滤）→ 过滤看过和不感兴趣的 →
+//按实验分组选用的排序策略打分 → 按比例插入探索内容。每次下发的笔记连同实验分组记为曝光，下次请求不再重复推荐
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// Options 推荐流参数
+type Options struct {
+	CandidateDays           int     // 只召回该天数内更新的笔记
+	SeenDays                int     // 曝光过的笔记在该天数内不再推荐
+	ExplorationRate         float64 // 每页中探索内容的比例
+	ImpressionRetentionDays int     // 曝光记录保留天数
+	DefaultRanker           string  // 未进入实验的用户使用的排序策略
+	Experiments             []Experiment
+}
+
+var opts = Options{
+	CandidateDays:           30,
+	SeenDays:                7,
+	ExplorationRate:         0.15,
+	ImpressionRetentionDays: 30,
+	DefaultRanker:           RankerPersonalized,
+}
+
+// Init 设置推荐流参数并启动曝光记录清理，未配置的项使用默认值，需在数据库初始化之后调用
+func Init(o Options) {
+	if o.CandidateDays > 0 {
+		opts.CandidateDays = o.CandidateDays
+	}
+	if o.SeenDays > 0 {
+		opts.SeenDays = o.SeenDays
+	}
+	if o.ExplorationRate >= 0 && o.ExplorationRate <= 1 {
+		opts.ExplorationRate = o.ExplorationRate
+	}
+	if o.ImpressionRetentionDays > 0 {
+		opts.ImpressionRetentionDays = o.ImpressionRetentionDays
+	}
+	if opts.ImpressionRetentionDays < opts.SeenDays {
+		opts.ImpressionRetentionDays = opts.SeenDays
+	}
+	if _, ok := lookupRanker(o.DefaultRanker); ok {
+		opts.DefaultRanker = o.DefaultRanker
+	} else if o.DefaultRanker != "" {
+		log.Printf("未注册的排序策略 %s，使用默认策略 %s", o.DefaultRanker, opts.DefaultRanker)
+	}
+	opts.Experiments = validExperiments(o.Experiments)
+	go cleanupImpressions()
+}
+
+// cleanupImpressions 每小时分批删除过期的曝光记录
+func cleanupImpressions() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		cutoff := time.Now().AddDate(0, 0, -opts.ImpressionRetentionDays)
+		for {
+			result := global.Db.Where("shown_at < ?", cutoff).Limit(1000).Delete(&models.FeedImpression{})
+			if result.Error != nil {
+				log.Printf("清理推荐曝光记录失败: %v", result.Error)
+				break
+			}
+			if result.RowsAffected < 1000 {
+				break
+			}
+		}
+	}
+}
+
+// Item 推荐流中的一条笔记
+type Item struct {
+	Note    models.Note
+	Sources []string // 召回来源，用于展示推荐理由
+	Score   float64  // 排序得分，探索内容为 0
+}
+
+// Recommend 为用户生成一页推荐，并把下发的笔记连同实验分组记为曝光
+func Recommend(userID uint, limit int) ([]Item, Assignment, error) {
+	assignment := Assign(userID)
+	ranker, _ := lookupRanker(assignment.Ranker)
+
+	p, err := loadProfile(userID)
+	if err != nil {
+		return nil, assignment, err
+	}
+
+	since := sinceUnix()
+	set := make(candidateSet)
+	for _, recall := range []func() error{
+		func() error { return recallFollowedAuthors(p, set, since) },
+		func() error { return recallFollowedTags(p, set) },
+		func() error { return recallInterestTags(p, set) },
+		func() error { return recallCoEngagement(p, set) },
+	} {
+		if err := recall(); err != nil {
+			return nil, assignment, err
+		}
+	}
+
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		if !p.excludedNotes[id] {
+			ids = append(ids, id)
+		}
+	}
+	var notes []models.Note
+	if len(ids) > 0 {
+		if err := global.Db.Where("note_id IN ?", ids).Find(&notes).Error; err != nil {
+			return nil, assignment, err
+		}
+	}
+
+	now := time.Now()
+	ranked := make([]Item, 0, len(notes))
+	for _, note := range notes {
+		if !p.allows(note) {
+			continue
+		}
+		c := set[note.NoteID]
+		ranked = append(ranked, Item{Note: note, Sources: c.sources, Score: ranker.Score(note, c.signals, now)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Note.NoteID > ranked[j].Note.NoteID
+	})
+
+	pool, err := explorationPool(since)
+	if err != nil {
+		return nil, assignment, err
+	}
+	items := blendExploration(ranked, pool, p, limit)
+	recordImpressions(userID, assignment, items)
+	return items, assignment, nil
+}
+
+// blendExploration 从探索池随机挑选笔记插入到随机位置；个性化候选不足时用探索池补足（冷启动）
+func blendExploration(ranked []Item, pool []models.Note, p *profile, limit int) []Item {
+	exploreSlots := int(math.Round(float64(limit) * opts.ExplorationRate))
+	if len(ranked) < limit-exploreSlots {
+		exploreSlots = limit - len(ranked)
+	}
+	if len(ranked) > limit-exploreSlots {
+		ranked = ranked[:limit-exploreSlots]
+	}
+
+	chosen := make(map[uint]bool, len(ranked))
+	for _, item := range ranked {
+		chosen[item.Note.NoteID] = true
+	}
+	var explore []Item
+	for _, i := range rand.Perm(len(pool)) {
+		if len(explore) >= exploreSlots {
+			break
+		}
+		note := pool[i]
+		if chosen[note.NoteID] || !p.allows(note) {
+			continue
+		}
+		chosen[note.NoteID] = true
+		explore = append(explore, Item{Note: note, Sources: []string{SourceExploration}})
+	}
+
+	items := ranked
+	for _, item := range explore {
+		pos := rand.Intn(len(items) + 1)
+		items = append(items, Item{})
+		copy(items[pos+1:], items[pos:])
+		items[pos] = item
+	}
+	return items
+}
+
+// recordImpressions 记录曝光及所在的实验分组，失败只打日志
+func recordImpressions(userID uint, assignment Assignment, items []Item) {
+	if len(items) == 0 {
+		return
+	}
+	now := time.Now()
+	impressions := make([]models.FeedImpression, 0, len(items))
+	for _, item := range items {
+		impressions = append(impressions, models.FeedImpression{
+			UserID:     userID,
+			NoteID:     item.Note.NoteID,
+			Source:     item.Sources[0],
+			Experiment: assignment.Experiment,
+			Variant:    assignment.Variant,
+			ShownAt:    now,
+		})
+	}
+	if err := global.Db.Create(&impressions).Error; err != nil {
+		log.Printf("记录推荐曝光失败: %v", err)
+	}
+}
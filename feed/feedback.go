@@ -0,0 +1,60 @@
+package feed
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidFeedback 不感兴趣的对象无效
+var ErrInvalidFeedback = errors.New("无效的不感兴趣对象")
+
+// normalizeFeedback 校验并规范化不感兴趣的对象
+func normalizeFeedback(targetType, targetKey string) (string, error) {
+	targetKey = strings.TrimSpace(targetKey)
+	switch targetType {
+	case models.FeedbackTargetNote, models.FeedbackTargetAuthor:
+		id, err := strconv.ParseUint(targetKey, 10, 64)
+		if err != nil || id == 0 {
+			return "", ErrInvalidFeedback
+		}
+		return strconv.FormatUint(id, 10), nil
+	case models.FeedbackTargetTag:
+		targetKey = strings.ToLower(targetKey)
+		if targetKey == "" || len([]rune(targetKey)) > 50 {
+			return "", ErrInvalidFeedback
+		}
+		return targetKey, nil
+	}
+	return "", ErrInvalidFeedback
+}
+
+// MarkNotInterested 标记不感兴趣，重复标记忽略
+func MarkNotInterested(userID uint, targetType, targetKey string) error {
+	key, err := normalizeFeedback(targetType, targetKey)
+	if err != nil {
+		return err
+	}
+	feedback := models.FeedFeedback{
+		UserID:     userID,
+		TargetType: targetType,
+		TargetKey:  key,
+		CreatedAt:  time.Now(),
+	}
+	return global.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&feedback).Error
+}
+
+// UndoNotInterested 撤销不感兴趣
+func UndoNotInterested(userID uint, targetType, targetKey string) error {
+	key, err := normalizeFeedback(targetType, targetKey)
+	if err != nil {
+		return err
+	}
+	return global.Db.Where("user_id = ? AND target_type = ? AND target_key = ?", userID, targetType, key).
+		Delete(&models.FeedFeedback{}).Error
+}
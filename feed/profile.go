@@ -0,0 +1,138 @@
+package feed
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// engagedNoteLimit 计算兴趣时取最近点赞/收藏的笔记数
+const engagedNoteLimit = 100
+
+// profile 生成推荐所需的用户画像与过滤条件
+type profile struct {
+	userID         uint
+	followees      []uint
+	followedTagIDs []string
+	likedNoteIDs   []uint
+	collectedIDs   []uint
+
+	excludedNotes   map[uint]bool   // 看过、点赞/收藏过、标记不感兴趣的笔记
+	excludedAuthors map[uint]bool   // 自己、拉黑关系、标记不感兴趣的作者
+	excludedTags    map[string]bool // 标记不感兴趣的标签（小写）
+}
+
+// engagedNoteIDs 点赞和收藏过的笔记（去重）
+func (p *profile) engagedNoteIDs() []uint {
+	seen := make(map[uint]bool, len(p.likedNoteIDs)+len(p.collectedIDs))
+	ids := make([]uint, 0, len(p.likedNoteIDs)+len(p.collectedIDs))
+	for _, list := range [][]uint{p.collectedIDs, p.likedNoteIDs} {
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// loadProfile 加载用户的关注、互动、不感兴趣和曝光记录
+func loadProfile(userID uint) (*profile, error) {
+	p := &profile{
+		userID:          userID,
+		excludedNotes:   make(map[uint]bool),
+		excludedAuthors: map[uint]bool{userID: true},
+		excludedTags:    make(map[string]bool),
+	}
+
+	if err := global.Db.Model(&models.Follower{}).Where("uid = ?", userID).Pluck("fid", &p.followees).Error; err != nil {
+		return nil, err
+	}
+	if err := global.Db.Model(&models.TagFollow{}).Where("user_id = ?", userID).Pluck("tag_id", &p.followedTagIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := global.Db.Model(&models.Like{}).
+		Where("uid = ? AND nid IS NOT NULL", userID).
+		Order("like_id DESC").Limit(engagedNoteLimit).
+		Pluck("nid", &p.likedNoteIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := global.Db.Model(&models.Collect{}).
+		Where("uid = ?", userID).
+		Order("collect_id DESC").Limit(engagedNoteLimit).
+		Pluck("nid", &p.collectedIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range p.engagedNoteIDs() {
+		p.excludedNotes[id] = true
+	}
+
+	// 近期曝光过的笔记
+	var seen []uint
+	if err := global.Db.Model(&models.FeedImpression{}).
+		Where("user_id = ? AND shown_at > ?", userID, time.Now().AddDate(0, 0, -opts.SeenDays)).
+		Distinct().Pluck("note_id", &seen).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range seen {
+		p.excludedNotes[id] = true
+	}
+
+	// 拉黑关系（任一方向）
+	var blocks []models.UserBlock
+	if err := global.Db.Where("uid = ? OR blocked_uid = ?", userID, userID).Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+	for _, b := range blocks {
+		p.excludedAuthors[b.Uid] = true
+		p.excludedAuthors[b.BlockedUid] = true
+	}
+
+	// 不感兴趣
+	var feedbacks []models.FeedFeedback
+	if err := global.Db.Where("user_id = ?", userID).Find(&feedbacks).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range feedbacks {
+		switch f.TargetType {
+		case models.FeedbackTargetNote:
+			if id, err := strconv.ParseUint(f.TargetKey, 10, 64); err == nil {
+				p.excludedNotes[uint(id)] = true
+			}
+		case models.FeedbackTargetAuthor:
+			if id, err := strconv.ParseUint(f.TargetKey, 10, 64); err == nil {
+				p.excludedAuthors[uint(id)] = true
+			}
+		case models.FeedbackTargetTag:
+			p.excludedTags[strings.ToLower(f.TargetKey)] = true
+		}
+	}
+	return p, nil
+}
+
+// noteTags 笔记的标签（小写）
+func noteTags(note models.Note) []string {
+	var tags []string
+	for _, tag := range strings.Split(note.NoteTagList, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// allows 笔记是否可以推荐给该用户
+func (p *profile) allows(note models.Note) bool {
+	if p.excludedNotes[note.NoteID] || p.excludedAuthors[note.NoteCreatorID] {
+		return false
+	}
+	for _, tag := range noteTags(note) {
+		if p.excludedTags[tag] {
+			return false
+		}
+	}
+	return true
+}
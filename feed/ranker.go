@@ -0,0 +1,109 @@
+package feed
+
+import (
+	"math"
+	"sort"
+	"time"
+	"travel-from-sysu-backend/models"
+)
+
+// Ranker 推荐流排序策略，对召回的候选笔记打分，分数越高越靠前
+type Ranker interface {
+	Name() string
+	Score(note models.Note, s Signals, now time.Time) float64
+}
+
+// 内置排序策略
+const (
+	RankerPersonalized = "personalized" // 召回信号加权 × 新鲜度衰减（默认）
+	RankerFresh        = "fresh"        // 只要命中召回就按更新时间倒序
+	RankerPopular      = "popular"      // 以热度分为主，召回信号只做加成
+)
+
+var rankers = make(map[string]Ranker)
+
+// Register 注册排序策略，同名策略会被覆盖
+func Register(r Ranker) {
+	rankers[r.Name()] = r
+}
+
+// lookupRanker 按名称查找排序策略
+func lookupRanker(name string) (Ranker, bool) {
+	r, ok := rankers[name]
+	return r, ok
+}
+
+// RankerNames 已注册的排序策略名称
+func RankerNames() []string {
+	names := make([]string, 0, len(rankers))
+	for name := range rankers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(personalizedRanker{})
+	Register(freshRanker{})
+	Register(popularRanker{})
+}
+
+// 打分权重
+const (
+	weightFollowedAuthor = 3.0
+	weightFollowedTag    = 2.0
+	weightInterestTag    = 4.0
+	weightCoEngagement   = 1.5
+	weightHotScore       = 0.02 // 热度分 0~100
+	recencyHalfLifeDays  = 7.0  // 新鲜度半衰期
+)
+
+// relevance 召回信号加权求和
+func relevance(s Signals) float64 {
+	r := 0.0
+	if s.FollowedAuthor {
+		r += weightFollowedAuthor
+	}
+	r += weightFollowedTag * float64(s.FollowedTags)
+	r += weightInterestTag * s.InterestTags
+	r += weightCoEngagement * math.Log1p(s.CoEngagement)
+	return r
+}
+
+// ageDays 笔记距更新时间的天数
+func ageDays(note models.Note, now time.Time) float64 {
+	days := now.Sub(time.Unix(note.NoteUpdateTime, 0)).Hours() / 24
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// personalizedRanker 召回信号和热度加权后乘以新鲜度衰减
+type personalizedRanker struct{}
+
+func (personalizedRanker) Name() string { return RankerPersonalized }
+
+func (personalizedRanker) Score(note models.Note, s Signals, now time.Time) float64 {
+	r := relevance(s) + weightHotScore*note.Score
+	return r * math.Pow(0.5, ageDays(note, now)/recencyHalfLifeDays)
+}
+
+// freshRanker 按更新时间倒序，召回信号只用于同一时间的先后
+type freshRanker struct{}
+
+func (freshRanker) Name() string { return RankerFresh }
+
+func (freshRanker) Score(note models.Note, s Signals, now time.Time) float64 {
+	return float64(note.NoteUpdateTime) + math.Min(relevance(s), 100)/100
+}
+
+// popularRanker 热度分为主，命中的召回信号按比例加成
+type popularRanker struct{}
+
+func (popularRanker) Name() string { return RankerPopular }
+
+func (popularRanker) Score(note models.Note, s Signals, now time.Time) float64 {
+	return note.Score * (1 + 0.1*math.Log1p(relevance(s)))
+}
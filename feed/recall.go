@@ -0,0 +1,203 @@
+package feed
+
+import (
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// 每路召回的候选上限
+const (
+	recallLimit        = 200
+	interestTagLimit   = 20  // 兴趣标签个数
+	similarUserLimit   = 100 // 协同过滤取的相似用户数
+	explorationPoolCap = 100 // 探索池大小
+)
+
+// 召回来源
+const (
+	SourceFollowedAuthor = "followed_author" // 关注的作者
+	SourceFollowedTag    = "followed_tag"    // 关注的标签
+	SourceInterestTag    = "interest_tag"    // 点赞/收藏过的笔记的标签
+	SourceCoEngagement   = "co_engagement"   // 收藏过相同笔记的人还收藏了
+	SourceExploration    = "exploration"     // 探索：近期热门中随机挑选
+)
+
+// Signals 候选笔记的召回信号
+type Signals struct {
+	FollowedAuthor bool    // 作者是否被关注
+	FollowedTags   int     // 命中的关注标签数
+	InterestTags   float64 // 命中的兴趣标签权重之和
+	CoEngagement   float64 // 相似用户的互动数（收藏计 1，点赞计 0.5）
+}
+
+// candidate 召回阶段的候选
+type candidate struct {
+	noteID  uint
+	signals Signals
+	sources []string
+}
+
+// candidateSet 按笔记 ID 合并多路召回结果
+type candidateSet map[uint]*candidate
+
+func (s candidateSet) get(noteID uint, source string) *candidate {
+	c, ok := s[noteID]
+	if !ok {
+		c = &candidate{noteID: noteID}
+		s[noteID] = c
+	}
+	for _, existing := range c.sources {
+		if existing == source {
+			return c
+		}
+	}
+	c.sources = append(c.sources, source)
+	return c
+}
+
+// noteCount 按笔记 ID 分组计数的查询结果
+type noteCount struct {
+	NID   uint
+	Count float64
+}
+
+// recallFollowedAuthors 关注的作者近期的笔记
+func recallFollowedAuthors(p *profile, set candidateSet, since int64) error {
+	if len(p.followees) == 0 {
+		return nil
+	}
+	var ids []uint
+	if err := global.Db.Model(&models.Note{}).
+		Where("note_creator_id IN ? AND note_update_time >= ?", p.followees, since).
+		Order("note_update_time DESC").
+		Limit(recallLimit).
+		Pluck("note_id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		set.get(id, SourceFollowedAuthor).signals.FollowedAuthor = true
+	}
+	return nil
+}
+
+// recallFollowedTags 关注的标签下的笔记
+func recallFollowedTags(p *profile, set candidateSet) error {
+	if len(p.followedTagIDs) == 0 {
+		return nil
+	}
+	var rows []noteCount
+	if err := global.Db.Model(&models.TagNoteRelation{}).
+		Select("n_id, COUNT(*) AS count").
+		Where("t_id IN ?", p.followedTagIDs).
+		Group("n_id").
+		Order("n_id DESC").
+		Limit(recallLimit).
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		set.get(row.NID, SourceFollowedTag).signals.FollowedTags = int(row.Count)
+	}
+	return nil
+}
+
+// recallInterestTags 根据点赞/收藏过的笔记的标签召回
+func recallInterestTags(p *profile, set candidateSet) error {
+	engaged := p.engagedNoteIDs()
+	if len(engaged) == 0 {
+		return nil
+	}
+	type tagWeight struct {
+		TID   string
+		Count float64
+	}
+	var tags []tagWeight
+	if err := global.Db.Model(&models.TagNoteRelation{}).
+		Select("t_id, COUNT(*) AS count").
+		Where("n_id IN ?", engaged).
+		Group("t_id").
+		Order("count DESC").
+		Limit(interestTagLimit).
+		Scan(&tags).Error; err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	weights := make(map[string]float64, len(tags))
+	tagIDs := make([]string, 0, len(tags))
+	for _, t := range tags {
+		weights[t.TID] = t.Count / float64(len(engaged))
+		tagIDs = append(tagIDs, t.TID)
+	}
+
+	var relations []models.TagNoteRelation
+	if err := global.Db.Select("n_id", "t_id").
+		Where("t_id IN ?", tagIDs).
+		Order("n_id DESC").
+		Limit(recallLimit * 3).
+		Find(&relations).Error; err != nil {
+		return err
+	}
+	for _, r := range relations {
+		set.get(r.NID, SourceInterestTag).signals.InterestTags += weights[r.TID]
+	}
+	return nil
+}
+
+// recallCoEngagement 协同过滤：和我收藏/点赞过相同笔记的人，还收藏/点赞了哪些笔记
+func recallCoEngagement(p *profile, set candidateSet) error {
+	engaged := p.engagedNoteIDs()
+	if len(engaged) == 0 {
+		return nil
+	}
+
+	var similar []uint
+	if err := global.Db.Raw(`SELECT uid FROM (
+			SELECT uid FROM collects WHERE nid IN ? AND uid <> ?
+			UNION ALL
+			SELECT uid FROM likes WHERE nid IN ? AND uid <> ?
+		) AS t GROUP BY uid ORDER BY COUNT(*) DESC LIMIT ?`,
+		engaged, p.userID, engaged, p.userID, similarUserLimit).
+		Scan(&similar).Error; err != nil {
+		return err
+	}
+	if len(similar) == 0 {
+		return nil
+	}
+
+	var rows []noteCount
+	if err := global.Db.Raw(`SELECT nid AS n_id, SUM(w) AS count FROM (
+			SELECT nid, 1.0 AS w FROM collects WHERE uid IN ?
+			UNION ALL
+			SELECT nid, 0.5 AS w FROM likes WHERE uid IN ? AND nid IS NOT NULL
+		) AS t GROUP BY nid ORDER BY count DESC LIMIT ?`,
+		similar, similar, recallLimit).
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		set.get(row.NID, SourceCoEngagement).signals.CoEngagement = row.Count
+	}
+	return nil
+}
+
+// explorationPool 近期热门笔记，用于探索和冷启动
+func explorationPool(since int64) ([]models.Note, error) {
+	var notes []models.Note
+	err := global.Db.Where("note_update_time >= ?", since).
+		Order("score DESC").
+		Limit(explorationPoolCap).
+		Find(&notes).Error
+	if err == nil && len(notes) == 0 {
+		// 近期没有新笔记时退回全站热门
+		err = global.Db.Order("score DESC").Limit(explorationPoolCap).Find(&notes).Error
+	}
+	return notes, err
+}
+
+// sinceUnix 召回时间窗口的起点
+func sinceUnix() int64 {
+	return time.Now().AddDate(0, 0, -opts.CandidateDays).Unix()
+}
@@ -0,0 +1,51 @@
+package hotscore
+
+import (
+	"math"
+	"time"
+)
+
+// Weights 热度公式参数
+type Weights struct {
+	Like      float64 `json:"like_weight"`    // 点赞权重
+	Collect   float64 `json:"collect_weight"` // 收藏权重
+	Comment   float64 `json:"comment_weight"` // 评论权重
+	View      float64 `json:"view_weight"`    // 浏览权重
+	Gravity   float64 `json:"gravity"`        // 时间衰减指数，越大旧笔记掉得越快
+	HalfScore float64 `json:"half_score"`     // 原始分等于该值时映射为 50 分
+}
+
+// Engagement 一篇笔记的互动数
+type Engagement struct {
+	Likes    float64 `json:"likes"`
+	Collects float64 `json:"collects"`
+	Comments float64 `json:"comments"`
+	Views    float64 `json:"views"`
+}
+
+// weighted 互动数加权求和
+func (w Weights) weighted(e Engagement) float64 {
+	return w.Like*e.Likes + w.Collect*e.Collects + w.Comment*e.Comments + w.View*e.Views
+}
+
+// scale 把非负的原始分映射到 0~100，HalfScore 处为 50 分，越往上越难涨
+func (w Weights) scale(raw float64) float64 {
+	if raw <= 0 || w.HalfScore <= 0 {
+		return 0
+	}
+	return 100 * raw / (raw + w.HalfScore)
+}
+
+// Score 累计热度：加权互动数除以 (发布小时数 + 2) 的 Gravity 次方
+func (w Weights) Score(total Engagement, updateTime int64, now time.Time) float64 {
+	ageHours := now.Sub(time.Unix(updateTime, 0)).Hours()
+	if ageHours < 0 {
+		ageHours = 0
+	}
+	return w.scale(w.weighted(total) / math.Pow(ageHours+2, w.Gravity))
+}
+
+// Trending 窗口热度：只看最近窗口内的新增互动，不做时间衰减
+func (w Weights) Trending(window Engagement) float64 {
+	return w.scale(w.weighted(window))
+}
@@ -0,0 +1,310 @@
+package hotscore
+
+//笔记热度计算：只重算互动有变化的笔记（内存脏集合 + 数据库增量扫描兜底），
+//另外定期对仍有热度的笔记做一次衰减重算；结果按批用一条 UPDATE ... CASE 写回
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Options 热度任务参数
+type Options struct {
+	IntervalSeconds int     // 增量重算间隔（秒）
+	DecayMinutes    int     // 全量衰减重算间隔（分钟）
+	WindowHours     int     // 窗口热度统计的小时数
+	BatchSize       int     // 每批读写的笔记数
+	MinScore        float64 // 低于该值的分数写为 0，之后不再参与衰减重算
+	Weights         Weights
+}
+
+var opts = Options{
+	IntervalSeconds: 60,
+	DecayMinutes:    30,
+	WindowHours:     24,
+	BatchSize:       500,
+	MinScore:        0.01,
+	Weights: Weights{
+		Like:      1,
+		Collect:   2,
+		Comment:   1.5,
+		View:      0.05,
+		Gravity:   1.5,
+		HalfScore: 1,
+	},
+}
+
+var (
+	dirtyMu sync.Mutex
+	dirty   = make(map[uint]struct{})
+)
+
+// DefaultWeights 当前生效的热度公式参数
+func DefaultWeights() Weights {
+	return opts.Weights
+}
+
+// Init 设置参数并启动热度任务，未配置的项使用默认值，需在数据库初始化之后调用
+func Init(o Options) {
+	if o.IntervalSeconds > 0 {
+		opts.IntervalSeconds = o.IntervalSeconds
+	}
+	if o.DecayMinutes > 0 {
+		opts.DecayMinutes = o.DecayMinutes
+	}
+	if o.WindowHours > 0 {
+		opts.WindowHours = o.WindowHours
+	}
+	if o.BatchSize > 0 {
+		opts.BatchSize = o.BatchSize
+	}
+	if o.MinScore > 0 {
+		opts.MinScore = o.MinScore
+	}
+	opts.Weights = mergeWeights(opts.Weights, o.Weights)
+	go run()
+}
+
+// mergeWeights 用 override 中大于 0 的项覆盖 base
+func mergeWeights(base, override Weights) Weights {
+	for _, p := range []struct{ dst, src *float64 }{
+		{&base.Like, &override.Like},
+		{&base.Collect, &override.Collect},
+		{&base.Comment, &override.Comment},
+		{&base.View, &override.View},
+		{&base.Gravity, &override.Gravity},
+		{&base.HalfScore, &override.HalfScore},
+	} {
+		if *p.src > 0 {
+			*p.dst = *p.src
+		}
+	}
+	return base
+}
+
+// MarkDirty 标记笔记的互动有变化，下一轮增量重算时更新热度
+func MarkDirty(noteIDs ...uint) {
+	dirtyMu.Lock()
+	defer dirtyMu.Unlock()
+	for _, id := range noteIDs {
+		if id != 0 {
+			dirty[id] = struct{}{}
+		}
+	}
+}
+
+// takeDirty 取出并清空脏集合
+func takeDirty() map[uint]struct{} {
+	dirtyMu.Lock()
+	defer dirtyMu.Unlock()
+	taken := dirty
+	dirty = make(map[uint]struct{})
+	return taken
+}
+
+// run 热度任务主循环
+func run() {
+	ticker := time.NewTicker(time.Duration(opts.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	// 启动时全量重算一次（兼容旧数据和停机期间的变化），之后增量扫描从当前时间开始
+	lastScan := time.Now()
+	lastDecay := lastScan
+	if err := decay(true); err != nil {
+		log.Printf("热度衰减重算失败: %v", err)
+	}
+
+	for now := range ticker.C {
+		if err := incremental(lastScan); err != nil {
+			log.Printf("热度增量重算失败: %v", err)
+		} else {
+			lastScan = now
+		}
+		if now.Sub(lastDecay) >= time.Duration(opts.DecayMinutes)*time.Minute {
+			if err := decay(false); err != nil {
+				log.Printf("热度衰减重算失败: %v", err)
+			} else {
+				lastDecay = now
+			}
+		}
+	}
+}
+
+// incremental 重算脏集合以及 since 之后有新增点赞/收藏/评论的笔记。
+// 取消点赞、删除评论等删除操作只能靠 MarkDirty，新增操作有数据库扫描兜底；
+// 扫描走 likes.create_date、collects.create_date 和 comments.created_at 上的索引
+func incremental(since time.Time) error {
+	ids := takeDirty()
+	var scanned []uint
+	if err := global.Db.Raw(`SELECT nid FROM likes WHERE create_date >= ? AND nid IS NOT NULL
+			UNION SELECT nid FROM collects WHERE create_date >= ?
+			UNION SELECT note_id FROM comments WHERE created_at >= ?`,
+		since, since, since).Scan(&scanned).Error; err != nil {
+		MarkDirty(keys(ids)...)
+		return err
+	}
+	for _, id := range scanned {
+		ids[id] = struct{}{}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	list := keys(ids)
+	for start := 0; start < len(list); start += opts.BatchSize {
+		batch := list[start:min(start+opts.BatchSize, len(list))]
+		var notes []models.Note
+		if err := global.Db.Select("note_id", "note_update_time", "like_counts", "collect_counts", "comment_counts", "view_count", "score", "trending_score").
+			Where("note_id IN ?", batch).Find(&notes).Error; err != nil {
+			MarkDirty(list[start:]...)
+			return err
+		}
+		if err := recompute(notes, time.Now()); err != nil {
+			MarkDirty(list[start:]...)
+			return err
+		}
+	}
+	return nil
+}
+
+// decay 重算所有仍有热度的笔记，让分数随时间下降、窗口外的互动移出窗口热度；all 为 true 时重算全部笔记
+func decay(all bool) error {
+	query := global.Db.Select("note_id", "note_update_time", "like_counts", "collect_counts", "comment_counts", "view_count", "score", "trending_score")
+	if !all {
+		query = query.Where("score > 0 OR trending_score > 0")
+	}
+	var notes []models.Note
+	return query.FindInBatches(&notes, opts.BatchSize, func(tx *gorm.DB, batch int) error {
+		return recompute(notes, time.Now())
+	}).Error
+}
+
+// recompute 计算一批笔记的热度，只写回有变化的
+func recompute(notes []models.Note, now time.Time) error {
+	if len(notes) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, note.NoteID)
+	}
+	window, err := windowEngagement(ids, now)
+	if err != nil {
+		return err
+	}
+
+	var changed []scored
+	for _, note := range notes {
+		s := scored{
+			noteID:   note.NoteID,
+			score:    opts.round(opts.Weights.Score(lifetime(note), note.NoteUpdateTime, now)),
+			trending: opts.round(opts.Weights.Trending(window[note.NoteID])),
+		}
+		if s.score != note.Score || s.trending != note.TrendingScore {
+			changed = append(changed, s)
+		}
+	}
+	return write(changed)
+}
+
+// round 四舍五入到两位小数，过小的分数归零
+func (o Options) round(score float64) float64 {
+	if score < o.MinScore {
+		return 0
+	}
+	return float64(int64(score*100+0.5)) / 100
+}
+
+// lifetime 笔记的累计互动数
+func lifetime(note models.Note) Engagement {
+	return Engagement{
+		Likes:    float64(note.LikeCounts),
+		Collects: float64(note.CollectCounts),
+		Comments: float64(note.CommentCounts),
+		Views:    float64(note.ViewCount),
+	}
+}
+
+// windowEngagement 统计笔记在最近窗口内新增的点赞、收藏和评论数
+func windowEngagement(ids []uint, now time.Time) (map[uint]Engagement, error) {
+	since := now.Add(-time.Duration(opts.WindowHours) * time.Hour)
+	type row struct {
+		NID   uint
+		Kind  string
+		Count float64
+	}
+	var rows []row
+	if err := global.Db.Raw(`SELECT nid AS n_id, 'like' AS kind, COUNT(*) AS count FROM likes
+			WHERE nid IN ? AND create_date >= ? GROUP BY nid
+		UNION ALL
+		SELECT nid, 'collect', COUNT(*) FROM collects
+			WHERE nid IN ? AND create_date >= ? GROUP BY nid
+		UNION ALL
+		SELECT note_id, 'comment', COUNT(*) FROM comments
+			WHERE note_id IN ? AND created_at >= ? GROUP BY note_id`,
+		ids, since, ids, since, ids, since).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]Engagement, len(rows))
+	for _, r := range rows {
+		e := result[r.NID]
+		switch r.Kind {
+		case "like":
+			e.Likes = r.Count
+		case "collect":
+			e.Collects = r.Count
+		case "comment":
+			e.Comments = r.Count
+		}
+		result[r.NID] = e
+	}
+	return result, nil
+}
+
+// scored 待写回的热度
+type scored struct {
+	noteID   uint
+	score    float64
+	trending float64
+}
+
+// write 分批用 UPDATE ... CASE 写回，一批一条语句
+func write(items []scored) error {
+	for start := 0; start < len(items); start += opts.BatchSize {
+		batch := items[start:min(start+opts.BatchSize, len(items))]
+		var scoreCase, trendingCase strings.Builder
+		args := make([]interface{}, 0, len(batch)*5)
+		ids := make([]uint, 0, len(batch))
+		for _, item := range batch {
+			scoreCase.WriteString(" WHEN ? THEN ?")
+			args = append(args, item.noteID, item.score)
+		}
+		for _, item := range batch {
+			trendingCase.WriteString(" WHEN ? THEN ?")
+			args = append(args, item.noteID, item.trending)
+			ids = append(ids, item.noteID)
+		}
+		args = append(args, ids)
+		sql := fmt.Sprintf("UPDATE notes SET score = CASE note_id%s END, trending_score = CASE note_id%s END WHERE note_id IN ?",
+			scoreCase.String(), trendingCase.String())
+		if err := global.Db.Exec(sql, args...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func keys(set map[uint]struct{}) []uint {
+	list := make([]uint, 0, len(set))
+	for id := range set {
+		list = append(list, id)
+	}
+	return list
+}
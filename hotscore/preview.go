@@ -0,0 +1,56 @@
+package hotscore
+
+import (
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// PreviewItem 一篇笔记的热度预览
+type PreviewItem struct {
+	NoteID         uint       `json:"note_id"`
+	Lifetime       Engagement `json:"lifetime"`        // 累计互动数
+	Window         Engagement `json:"window"`          // 窗口内新增互动数
+	AgeHours       float64    `json:"age_hours"`       // 距更新时间的小时数
+	StoredScore    float64    `json:"stored_score"`    // 当前库中的热度分
+	StoredTrending float64    `json:"stored_trending"` // 当前库中的窗口热度分
+	Score          float64    `json:"score"`           // 按给定参数计算的热度分
+	Trending       float64    `json:"trending"`        // 按给定参数计算的窗口热度分
+}
+
+// Preview 按给定参数试算笔记热度，不写库；override 中大于 0 的项覆盖当前参数
+func Preview(noteIDs []uint, override Weights) (Weights, []PreviewItem, error) {
+	weights := mergeWeights(opts.Weights, override)
+	var notes []models.Note
+	if err := global.Db.Where("note_id IN ?", noteIDs).Order("note_id").Find(&notes).Error; err != nil {
+		return weights, nil, err
+	}
+	if len(notes) == 0 {
+		return weights, []PreviewItem{}, nil
+	}
+
+	now := time.Now()
+	ids := make([]uint, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, note.NoteID)
+	}
+	window, err := windowEngagement(ids, now)
+	if err != nil {
+		return weights, nil, err
+	}
+
+	items := make([]PreviewItem, 0, len(notes))
+	for _, note := range notes {
+		items = append(items, PreviewItem{
+			NoteID:         note.NoteID,
+			Lifetime:       lifetime(note),
+			Window:         window[note.NoteID],
+			AgeHours:       opts.round(now.Sub(time.Unix(note.NoteUpdateTime, 0)).Hours()),
+			StoredScore:    note.Score,
+			StoredTrending: note.TrendingScore,
+			Score:          opts.round(weights.Score(lifetime(note), note.NoteUpdateTime, now)),
+			Trending:       opts.round(weights.Trending(window[note.NoteID])),
+		})
+	}
+	return weights, items, nil
+}
@@ -0,0 +1,155 @@
+package imaging
+
+//图片处理流水线：按文件内容识别格式，按 EXIF 方向摆正，限制尺寸后重新编码（同时去掉 EXIF/GPS 等元数据），
+//并生成中图和缩略图两个规格。JPEG/PNG/WebP 都走同一流程；没有纯 Go 的 WebP 编码器，WebP 按是否透明转成 JPEG 或 PNG
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+// 规格名，同时用作对象文件名
+const (
+	Original  = "original"
+	Medium    = "medium"
+	Thumbnail = "thumb"
+)
+
+var (
+	// ErrUnsupported 内容不是支持的图片格式
+	ErrUnsupported = errors.New("不支持的图片格式，仅支持 jpg、png、webp")
+	// ErrTooLarge 文件或像素数超过上限
+	ErrTooLarge = errors.New("图片过大")
+)
+
+// Options 图片处理参数
+type Options struct {
+	MaxDimension       int   // 原图长边上限（像素），超过则等比缩小
+	MediumDimension    int   // 中图长边（像素）
+	ThumbnailDimension int   // 缩略图长边（像素）
+	Quality            int   // JPEG 编码质量 1~100
+	MaxPixels          int   // 解码前检查的像素数上限，防止解压炸弹
+	MaxBytes           int64 // 上传文件大小上限（字节）
+}
+
+var opts = Options{
+	MaxDimension:       2048,
+	MediumDimension:    1080,
+	ThumbnailDimension: 360,
+	Quality:            82,
+	MaxPixels:          50_000_000,
+	MaxBytes:           20 << 20,
+}
+
+// Init 设置参数，未配置的项使用默认值
+func Init(o Options) {
+	if o.MaxDimension > 0 {
+		opts.MaxDimension = o.MaxDimension
+	}
+	if o.MediumDimension > 0 {
+		opts.MediumDimension = o.MediumDimension
+	}
+	if o.ThumbnailDimension > 0 {
+		opts.ThumbnailDimension = o.ThumbnailDimension
+	}
+	if o.Quality > 0 && o.Quality <= 100 {
+		opts.Quality = o.Quality
+	}
+	if o.MaxPixels > 0 {
+		opts.MaxPixels = o.MaxPixels
+	}
+	if o.MaxBytes > 0 {
+		opts.MaxBytes = o.MaxBytes
+	}
+}
+
+// Variant 一个规格的编码结果
+type Variant struct {
+	Name   string
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// Result 处理结果，Variants 第一项为原图
+type Result struct {
+	Format      string // 识别出的输入格式：jpeg/png/webp
+	ContentType string // 输出的内容类型
+	Ext         string // 输出的扩展名
+	Width       int    // 处理后原图的宽
+	Height      int    // 处理后原图的高
+	Variants    []Variant
+}
+
+// Process 处理一张图片
+func Process(data []byte) (*Result, error) {
+	if int64(len(data)) > opts.MaxBytes {
+		return nil, ErrTooLarge
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png" && format != "webp") {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width*cfg.Height > opts.MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	switch format {
+	case "jpeg":
+		img = orient(img, exifOrientation(data))
+	case "webp":
+		img = orient(img, webpOrientation(data))
+	}
+
+	// 不透明的图统一转成 JPEG，带透明通道的保留 PNG
+	result := &Result{Format: format, ContentType: "image/jpeg", Ext: ".jpg"}
+	if !isOpaque(img) {
+		result.ContentType, result.Ext = "image/png", ".png"
+	}
+
+	original := fit(img, opts.MaxDimension)
+	medium := fit(original, opts.MediumDimension)
+	thumb := fit(medium, opts.ThumbnailDimension)
+	for _, v := range []struct {
+		name string
+		img  image.Image
+	}{{Original, original}, {Medium, medium}, {Thumbnail, thumb}} {
+		encoded, err := encode(v.img, result.Ext)
+		if err != nil {
+			return nil, err
+		}
+		b := v.img.Bounds()
+		result.Variants = append(result.Variants, Variant{Name: v.name, Data: encoded, Width: b.Dx(), Height: b.Dy()})
+	}
+	result.Width, result.Height = result.Variants[0].Width, result.Variants[0].Height
+	return result, nil
+}
+
+// isOpaque 判断图片是否完全不透明
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// encode 按扩展名编码
+func encode(img image.Image, ext string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if ext == ".png" {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: opts.Quality})
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,289 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+)
+
+// gpsMarker 写进 EXIF 的假定位信息，处理后的图片中不应再出现
+const gpsMarker = "GPS 23.0965N 113.2985E"
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// withOptions 临时替换处理参数
+func withOptions(t *testing.T, o Options) {
+	t.Helper()
+	previous := opts
+	opts = o
+	t.Cleanup(func() { opts = previous })
+}
+
+// halves 左半边红、右半边蓝的图片
+func halves(w, h int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 255, A: alpha}
+			if x >= w/2 {
+				c = color.NRGBA{B: 255, A: alpha}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// tiffExif 只含方向标记的大端 TIFF 数据，末尾附带 gpsMarker
+func tiffExif(orientation int) []byte {
+	var b bytes.Buffer
+	b.WriteString("MM\x00\x2a")
+	binary.Write(&b, binary.BigEndian, uint32(8))      // 第一个 IFD 的偏移
+	binary.Write(&b, binary.BigEndian, uint16(1))      // 条目数
+	binary.Write(&b, binary.BigEndian, uint16(0x0112)) // Orientation
+	binary.Write(&b, binary.BigEndian, uint16(3))      // SHORT
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	binary.Write(&b, binary.BigEndian, uint16(orientation))
+	binary.Write(&b, binary.BigEndian, uint16(0))
+	binary.Write(&b, binary.BigEndian, uint32(0)) // 没有下一个 IFD
+	b.WriteString(gpsMarker)
+	return b.Bytes()
+}
+
+// jpegWithExif 编码 JPEG 并在 SOI 之后插入带方向标记的 APP1 段
+func jpegWithExif(t *testing.T, img image.Image, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	segment := append([]byte("Exif\x00\x00"), tiffExif(orientation)...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	data := append([]byte{}, buf.Bytes()[:2]...)
+	data = append(data, app1...)
+	data = append(data, segment...)
+	return append(data, buf.Bytes()[2:]...)
+}
+
+// webpChunk 编码一个 RIFF 块，奇数长度补一个字节
+func webpChunk(fourcc string, payload []byte) []byte {
+	chunk := append([]byte(fourcc), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// webpWithExif 把简单格式的有损 WebP 改写成带 EXIF 块的扩展格式
+func webpWithExif(t *testing.T, simple []byte, w, h, orientation int) []byte {
+	t.Helper()
+	if string(simple[12:16]) != "VP8 " {
+		t.Fatal("需要简单格式的有损 WebP")
+	}
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 // 含 EXIF
+	vp8x[4], vp8x[5], vp8x[6] = byte(w-1), byte((w-1)>>8), byte((w-1)>>16)
+	vp8x[7], vp8x[8], vp8x[9] = byte(h-1), byte((h-1)>>8), byte((h-1)>>16)
+
+	body := []byte("WEBP")
+	body = append(body, webpChunk("VP8X", vp8x)...)
+	body = append(body, simple[12:]...)
+	body = append(body, webpChunk("EXIF", tiffExif(orientation))...)
+	data := append([]byte("RIFF"), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(body)))
+	return append(data, body...)
+}
+
+// near 颜色是否接近（有损编码有误差）
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(v uint32, w uint8) bool {
+		d := int(v>>8) - int(w)
+		return d > -60 && d < 60
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
+
+// decodeVariant 解码处理结果中的一个规格
+func decodeVariant(t *testing.T, v Variant) image.Image {
+	t.Helper()
+	img, _, err := image.Decode(bytes.NewReader(v.Data))
+	if err != nil {
+		t.Fatalf("解码 %s 失败: %v", v.Name, err)
+	}
+	return img
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestProcessEXIFOrientation(t *testing.T) {
+	cases := []struct {
+		orientation int
+		w, h        int
+		topLeft     color.RGBA // 摆正后左上角的颜色
+		bottomRight color.RGBA
+	}{
+		{1, 64, 32, red, blue},
+		{2, 64, 32, blue, red}, // 水平翻转
+		{3, 64, 32, blue, red}, // 旋转 180°
+		{6, 32, 64, red, blue}, // 顺时针 90°，原来的左半边到了上方
+		{8, 32, 64, blue, red}, // 逆时针 90°，原来的左半边到了下方
+		{9, 64, 32, red, blue}, // 非法值按 1 处理
+	}
+	for _, c := range cases {
+		result, err := Process(jpegWithExif(t, halves(64, 32, 255), c.orientation))
+		if err != nil {
+			t.Fatalf("方向 %d: %v", c.orientation, err)
+		}
+		if result.Width != c.w || result.Height != c.h {
+			t.Errorf("方向 %d: 尺寸 %dx%d，want %dx%d", c.orientation, result.Width, result.Height, c.w, c.h)
+			continue
+		}
+		img := decodeVariant(t, result.Variants[0])
+		if !near(img.At(2, 2), c.topLeft) || !near(img.At(c.w-3, c.h-3), c.bottomRight) {
+			t.Errorf("方向 %d: 左上 %v 右下 %v，want %v %v", c.orientation, img.At(2, 2), img.At(c.w-3, c.h-3), c.topLeft, c.bottomRight)
+		}
+	}
+}
+
+func TestProcessStripsMetadata(t *testing.T) {
+	lossy := readFixture(t, "blue-purple-pink.lossy.webp")
+	inputs := map[string][]byte{
+		"jpeg": jpegWithExif(t, halves(64, 32, 255), 1),
+		"webp": webpWithExif(t, lossy, 150, 100, 1),
+	}
+	for name, data := range inputs {
+		if !bytes.Contains(data, []byte(gpsMarker)) {
+			t.Fatalf("%s: 测试图片应带有 EXIF", name)
+		}
+		result, err := Process(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, v := range result.Variants {
+			if bytes.Contains(v.Data, []byte(gpsMarker)) || bytes.Contains(v.Data, []byte("Exif\x00\x00")) {
+				t.Errorf("%s 的 %s 规格仍带有 EXIF", name, v.Name)
+			}
+		}
+	}
+}
+
+func TestProcessWebP(t *testing.T) {
+	lossy := readFixture(t, "blue-purple-pink.lossy.webp")
+	cases := []struct {
+		name        string
+		data        []byte
+		contentType string
+		w, h        int
+	}{
+		{"有损 WebP 转 JPEG", lossy, "image/jpeg", 150, 100},
+		{"带透明通道的 WebP 转 PNG", readFixture(t, "yellow_rose.lossy-with-alpha.webp"), "image/png", 400, 301},
+		{"EXIF 方向随图摆正", webpWithExif(t, lossy, 150, 100, 6), "image/jpeg", 100, 150},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := Process(c.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Format != "webp" || result.ContentType != c.contentType {
+				t.Errorf("格式 %s → %s，want webp → %s", result.Format, result.ContentType, c.contentType)
+			}
+			if result.Width != c.w || result.Height != c.h {
+				t.Errorf("尺寸 %dx%d，want %dx%d", result.Width, result.Height, c.w, c.h)
+			}
+			if len(result.Variants) != 3 {
+				t.Errorf("应生成 3 个规格，实际 %d 个", len(result.Variants))
+			}
+			for _, v := range result.Variants {
+				if _, format, err := image.DecodeConfig(bytes.NewReader(v.Data)); err != nil || "image/"+format != c.contentType {
+					t.Errorf("%s 规格编码为 %s（%v）", v.Name, format, err)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessVariantSizes(t *testing.T) {
+	withOptions(t, Options{MaxDimension: 100, MediumDimension: 50, ThumbnailDimension: 20, Quality: 80, MaxPixels: 1_000_000, MaxBytes: 1 << 20})
+
+	encodePNG := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		png.Encode(&buf, img)
+		return buf.Bytes()
+	}
+	cases := []struct {
+		name        string
+		data        []byte
+		contentType string
+		sizes       [3][2]int // 原图、中图、缩略图
+	}{
+		{"横图按长边缩小", encodePNG(halves(300, 150, 255)), "image/jpeg", [3][2]int{{100, 50}, {50, 25}, {20, 10}}},
+		{"竖图按长边缩小", encodePNG(halves(90, 180, 255)), "image/jpeg", [3][2]int{{50, 100}, {25, 50}, {10, 20}}},
+		{"小图不放大", encodePNG(halves(16, 8, 255)), "image/jpeg", [3][2]int{{16, 8}, {16, 8}, {16, 8}}},
+		{"透明图保留 PNG", encodePNG(halves(60, 30, 128)), "image/png", [3][2]int{{60, 30}, {50, 25}, {20, 10}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := Process(c.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.ContentType != c.contentType {
+				t.Errorf("内容类型 %s，want %s", result.ContentType, c.contentType)
+			}
+			names := []string{Original, Medium, Thumbnail}
+			for i, v := range result.Variants {
+				if v.Name != names[i] {
+					t.Errorf("第 %d 个规格为 %s，want %s", i, v.Name, names[i])
+				}
+				b := decodeVariant(t, v).Bounds()
+				if got := [2]int{v.Width, v.Height}; got != c.sizes[i] || b.Dx() != v.Width || b.Dy() != v.Height {
+					t.Errorf("%s 规格 %v（实际编码 %dx%d），want %v", v.Name, got, b.Dx(), b.Dy(), c.sizes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	withOptions(t, Options{MaxDimension: 100, MediumDimension: 50, ThumbnailDimension: 20, Quality: 80, MaxPixels: 10_000, MaxBytes: 1 << 20})
+
+	var big bytes.Buffer
+	png.Encode(&big, image.NewGray(image.Rect(0, 0, 200, 100)))
+	cases := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"不是图片", []byte("GIF89a not really"), ErrUnsupported},
+		{"扩展名对但内容不是 WebP", []byte("RIFF\x10\x00\x00\x00WEBPVP8 \x04\x00\x00\x00abcd"), ErrUnsupported},
+		{"像素数超过上限", big.Bytes(), ErrTooLarge},
+		{"WebP 像素数超过上限", readFixture(t, "yellow_rose.lossy-with-alpha.webp"), ErrTooLarge},
+		{"文件超过大小上限", make([]byte, 1<<20+1), ErrTooLarge},
+	}
+	for _, c := range cases {
+		if _, err := Process(c.data); !errors.Is(err, c.want) {
+			t.Errorf("%s: 返回 %v，want %v", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// toRGBA 转成原点在 (0,0) 的 RGBA（预乘透明度）
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+	return dst
+}
+
+// fit 等比缩小到长边不超过 max，本身不超过时原样返回
+func fit(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return img
+	}
+	scale := float64(max) / float64(w)
+	if h > w {
+		scale = float64(max) / float64(h)
+	}
+	dw := int(math.Max(1, math.Round(float64(w)*scale)))
+	dh := int(math.Max(1, math.Round(float64(h)*scale)))
+	return resize(img, dw, dh)
+}
+
+// tap 目标像素取样的一个源像素及其权重
+type tap struct {
+	index  int
+	weight float32
+}
+
+// boxWeights 区域平均缩小：每个目标像素按覆盖面积对源像素加权
+func boxWeights(src, dst int) [][]tap {
+	scale := float64(src) / float64(dst)
+	weights := make([][]tap, dst)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < src && float64(j) < end; j++ {
+			overlap := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if overlap > 0 {
+				weights[i] = append(weights[i], tap{index: j, weight: float32(overlap / scale)})
+			}
+		}
+	}
+	return weights
+}
+
+// resize 缩小到 dw×dh，先横向再纵向
+func resize(img image.Image, dw, dh int) *image.RGBA {
+	src := toRGBA(img)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	xw, yw := boxWeights(sw, dw), boxWeights(sh, dh)
+
+	tmp := make([]float32, dw*sh*4)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, taps := range xw {
+			o := (y*dw + x) * 4
+			for _, t := range taps {
+				i := t.index * 4
+				tmp[o] += float32(row[i]) * t.weight
+				tmp[o+1] += float32(row[i+1]) * t.weight
+				tmp[o+2] += float32(row[i+2]) * t.weight
+				tmp[o+3] += float32(row[i+3]) * t.weight
+			}
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y, taps := range yw {
+		for x := 0; x < dw; x++ {
+			var c [4]float32
+			for _, t := range taps {
+				o := (t.index*dw + x) * 4
+				c[0] += tmp[o] * t.weight
+				c[1] += tmp[o+1] * t.weight
+				c[2] += tmp[o+2] * t.weight
+				c[3] += tmp[o+3] * t.weight
+			}
+			d := y*dst.Stride + x*4
+			a := clamp8(c[3])
+			// 预乘格式要求颜色分量不超过透明度
+			dst.Pix[d] = min(clamp8(c[0]), a)
+			dst.Pix[d+1] = min(clamp8(c[1]), a)
+			dst.Pix[d+2] = min(clamp8(c[2]), a)
+			dst.Pix[d+3] = a
+		}
+	}
+	return dst
+}
+
+func clamp8(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// exifOrientation 读取 JPEG 中 EXIF 的方向标记（1~8），没有或无法解析时返回 1
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // 填充字节
+			i++
+			continue
+		}
+		if marker == 0xD9 || marker == 0xDA { // 到图像数据为止
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation 在 EXIF 的第一个 IFD 中查找方向标记 0x0112
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(t[4:8]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 1
+	}
+	n := int(order.Uint16(t[ifd:]))
+	for k := 0; k < n; k++ {
+		e := ifd + 2 + k*12
+		if e+12 > len(t) {
+			break
+		}
+		if order.Uint16(t[e:]) == 0x0112 {
+			if v := int(order.Uint16(t[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient 按 EXIF 方向标记摆正图片
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := toRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // 5~8 需要交换宽高
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // 水平翻转
+				sx, sy = w-1-x, y
+			case 3: // 旋转 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				sx, sy = x, h-1-y
+			case 5: // 沿主对角线翻转
+				sx, sy = y, x
+			case 6: // 顺时针旋转 90°
+				sx, sy = y, h-1-x
+			case 7: // 沿副对角线翻转
+				sx, sy = w-1-y, h-1-x
+			case 8: // 逆时针旋转 90°
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:])
+		}
+	}
+	return dst
+}
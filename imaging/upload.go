@@ -0,0 +1,125 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"path"
+	"strings"
+	"travel-from-sysu-backend/storage"
+
+	"github.com/google/uuid"
+)
+
+// Uploaded 上传后的图片信息，没有生成的规格使用原图地址
+type Uploaded struct {
+	URL          string `json:"url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	MediumURL    string `json:"medium_url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"` // 原图的内容类型
+	Size         int64  `json:"size"`         // 原图大小（字节）
+	Hash         string `json:"hash"`         // 原图的 SHA-256（十六进制）
+}
+
+// Upload 读取表单中的图片，处理后写入对象存储的 directory 目录
+func Upload(file *multipart.FileHeader, directory string) (Uploaded, error) {
+	if file.Size > opts.MaxBytes {
+		return Uploaded{}, ErrTooLarge
+	}
+	src, err := file.Open()
+	if err != nil {
+		return Uploaded{}, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer src.Close()
+
+	result, err := Read(src)
+	if err != nil {
+		return Uploaded{}, err
+	}
+	return Store(result, directory)
+}
+
+// Read 读取并处理图片，超过大小上限时返回 ErrTooLarge
+func Read(r io.Reader) (*Result, error) {
+	data, err := io.ReadAll(io.LimitReader(r, opts.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	return Process(data)
+}
+
+// Store 把处理结果写入对象存储。有多个规格时放在 <directory>/<uuid>/ 下并以规格命名，
+// 只有原图时保存为 <directory>/<uuid><ext>；任一写入失败会删除已写入的对象
+func Store(result *Result, directory string) (Uploaded, error) {
+	base := strings.Trim(directory, "/") + "/" + uuid.New().String()
+	s := storage.Default()
+
+	original := result.Variants[0].Data
+	sum := sha256.Sum256(original)
+	up := Uploaded{
+		Width:       result.Width,
+		Height:      result.Height,
+		ContentType: result.ContentType,
+		Size:        int64(len(original)),
+		Hash:        hex.EncodeToString(sum[:]),
+	}
+	var written []string
+	for _, v := range result.Variants {
+		key := base + result.Ext
+		if len(result.Variants) > 1 {
+			key = base + "/" + v.Name + result.Ext
+		}
+		if err := s.Put(key, bytes.NewReader(v.Data), int64(len(v.Data)), result.ContentType); err != nil {
+			for _, k := range written {
+				if err := s.Delete(k); err != nil {
+					log.Printf("删除图片 %s 失败: %v", k, err)
+				}
+			}
+			return Uploaded{}, fmt.Errorf("图片上传失败: %v", err)
+		}
+		written = append(written, key)
+
+		switch v.Name {
+		case Original:
+			up.URL = s.URL(key)
+		case Medium:
+			up.MediumURL = s.URL(key)
+		case Thumbnail:
+			up.ThumbnailURL = s.URL(key)
+		}
+	}
+	if up.MediumURL == "" {
+		up.MediumURL = up.URL
+	}
+	if up.ThumbnailURL == "" {
+		up.ThumbnailURL = up.URL
+	}
+	return up, nil
+}
+
+// VariantURL 由原图地址推出指定规格的地址；不是流水线生成的多规格原图（旧数据，包括以前原样保存的 WebP）时返回原地址
+func VariantURL(url, name string) string {
+	ext := path.Ext(url)
+	base := strings.TrimSuffix(url, ext)
+	if !strings.HasSuffix(base, "/"+Original) {
+		return url
+	}
+	return strings.TrimSuffix(base, Original) + name + ext
+}
+
+// VariantURLs 原图对应的所有规格地址，删除图片时一并清理
+func VariantURLs(url string) []string {
+	urls := []string{url}
+	for _, name := range []string{Medium, Thumbnail} {
+		if v := VariantURL(url, name); v != url {
+			urls = append(urls, v)
+		}
+	}
+	return urls
+}
@@ -0,0 +1,28 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器，供 image.Decode 识别
+)
+
+// webpOrientation 读取 WebP 中 EXIF 块的方向标记（1~8），没有或无法解析时返回 1
+func webpOrientation(data []byte) int {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 1
+	}
+	for i := 12; i+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size
+		if size < 0 || end > len(data) {
+			return 1
+		}
+		if string(data[i:i+4]) == "EXIF" {
+			// 规范要求块内直接是 TIFF 数据，部分编码器会多带 "Exif\0\0" 前缀
+			return tiffOrientation(bytes.TrimPrefix(data[i+8:end], []byte("Exif\x00\x00")))
+		}
+		i = end + size%2 // 块按偶数字节对齐
+	}
+	return 1
+}
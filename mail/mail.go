@@ -0,0 +1,66 @@
+package mail
+
+//邮件发送：统一的 Transport 接口，生产环境使用 SMTP；内存实现只记录不发送，需显式配置，仅用于测试和本地调试
+
+import (
+	"fmt"
+	"log"
+)
+
+// Message 一封邮件，同时带 HTML 和纯文本正文
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+	Headers map[string]string // 额外的邮件头，如 List-Unsubscribe
+}
+
+// Transport 邮件发送通道
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// DefaultTransport 全局邮件发送通道，未初始化时发送会返回错误
+var DefaultTransport Transport
+
+// SMTPConfig SMTP 连接参数
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// InitTransport 按驱动名初始化全局发送通道：smtp 或 memory。
+// 未配置驱动时不创建发送通道，依赖邮件的功能不会启动；未知的驱动返回错误
+func InitTransport(driver string, cfg SMTPConfig) error {
+	switch driver {
+	case "smtp":
+		DefaultTransport = NewSMTPTransport(cfg)
+	case "memory":
+		log.Printf("已启用内存邮件通道，邮件只记录不发送，请勿在生产环境开启")
+		DefaultTransport = NewMemoryTransport()
+	case "":
+		log.Printf("未配置邮件驱动，邮件不会发出")
+		DefaultTransport = nil
+	default:
+		return fmt.Errorf("未知的邮件驱动 %q", driver)
+	}
+	return nil
+}
+
+// Enabled 是否已初始化发送通道
+func Enabled() bool {
+	return DefaultTransport != nil
+}
+
+// Send 通过全局发送通道发送邮件
+func Send(msg *Message) error {
+	if DefaultTransport == nil {
+		return fmt.Errorf("邮件发送通道未初始化")
+	}
+	return DefaultTransport.Send(msg)
+}
@@ -0,0 +1,31 @@
+package mail
+
+import "testing"
+
+func TestInitTransport(t *testing.T) {
+	previous := DefaultTransport
+	t.Cleanup(func() { DefaultTransport = previous })
+
+	if err := InitTransport("", SMTPConfig{}); err != nil || Enabled() {
+		t.Fatalf("未配置驱动时不应创建发送通道: err=%v enabled=%v", err, Enabled())
+	}
+	if err := Send(&Message{To: "a@example.com"}); err == nil {
+		t.Fatal("没有发送通道时发送应返回错误")
+	}
+	if err := InitTransport("sendmail", SMTPConfig{}); err == nil {
+		t.Fatal("未知驱动应返回错误")
+	}
+	if err := InitTransport("memory", SMTPConfig{}); err != nil || !Enabled() {
+		t.Fatalf("显式配置 memory 时应启用内存通道: err=%v", err)
+	}
+}
+
+func TestMemoryTransportLimit(t *testing.T) {
+	transport := NewMemoryTransport()
+	for i := 0; i < memoryLimit+10; i++ {
+		transport.Send(&Message{Subject: "s"})
+	}
+	if n := len(transport.Sent()); n != memoryLimit {
+		t.Fatalf("内存通道应最多保留 %d 封，实际 %d", memoryLimit, n)
+	}
+}
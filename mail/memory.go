@@ -0,0 +1,42 @@
+package mail
+
+import "sync"
+
+// memoryLimit 内存通道最多保留的邮件数，超出后丢弃最早的
+const memoryLimit = 1000
+
+// MemoryTransport 把邮件保存在内存里，不真正发送，用于测试和本地调试
+type MemoryTransport struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewMemoryTransport 创建内存发送通道
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+// Send 记录邮件
+func (t *MemoryTransport) Send(msg *Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.sent) >= memoryLimit {
+		t.sent = t.sent[1:]
+	}
+	t.sent = append(t.sent, *msg)
+	return nil
+}
+
+// Sent 返回已记录邮件的副本
+func (t *MemoryTransport) Sent() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message(nil), t.sent...)
+}
+
+// Reset 清空已记录的邮件
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent = nil
+}
@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SMTPTransport 通过 SMTP 发送邮件，发送 multipart/alternative 格式（纯文本 + HTML）
+type SMTPTransport struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPTransport 创建 SMTP 发送通道
+func NewSMTPTransport(cfg SMTPConfig) *SMTPTransport {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	return &SMTPTransport{cfg: cfg}
+}
+
+// Send 发送邮件，服务器支持 STARTTLS 时 net/smtp 会自动升级
+func (t *SMTPTransport) Send(msg *Message) error {
+	from := msg.From
+	if from == "" {
+		from = t.cfg.From
+	}
+
+	var auth smtp.Auth
+	if t.cfg.Username != "" {
+		auth = smtp.PlainAuth("", t.cfg.Username, t.cfg.Password, t.cfg.Host)
+	}
+
+	body, err := buildMIME(from, msg)
+	if err != nil {
+		return err
+	}
+	// 信封地址只能是纯邮箱地址，不能带显示名
+	envelopeFrom := from
+	if parsed, err := netmail.ParseAddress(from); err == nil {
+		envelopeFrom = parsed.Address
+	}
+	addr := t.cfg.Host + ":" + strconv.Itoa(t.cfg.Port)
+	return smtp.SendMail(addr, auth, envelopeFrom, []string{msg.To}, body)
+}
+
+// buildMIME 组装 multipart/alternative 邮件原文
+func buildMIME(from string, msg *Message) ([]byte, error) {
+	boundaryBytes := make([]byte, 12)
+	if _, err := rand.Read(boundaryBytes); err != nil {
+		return nil, err
+	}
+	boundary := "alt-" + hex.EncodeToString(boundaryBytes)
+
+	var buf bytes.Buffer
+	headers := map[string]string{
+		"From":         from,
+		"To":           msg.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"MIME-Version": "1.0",
+		"Content-Type": fmt.Sprintf("multipart/alternative; boundary=%q", boundary),
+	}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, headers[k])
+	}
+	buf.WriteString("\r\n")
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, part := range parts {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", part.contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
@@ -17,15 +17,167 @@ package main
 
 import (
 	"github.com/gin-gonic/gin"
+	"log"
+	"travel-from-sysu-backend/analytics"
 	"travel-from-sysu-backend/config"
+	"travel-from-sysu-backend/digest"
+	"travel-from-sysu-backend/feed"
+	"travel-from-sysu-backend/hotscore"
+	"travel-from-sysu-backend/imaging"
+	"travel-from-sysu-backend/mail"
+	"travel-from-sysu-backend/media"
+	"travel-from-sysu-backend/push"
+	"travel-from-sysu-backend/realtime"
+	"travel-from-sysu-backend/resumable"
 	"travel-from-sysu-backend/router"
-	"travel-from-sysu-backend/utils"
+	"travel-from-sysu-backend/search"
+	"travel-from-sysu-backend/storage"
+	"travel-from-sysu-backend/timeline"
+	"travel-from-sysu-backend/views"
 )
 
 func main() {
-	go utils.UpdateHotRecommendations()
-
 	config.InitConfig()
+	storageCfg := config.AppCongfig.Storage
+	if err := storage.Init(storage.Config{
+		Driver: storageCfg.Driver,
+		Local:  storage.LocalConfig(storageCfg.Local),
+		Aliyun: storage.AliyunConfig(storageCfg.Aliyun),
+		S3:     storage.S3Config(storageCfg.S3),
+	}); err != nil {
+		log.Fatalf("初始化对象存储失败: %v", err)
+	}
+	imagingCfg := config.AppCongfig.Imaging
+	imaging.Init(imaging.Options{
+		MaxDimension:       imagingCfg.MaxDimension,
+		MediumDimension:    imagingCfg.MediumDimension,
+		ThumbnailDimension: imagingCfg.ThumbnailDimension,
+		Quality:            imagingCfg.Quality,
+		MaxPixels:          imagingCfg.MaxMegapixels * 1_000_000,
+		MaxBytes:           int64(imagingCfg.MaxMegabytes) << 20,
+	})
+	mediaCfg := config.AppCongfig.Media
+	media.Init(media.Options{
+		PresignMinutes: mediaCfg.PresignMinutes,
+		MaxImageMB:     mediaCfg.MaxImageMB,
+		MaxVideoMB:     mediaCfg.MaxVideoMB,
+
+		GCIntervalMinutes: mediaCfg.GCIntervalMinutes,
+		GCGraceHours:      mediaCfg.GCGraceHours,
+		GCBatchSize:       mediaCfg.GCBatchSize,
+		GCDryRun:          mediaCfg.GCDryRun,
+	})
+	resumableCfg := config.AppCongfig.Resumable
+	resumable.Init(resumable.Options{
+		TempDir:        resumableCfg.TempDir,
+		SessionHours:   resumableCfg.SessionHours,
+		MaxSizeMB:      resumableCfg.MaxSizeMB,
+		MaxChunkMB:     resumableCfg.MaxChunkMB,
+		CleanupMinutes: resumableCfg.CleanupMinutes,
+	})
+	realtime.InitHub(config.AppCongfig.Realtime.SendBuffer, config.AppCongfig.Realtime.HeartbeatSeconds)
+	realtime.SetAllowedOrigins(config.AppCongfig.Realtime.AllowedOrigins)
+	mailCfg := config.AppCongfig.Mail
+	if err := mail.InitTransport(mailCfg.Driver, mail.SMTPConfig{
+		Host:     mailCfg.Host,
+		Port:     mailCfg.Port,
+		Username: mailCfg.Username,
+		Password: mailCfg.Password,
+		From:     mailCfg.From,
+	}); err != nil {
+		log.Fatalf("初始化邮件通道失败: %v", err)
+	}
+	go digest.Run()
+	if pushCfg := config.AppCongfig.Push; pushCfg.Enabled {
+		push.Init(push.Options{
+			Workers:       pushCfg.Workers,
+			QueueSize:     pushCfg.QueueSize,
+			MaxRetries:    pushCfg.MaxRetries,
+			BackoffMillis: pushCfg.BackoffMillis,
+			SweepSeconds:  pushCfg.SweepSeconds,
+			Fake:          pushCfg.Fake,
+			APNs: push.APNsConfig{
+				KeyFile:    pushCfg.APNs.KeyFile,
+				KeyID:      pushCfg.APNs.KeyID,
+				TeamID:     pushCfg.APNs.TeamID,
+				Topic:      pushCfg.APNs.Topic,
+				Production: pushCfg.APNs.Production,
+			},
+			FCM: push.FCMConfig{
+				CredentialsFile: pushCfg.FCM.CredentialsFile,
+				ProjectID:       pushCfg.FCM.ProjectID,
+			},
+		})
+	}
+	searchCfg := config.AppCongfig.Search
+	search.Init(search.Options{
+		ScoreWeight:    searchCfg.ScoreWeight,
+		RefreshMinutes: searchCfg.RefreshMinutes,
+		SnippetLength:  searchCfg.SnippetLength,
+	})
+	search.InitHistory(search.HistoryOptions{
+		HistoryLimit:     searchCfg.HistoryLimit,
+		DedupeMinutes:    searchCfg.DedupeMinutes,
+		RetentionDays:    searchCfg.LogRetentionDays,
+		TrendingMinUsers: searchCfg.TrendingMinUsers,
+		SuggestMinCount:  searchCfg.SuggestMinCount,
+		SuggestMinUsers:  searchCfg.SuggestMinUsers,
+		Blocklist:        searchCfg.Blocklist,
+	})
+	feedCfg := config.AppCongfig.Feed
+	var experiments []feed.Experiment
+	for _, e := range feedCfg.Experiments {
+		experiment := feed.Experiment{Name: e.Name, Traffic: e.Traffic}
+		for _, v := range e.Variants {
+			experiment.Variants = append(experiment.Variants, feed.Variant{Name: v.Name, Ranker: v.Ranker, Weight: v.Weight})
+		}
+		experiments = append(experiments, experiment)
+	}
+	feed.Init(feed.Options{
+		CandidateDays:           feedCfg.CandidateDays,
+		SeenDays:                feedCfg.SeenDays,
+		ExplorationRate:         feedCfg.ExplorationRate,
+		ImpressionRetentionDays: feedCfg.ImpressionRetentionDays,
+		DefaultRanker:           feedCfg.DefaultRanker,
+		Experiments:             experiments,
+	})
+	timelineCfg := config.AppCongfig.Timeline
+	timeline.Init(timeline.Options{
+		BigAccountFans: timelineCfg.BigAccountFans,
+		MaxEntries:     timelineCfg.MaxEntries,
+		BackfillCount:  timelineCfg.BackfillCount,
+		BatchSize:      timelineCfg.BatchSize,
+		TrimMinutes:    timelineCfg.TrimMinutes,
+	})
+	analyticsCfg := config.AppCongfig.Analytics
+	analytics.Init(analytics.Options{
+		IntervalMinutes: analyticsCfg.IntervalMinutes,
+		RecomputeDays:   analyticsCfg.RecomputeDays,
+		BackfillDays:    analyticsCfg.BackfillDays,
+	})
+	viewsCfg := config.AppCongfig.Views
+	views.Init(views.Options{
+		DedupeMinutes: viewsCfg.DedupeMinutes,
+		FlushSeconds:  viewsCfg.FlushSeconds,
+		BatchSize:     viewsCfg.BatchSize,
+		DevicesPerIP:  viewsCfg.DevicesPerIP,
+	})
+	if hotCfg := config.AppCongfig.HotScore; hotCfg.Enabled {
+		hotscore.Init(hotscore.Options{
+			IntervalSeconds: hotCfg.IntervalSeconds,
+			DecayMinutes:    hotCfg.DecayMinutes,
+			WindowHours:     hotCfg.WindowHours,
+			BatchSize:       hotCfg.BatchSize,
+			Weights: hotscore.Weights{
+				Like:      hotCfg.LikeWeight,
+				Collect:   hotCfg.CollectWeight,
+				Comment:   hotCfg.CommentWeight,
+				View:      hotCfg.ViewWeight,
+				Gravity:   hotCfg.Gravity,
+				HalfScore: hotCfg.HalfScore,
+			},
+		})
+	}
 	r := router.SetupRouter()
 
 	// 配置 CORS
@@ -45,8 +197,9 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH, HEAD")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Tus-Resumable, Upload-Offset, Upload-Length, Upload-Checksum")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Location, Tus-Resumable, Upload-Offset, Upload-Length, Upload-Expires")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,222 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/imaging"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidType = errors.New("不支持的文件类型")
+	ErrTooLarge    = errors.New("文件大小超出限制")
+	ErrNotFound    = errors.New("没有对应的直传记录")
+	ErrNotUploaded = errors.New("文件尚未上传")
+	ErrMismatch    = errors.New("上传的文件与申请时声明的不一致")
+)
+
+// directTypes 允许直传的内容类型及对应的扩展名
+var directTypes = map[string]map[string]string{
+	models.MediaKindImage: {"image/jpeg": ".jpg", "image/png": ".png", "image/webp": ".webp"},
+	models.MediaKindVideo: {"video/mp4": ".mp4", "video/quicktime": ".mov"},
+}
+
+// directPrefix 直传文件的路径前缀，按类型和用户隔离
+func directPrefix(kind string, ownerID uint) string {
+	return fmt.Sprintf("direct/%s/%d/", kind, ownerID)
+}
+
+// maxSize 各类型的大小上限（字节）
+func maxSize(kind string) int64 {
+	if kind == models.MediaKindVideo {
+		return opts.MaxVideoMB << 20
+	}
+	return opts.MaxImageMB << 20
+}
+
+// Ticket 直传凭证：客户端可以用 PutURL 直接 PUT（需带上 Headers），也可以按 Post 表单上传
+type Ticket struct {
+	Key       string             `json:"key"`
+	PutURL    string             `json:"put_url"`
+	Headers   map[string]string  `json:"headers"`
+	Post      storage.PostPolicy `json:"post"`
+	MaxSize   int64              `json:"max_size"`
+	ExpiresAt time.Time          `json:"expires_at"`
+}
+
+// Presign 为 ownerID 签发直传凭证，对象路径由服务端生成，表单上传的大小不能超过声明的 size
+func Presign(ownerID uint, kind, contentType string, size int64) (*Ticket, error) {
+	types, ok := directTypes[kind]
+	if !ok {
+		return nil, ErrInvalidType
+	}
+	ext, ok := types[contentType]
+	if !ok {
+		return nil, ErrInvalidType
+	}
+	if size <= 0 || size > maxSize(kind) {
+		return nil, ErrTooLarge
+	}
+
+	key := directPrefix(kind, ownerID) + uuid.New().String() + ext
+	expires := time.Duration(opts.PresignMinutes) * time.Minute
+	putURL, err := storage.Default().SignURL(http.MethodPut, key, expires, contentType)
+	if err != nil {
+		return nil, err
+	}
+	post, err := storage.Default().PresignPost(key, contentType, size, expires)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(expires)
+	if err := global.Db.Create(&models.Media{
+		OwnerID:     ownerID,
+		Key:         key,
+		URL:         storage.Default().URL(key),
+		Kind:        kind,
+		ContentType: contentType,
+		Size:        size,
+		Status:      models.MediaStatusPending,
+		ExpiresAt:   &expiresAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &Ticket{
+		Key:       key,
+		PutURL:    putURL,
+		Headers:   map[string]string{"Content-Type": contentType},
+		Post:      post,
+		MaxSize:   size,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Completed 直传确认结果，图片会经过与服务端上传相同的处理，返回处理后的地址
+type Completed struct {
+	Kind  string            `json:"kind"`
+	URL   string            `json:"url"`
+	Size  int64             `json:"size"`
+	Image *imaging.Uploaded `json:"image,omitempty"`
+}
+
+// Complete 客户端上传完成后回调：核对对象存在且大小、类型与声明一致后登记为已确认。
+// 图片会下载下来走图片处理流程，另存为多规格后删除直传的原始对象；不符合要求的对象会被删除
+func Complete(ownerID uint, key string) (*Completed, error) {
+	var m models.Media
+	if err := global.Db.Where("`key` = ? AND owner_id = ?", key, ownerID).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if m.Status != models.MediaStatusPending {
+		return &Completed{Kind: m.Kind, URL: m.URL, Size: m.Size}, nil
+	}
+
+	s := storage.Default()
+	info, err := s.Stat(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotUploaded
+		}
+		return nil, err
+	}
+	storedType := strings.ToLower(strings.TrimSpace(strings.SplitN(info.ContentType, ";", 2)[0]))
+	if info.Size <= 0 || info.Size > m.Size || (storedType != "" && storedType != m.ContentType) {
+		reject(&m)
+		return nil, ErrMismatch
+	}
+
+	if m.Kind == models.MediaKindVideo {
+		hash, err := checkVideo(key)
+		if err != nil {
+			if errors.Is(err, ErrMismatch) {
+				reject(&m)
+			}
+			return nil, err
+		}
+		if err := global.Db.Model(&m).Updates(map[string]interface{}{
+			"size":       info.Size,
+			"hash":       hash,
+			"status":     models.MediaStatusConfirmed,
+			"expires_at": nil,
+		}).Error; err != nil {
+			return nil, err
+		}
+		return &Completed{Kind: m.Kind, URL: m.URL, Size: info.Size}, nil
+	}
+
+	image, err := processImage(key)
+	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupported) || errors.Is(err, imaging.ErrTooLarge) {
+			reject(&m)
+			return nil, fmt.Errorf("%w: %v", ErrMismatch, err)
+		}
+		return nil, err
+	}
+	// 直传记录换成处理后的原图，直传的原始对象不再需要
+	if err := global.Db.Delete(&m).Error; err != nil {
+		return nil, err
+	}
+	if err := Register(ownerID, image.URL, models.MediaKindImage, image.ContentType, image.Size, image.Hash); err != nil {
+		return nil, err
+	}
+	if err := s.Delete(key); err != nil {
+		log.Printf("删除直传原图 %s 失败: %v", key, err)
+	}
+	return &Completed{Kind: models.MediaKindImage, URL: image.URL, Size: image.Size, Image: image}, nil
+}
+
+// checkVideo 读文件头确认是 mp4/mov，并顺带读完整个文件计算 SHA-256
+func checkVideo(key string) (string, error) {
+	body, err := storage.Default().Open(key)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(body, head); err != nil || !IsVideoHeader(head) {
+		return "", ErrMismatch
+	}
+	return Hash(io.MultiReader(bytes.NewReader(head), body))
+}
+
+// processImage 下载直传的图片并按服务端上传的规则处理、另存
+func processImage(key string) (*imaging.Uploaded, error) {
+	body, err := storage.Default().Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	result, err := imaging.Read(body)
+	if err != nil {
+		return nil, err
+	}
+	image, err := imaging.Store(result, "note_pics")
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// reject 删除不符合要求的直传对象和记录
+func reject(m *models.Media) {
+	if err := storage.Default().Delete(m.Key); err != nil {
+		log.Printf("删除直传对象 %s 失败: %v", m.Key, err)
+	}
+	if err := global.Db.Delete(m).Error; err != nil {
+		log.Printf("删除直传记录失败: %v", err)
+	}
+}
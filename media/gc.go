@@ -0,0 +1,177 @@
+package media
+
+import (
+	"log"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/imaging"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/storage"
+
+	"gorm.io/gorm"
+)
+
+// 回收原因
+const (
+	ReasonExpired      = "expired"      // 直传地址过期后仍未回调确认
+	ReasonUnreferenced = "unreferenced" // 上传后超过宽限期仍未被笔记或头像引用
+)
+
+// GCItem 一条待回收的媒体文件
+type GCItem struct {
+	ID        uint      `json:"id"`
+	OwnerID   uint      `json:"owner_id"`
+	Key       string    `json:"key"`
+	URL       string    `json:"url"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	Size      int64     `json:"size"`
+	Reason    string    `json:"reason"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GCReport 一次回收的结果；试运行时 Deleted 为 0，Items 是本该删除的文件
+type GCReport struct {
+	DryRun     bool      `json:"dry_run"`
+	Cutoff     time.Time `json:"cutoff"`      // 早于该时间的记录才会被回收
+	Items      []GCItem  `json:"items"`       // 待回收的文件
+	TotalBytes int64     `json:"total_bytes"` // 待回收文件的总大小
+	Relinked   int       `json:"relinked"`    // 登记为未引用、实际仍被笔记或头像使用而补记引用的文件数
+	Deleted    int       `json:"deleted"`     // 实际删除的文件数
+	Failed     int       `json:"failed"`      // 删除失败的文件数，下次回收时重试
+}
+
+// gcLoop 定时回收未被引用的文件
+func gcLoop() {
+	ticker := time.NewTicker(time.Duration(opts.GCIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		report, err := CollectGarbage(opts.GCDryRun)
+		if err != nil {
+			log.Printf("回收媒体文件失败: %v", err)
+			continue
+		}
+		if len(report.Items) == 0 && report.Relinked == 0 {
+			continue
+		}
+		if report.DryRun {
+			log.Printf("媒体文件回收试运行: %d 个文件共 %d 字节待删除，补记引用 %d 个", len(report.Items), report.TotalBytes, report.Relinked)
+			continue
+		}
+		log.Printf("媒体文件回收: 删除 %d 个，失败 %d 个，补记引用 %d 个", report.Deleted, report.Failed, report.Relinked)
+	}
+}
+
+// CollectGarbage 回收过期的直传记录和超过宽限期仍未被引用的文件；dryRun 为 true 时只生成报告。
+// 删除前会再按 URL 查一遍笔记和头像，兼容引用记录缺失的旧数据，仍在使用的文件补记引用而不删除
+func CollectGarbage(dryRun bool) (*GCReport, error) {
+	report := &GCReport{
+		DryRun: dryRun,
+		Cutoff: time.Now().Add(-time.Duration(opts.GCGraceHours) * time.Hour),
+		Items:  []GCItem{},
+	}
+
+	var candidates []models.Media
+	if err := global.Db.
+		Where("(status = ? AND expires_at < ?) OR (status = ? AND note_id = 0 AND updated_at < ?)",
+			models.MediaStatusPending, report.Cutoff, models.MediaStatusConfirmed, report.Cutoff).
+		Order("id").Limit(opts.GCBatchSize).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	for _, m := range candidates {
+		reason := ReasonExpired
+		if m.Status == models.MediaStatusConfirmed {
+			reason = ReasonUnreferenced
+			noteID, used, err := inUse(m)
+			if err != nil {
+				return nil, err
+			}
+			if used {
+				report.Relinked++
+				if !dryRun {
+					if err := Attach(noteID, []string{m.URL}); err != nil {
+						log.Printf("补记媒体文件 %s 的引用失败: %v", m.URL, err)
+					}
+				}
+				continue
+			}
+		}
+
+		report.Items = append(report.Items, GCItem{
+			ID:        m.ID,
+			OwnerID:   m.OwnerID,
+			Key:       m.Key,
+			URL:       m.URL,
+			Kind:      m.Kind,
+			Status:    m.Status,
+			Size:      m.Size,
+			Reason:    reason,
+			UpdatedAt: m.UpdatedAt,
+		})
+		report.TotalBytes += m.Size
+		if dryRun {
+			continue
+		}
+		deleted, err := collect(m)
+		if err != nil {
+			log.Printf("回收媒体文件 %s 失败: %v", m.Key, err)
+			report.Failed++
+			continue
+		}
+		if deleted {
+			report.Deleted++
+		}
+	}
+	return report, nil
+}
+
+// inUse 按 URL 查找仍在使用该文件的笔记或头像，返回引用它的笔记 ID（头像为 0）
+func inUse(m models.Media) (uint, bool, error) {
+	var notes []models.Note
+	if err := global.Db.Select("note_id").
+		Where("note_urls LIKE ?", "%"+m.Key+"%").
+		Limit(1).Find(&notes).Error; err != nil {
+		return 0, false, err
+	}
+	if len(notes) > 0 {
+		return notes[0].NoteID, true, nil
+	}
+	var avatars int64
+	if err := global.Db.Model(&models.User{}).Where("avatar = ?", m.URL).Count(&avatars).Error; err != nil {
+		return 0, false, err
+	}
+	return 0, avatars > 0, nil
+}
+
+// collect 删除记录和对象，返回是否删除。记录按读取时的状态有条件删除，读取之后被确认或被笔记引用的文件
+// 删不到记录，对象也不删；删除对象失败时回滚，记录留到下次回收重试。
+// 待上传的直传对象可能根本不存在，删除不存在的对象不算失败
+func collect(m models.Media) (bool, error) {
+	deleted := false
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("id = ? AND status = ?", m.ID, m.Status)
+		if m.Status == models.MediaStatusConfirmed {
+			query = query.Where("note_id = 0")
+		}
+		result := query.Delete(&models.Media{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if m.Status == models.MediaStatusPending {
+			if err := storage.Default().Delete(m.Key); err != nil {
+				return err
+			}
+		} else {
+			for _, u := range imaging.VariantURLs(m.URL) {
+				if err := storage.DeleteURL(u); err != nil {
+					return err
+				}
+			}
+		}
+		deleted = true
+		return nil
+	})
+	return deleted && err == nil, err
+}
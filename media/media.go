@@ -0,0 +1,266 @@
+package media
+
+//媒体文件登记：经服务端上传、断点续传和客户端直传的文件都记录在 media 表里，
+//发布笔记时只接受本人上传且已确认的文件，被笔记引用后记下笔记 ID；
+//超过宽限期仍未被引用的文件由定时任务回收
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/imaging"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnconfirmed = errors.New("包含未上传完成的媒体文件")
+	ErrNotOwned    = errors.New("包含不属于当前用户或已被其他笔记使用的媒体文件")
+)
+
+// Options 媒体文件参数
+type Options struct {
+	PresignMinutes int   // 直传地址的有效期（分钟）
+	MaxImageMB     int64 // 直传图片的大小上限（MB）
+	MaxVideoMB     int64 // 直传视频的大小上限（MB）
+
+	GCIntervalMinutes int  // 回收未引用文件的间隔（分钟）
+	GCGraceHours      int  // 上传后超过多少小时仍未被引用才回收
+	GCBatchSize       int  // 每次回收最多处理的记录数
+	GCDryRun          bool // 只输出回收报告，不实际删除
+}
+
+var opts = Options{
+	PresignMinutes: 15,
+	MaxImageMB:     20,
+	MaxVideoMB:     20 * 1024,
+
+	GCIntervalMinutes: 60,
+	GCGraceHours:      24,
+	GCBatchSize:       200,
+}
+
+// Init 设置参数，未配置的项使用默认值
+func Init(o Options) {
+	if o.PresignMinutes > 0 {
+		opts.PresignMinutes = o.PresignMinutes
+	}
+	if o.MaxImageMB > 0 {
+		opts.MaxImageMB = o.MaxImageMB
+	}
+	if o.MaxVideoMB > 0 {
+		opts.MaxVideoMB = o.MaxVideoMB
+	}
+	if o.GCIntervalMinutes > 0 {
+		opts.GCIntervalMinutes = o.GCIntervalMinutes
+	}
+	if o.GCGraceHours > 0 {
+		opts.GCGraceHours = o.GCGraceHours
+	}
+	if o.GCBatchSize > 0 {
+		opts.GCBatchSize = o.GCBatchSize
+	}
+	opts.GCDryRun = o.GCDryRun
+	go gcLoop()
+}
+
+// Register 登记服务端已写入对象存储的文件，状态直接为已确认，等待被笔记引用
+func Register(ownerID uint, url, kind, contentType string, size int64, hash string) error {
+	key, ok := storage.Default().KeyFromURL(url)
+	if !ok {
+		return fmt.Errorf("%w: %s", storage.ErrForeignURL, url)
+	}
+	return global.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"owner_id", "url", "kind", "content_type", "size", "hash", "status", "note_id", "expires_at", "updated_at"}),
+	}).Create(&models.Media{
+		OwnerID:     ownerID,
+		Key:         key,
+		URL:         url,
+		Kind:        kind,
+		ContentType: contentType,
+		Size:        size,
+		Hash:        hash,
+		Status:      models.MediaStatusConfirmed,
+	}).Error
+}
+
+// Hash 计算内容的 SHA-256（十六进制）
+func Hash(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// keysOf 把 URL 换成对象路径，不是本站存储的地址返回 ErrUnconfirmed
+func keysOf(urls []string) ([]string, error) {
+	keys := make([]string, 0, len(urls))
+	for _, url := range urls {
+		key, ok := storage.Default().KeyFromURL(url)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnconfirmed, url)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// localKeys 取出本站存储的对象路径，忽略外部地址（如默认头像）
+func localKeys(urls []string) []string {
+	keys := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if key, ok := storage.Default().KeyFromURL(url); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// CheckOwned 检查 URL 是否都是 ownerID 上传、已确认的 kind 类型文件，且没有被 noteID 以外的笔记或头像引用。
+// 新发布的笔记 noteID 传 0。只用于提前拒绝请求，保存笔记时需在事务中调用 Claim
+func CheckOwned(ownerID, noteID uint, kind string, urls []string) error {
+	return checkOwned(global.Db, ownerID, noteID, kind, urls)
+}
+
+// Claim 在事务 tx 中锁住文件记录，按 CheckOwned 的规则检查后记为被 noteID 引用。
+// 检查和引用在同一事务中完成，两篇笔记同时引用同一个文件时后提交的一方会检查失败，调用方回滚整个事务
+func Claim(tx *gorm.DB, ownerID, noteID uint, kind string, urls []string) error {
+	if err := checkOwned(tx.Clauses(clause.Locking{Strength: "UPDATE"}), ownerID, noteID, kind, urls); err != nil {
+		return err
+	}
+	return attach(tx, noteID, urls)
+}
+
+func checkOwned(db *gorm.DB, ownerID, noteID uint, kind string, urls []string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	keys, err := keysOf(urls)
+	if err != nil {
+		return err
+	}
+
+	var records []models.Media
+	if err := db.Where("`key` IN ?", keys).Find(&records).Error; err != nil {
+		return err
+	}
+	byKey := make(map[string]models.Media, len(records))
+	for _, m := range records {
+		byKey[m.Key] = m
+	}
+	for i, key := range keys {
+		m, ok := byKey[key]
+		if !ok || m.Status == models.MediaStatusPending {
+			return fmt.Errorf("%w: %s", ErrUnconfirmed, urls[i])
+		}
+		if m.Kind != kind {
+			return fmt.Errorf("%w: %s", ErrInvalidType, urls[i])
+		}
+		if m.OwnerID != ownerID || (m.Status == models.MediaStatusAttached && m.NoteID != noteID) {
+			return fmt.Errorf("%w: %s", ErrNotOwned, urls[i])
+		}
+	}
+	return nil
+}
+
+// CheckRemovable 检查 ownerID 能否删除该文件：已登记的文件必须是本人上传且没有被引用；
+// 未登记的旧文件无法确认上传者，仍被笔记或头像使用时不能删除
+func CheckRemovable(ownerID uint, url string) error {
+	key, ok := storage.Default().KeyFromURL(url)
+	if !ok {
+		return fmt.Errorf("%w: %s", storage.ErrForeignURL, url)
+	}
+	var m models.Media
+	if err := global.Db.Where("`key` = ?", key).Limit(1).Find(&m).Error; err != nil {
+		return err
+	}
+	if m.ID == 0 {
+		_, used, err := inUse(models.Media{Key: key, URL: url})
+		if err != nil {
+			return err
+		}
+		if used {
+			return fmt.Errorf("%w: %s", ErrNotOwned, url)
+		}
+		return nil
+	}
+	if m.OwnerID != ownerID || m.Status == models.MediaStatusAttached {
+		return fmt.Errorf("%w: %s", ErrNotOwned, url)
+	}
+	return nil
+}
+
+// Attach 记下文件被 noteID 引用；头像等不属于笔记的引用 noteID 传 0
+func Attach(noteID uint, urls []string) error {
+	return attach(global.Db, noteID, urls)
+}
+
+func attach(db *gorm.DB, noteID uint, urls []string) error {
+	keys := localKeys(urls)
+	if len(keys) == 0 {
+		return nil
+	}
+	return db.Model(&models.Media{}).
+		Where("`key` IN ? AND status <> ?", keys, models.MediaStatusPending).
+		Updates(map[string]interface{}{
+			"status":  models.MediaStatusAttached,
+			"note_id": noteID,
+		}).Error
+}
+
+// Detach 取消引用，文件回到已确认状态，超过宽限期后被回收
+func Detach(urls []string) error {
+	keys := localKeys(urls)
+	if len(keys) == 0 {
+		return nil
+	}
+	return global.Db.Model(&models.Media{}).
+		Where("`key` IN ? AND status = ?", keys, models.MediaStatusAttached).
+		Updates(map[string]interface{}{
+			"status":  models.MediaStatusConfirmed,
+			"note_id": 0,
+		}).Error
+}
+
+// Remove 删除对象存储中的文件（图片连同各规格）和对应的登记记录，出错时继续处理其余文件并返回第一个错误
+func Remove(urls []string) error {
+	var first error
+	for _, url := range urls {
+		if err := remove(url); err != nil {
+			log.Printf("删除文件 %s 失败: %v", url, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func remove(url string) error {
+	for _, u := range imaging.VariantURLs(url) {
+		if err := storage.DeleteURL(u); err != nil {
+			return err
+		}
+	}
+	if key, ok := storage.Default().KeyFromURL(url); ok {
+		return global.Db.Where("`key` = ?", key).Delete(&models.Media{}).Error
+	}
+	return nil
+}
+
+// videoBoxes 视频文件开头允许出现的 box 类型
+var videoBoxes = map[string]bool{"ftyp": true, "moov": true, "mdat": true, "wide": true, "free": true, "skip": true}
+
+// IsVideoHeader 按文件头判断是否为 mp4/mov：mp4 的第一个 box 是 ftyp，较老的 mov 可能直接以 moov/mdat/wide 等开头
+func IsVideoHeader(head []byte) bool {
+	return len(head) >= 8 && videoBoxes[string(head[4:8])]
+}
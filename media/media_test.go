@@ -0,0 +1,157 @@
+package media
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/storage"
+	"travel-from-sysu-backend/testutil"
+
+	"gorm.io/gorm"
+)
+
+// setup 准备内存数据库和本地存储，登记 ownerID 上传的一张已确认图片并返回其 URL
+func setup(t *testing.T, ownerID uint) string {
+	t.Helper()
+	testutil.OpenDB(t, &models.Media{}, &models.Note{}, &models.User{})
+	if err := storage.Init(storage.Config{
+		Driver: "local",
+		Local:  storage.LocalConfig{Dir: t.TempDir(), BaseURL: "http://localhost:3000", Secret: "test-secret"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	url := storage.Default().URL("note_pics/a.jpg")
+	if err := Register(ownerID, url, models.MediaKindImage, "image/jpeg", 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	return url
+}
+
+func mediaOf(t *testing.T, url string) models.Media {
+	t.Helper()
+	key, _ := storage.Default().KeyFromURL(url)
+	var m models.Media
+	if err := global.Db.Where("`key` = ?", key).First(&m).Error; err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestClaim(t *testing.T) {
+	url := setup(t, 1)
+
+	cases := []struct {
+		name    string
+		ownerID uint
+		noteID  uint
+		kind    string
+		want    error
+	}{
+		{"其他用户", 2, 10, models.MediaKindImage, ErrNotOwned},
+		{"类型不符", 1, 10, models.MediaKindVideo, ErrInvalidType},
+		{"本人引用", 1, 10, models.MediaKindImage, nil},
+		{"同一笔记再次引用", 1, 10, models.MediaKindImage, nil},
+		{"已被其他笔记引用", 1, 11, models.MediaKindImage, ErrNotOwned},
+	}
+	for _, c := range cases {
+		err := global.Db.Transaction(func(tx *gorm.DB) error {
+			return Claim(tx, c.ownerID, c.noteID, c.kind, []string{url})
+		})
+		if !errors.Is(err, c.want) {
+			t.Fatalf("%s: 期望 %v，得到 %v", c.name, c.want, err)
+		}
+	}
+	if m := mediaOf(t, url); m.Status != models.MediaStatusAttached || m.NoteID != 10 {
+		t.Fatalf("应被笔记 10 引用: status=%s note=%d", m.Status, m.NoteID)
+	}
+}
+
+func TestClaimRollsBack(t *testing.T) {
+	url := setup(t, 1)
+
+	// 引用之后保存笔记失败，整个事务回滚，文件仍可被其他笔记使用
+	failed := errors.New("保存笔记失败")
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := Claim(tx, 1, 10, models.MediaKindImage, []string{url}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("期望事务返回保存失败，得到 %v", err)
+	}
+	if m := mediaOf(t, url); m.Status != models.MediaStatusConfirmed || m.NoteID != 0 {
+		t.Fatalf("回滚后应保持已确认: status=%s note=%d", m.Status, m.NoteID)
+	}
+	if err := CheckOwned(1, 11, models.MediaKindImage, []string{url}); err != nil {
+		t.Fatalf("回滚后其他笔记应能使用该文件: %v", err)
+	}
+}
+
+func TestCollectSkipsClaimedMedia(t *testing.T) {
+	url := setup(t, 1)
+	key, _ := storage.Default().KeyFromURL(url)
+	if err := storage.Default().Put(key, strings.NewReader("jpeg"), 4, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 回收任务读到候选之后，发布笔记的事务先一步引用了该文件
+	candidate := mediaOf(t, url)
+	if err := global.Db.Transaction(func(tx *gorm.DB) error {
+		return Claim(tx, 1, 10, models.MediaKindImage, []string{url})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := collect(candidate)
+	if err != nil || deleted {
+		t.Fatalf("已被引用的文件不应回收: deleted=%v err=%v", deleted, err)
+	}
+	if _, err := storage.Default().Stat(key); err != nil {
+		t.Fatalf("已被引用的文件对象不应被删除: %v", err)
+	}
+	if m := mediaOf(t, url); m.Status != models.MediaStatusAttached {
+		t.Fatalf("记录应保留为已引用，得到 %s", m.Status)
+	}
+
+	// 仍未被引用的文件正常回收
+	other := storage.Default().URL("note_pics/b.jpg")
+	if err := storage.Default().Put("note_pics/b.jpg", strings.NewReader("jpeg"), 4, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(1, other, models.MediaKindImage, "image/jpeg", 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, err := collect(mediaOf(t, other)); err != nil || !deleted {
+		t.Fatalf("未被引用的文件应回收: deleted=%v err=%v", deleted, err)
+	}
+	if _, err := storage.Default().Stat("note_pics/b.jpg"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("回收后对象应被删除，得到 %v", err)
+	}
+}
+
+func TestCheckRemovableLegacyFile(t *testing.T) {
+	setup(t, 1)
+
+	// 登记表出现之前发布的笔记引用的文件没有登记记录
+	used := storage.Default().URL("note_pics/legacy.jpg")
+	if err := global.Db.Create(&models.Note{NoteTitle: "旧笔记", NoteURLs: `["` + used + `"]`, NoteCreatorID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckRemovable(2, used); !errors.Is(err, ErrNotOwned) {
+		t.Fatalf("仍被笔记使用的未登记文件不能删除，得到 %v", err)
+	}
+
+	avatar := storage.Default().URL("avatars/legacy.jpg")
+	if err := global.Db.Create(&models.User{UserId: 1, Username: "alice", Password: "x", Avatar: avatar}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckRemovable(2, avatar); !errors.Is(err, ErrNotOwned) {
+		t.Fatalf("仍被用作头像的未登记文件不能删除，得到 %v", err)
+	}
+
+	if err := CheckRemovable(2, storage.Default().URL("note_pics/orphan.jpg")); err != nil {
+		t.Fatalf("没有被使用的未登记文件可以删除，得到 %v", err)
+	}
+}
@@ -9,5 +9,5 @@ type Collect struct {
 	Nid        *uint     `gorm:"not null;index" json:"nid"` // 笔记 ID（外键，关联 Note 表的 NoteID）
 	User       User      `gorm:"foreignKey:Uid;references:UserId"`
 	Note       Note      `gorm:"constraint:OnDelete:CASCADE;foreignKey:Nid;references:NoteID"`
-	CreateDate time.Time `gorm:"type:datetime" json:"create_date"` // 收藏时间
+	CreateDate time.Time `gorm:"type:datetime;index" json:"create_date"` // 收藏时间
 }
@@ -11,6 +11,8 @@ type Comments struct {
 	ReplyUid    uint      `json:"reply_uid"`
 	Level       int       `json:"level"`
 	Content     string    `json:"content" gorm:"index"`
-	CreatedAt   time.Time `json:"created_at"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
 	CommentLike uint      `json:"comment_like"`
+
+	Mentions []MentionEntity `gorm:"-" json:"mentions,omitempty"` // 正文中的 @ 提及，查询时填充
 }
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// NoteDailyStat 笔记按天汇总的数据，供创作者数据看板使用
+type NoteDailyStat struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	NoteID        uint      `gorm:"not null;uniqueIndex:idx_note_daily_stat,priority:1" json:"note_id"`                                                   // 笔记 ID
+	AuthorID      uint      `gorm:"not null;index:idx_note_daily_stat_author,priority:1" json:"author_id"`                                                // 作者 ID
+	Day           time.Time `gorm:"type:date;not null;uniqueIndex:idx_note_daily_stat,priority:2;index:idx_note_daily_stat_author,priority:2" json:"day"` // 日期
+	Views         int64     `gorm:"not null;default:0" json:"views"`                                                                                      // 浏览数
+	FollowerViews int64     `gorm:"not null;default:0" json:"follower_views"`                                                                             // 其中粉丝的浏览数
+	Likes         int64     `gorm:"not null;default:0" json:"likes"`                                                                                      // 当天新增且仍保留的点赞数
+	Collects      int64     `gorm:"not null;default:0" json:"collects"`                                                                                   // 当天新增且仍保留的收藏数
+	Comments      int64     `gorm:"not null;default:0" json:"comments"`                                                                                   // 当天新增且仍保留的评论数
+}
+
+// AccountDailyStat 账号按天汇总的数据
+type AccountDailyStat struct {
+	ID      uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID  uint      `gorm:"not null;uniqueIndex:idx_account_daily_stat,priority:1" json:"user_id"`       // 用户 ID
+	Day     time.Time `gorm:"type:date;not null;uniqueIndex:idx_account_daily_stat,priority:2" json:"day"` // 日期
+	NewFans int64     `gorm:"not null;default:0" json:"new_fans"`                                          // 当天关注且仍在关注的粉丝数
+}
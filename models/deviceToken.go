@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DeviceToken 移动端推送设备令牌，一个用户可以有多台设备
+type DeviceToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`                       // 所属用户 ID
+	Platform  string    `gorm:"type:varchar(10);not null" json:"platform"`           // 设备平台：ios/android
+	Provider  string    `gorm:"type:varchar(10);not null" json:"provider"`           // 推送厂商：apns/fcm/fake
+	Token     string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"token"` // 厂商下发的设备令牌
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"` // 最近一次登记时间
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// DigestLog 摘要邮件发送记录，(用户, 周期) 唯一，防止同一周期重复发送
+type DigestLog struct {
+	ID                 uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID             uint      `gorm:"not null;uniqueIndex:idx_digest_user_period" json:"user_id"`                     // 用户 ID
+	PeriodKey          string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_digest_user_period" json:"period_key"` // 周期标识，如 daily:2024-05-01、weekly:2024-W18
+	Status             string    `gorm:"type:varchar(10);not null;default:'sending'" json:"status"`                      // sending/sent
+	NotificationCount  int       `gorm:"default:0" json:"notification_count"`                                            // 本次包含的通知条数
+	LastNotificationID uint      `gorm:"default:0" json:"last_notification_id"`                                          // 本次包含的最大通知 ID，下次只取更新的通知
+	SentAt             time.Time `json:"sent_at"`
+	CreatedAt          time.Time `json:"created_at"`
+}
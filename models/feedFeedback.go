@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// 不感兴趣的对象类型
+const (
+	FeedbackTargetNote   = "note"   // 某篇笔记
+	FeedbackTargetAuthor = "author" // 某个作者的全部笔记
+	FeedbackTargetTag    = "tag"    // 带某个标签的笔记
+)
+
+// FeedFeedback 用户在推荐流中标记的“不感兴趣”，命中的笔记不再推荐
+type FeedFeedback struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_feed_feedback" json:"user_id"`                      // 用户 ID
+	TargetType string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_feed_feedback" json:"target_type"` // note/author/tag
+	TargetKey  string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_feed_feedback" json:"target_key"`  // 笔记 ID、作者 ID 或标签名
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// FeedImpression 推荐流的曝光记录，用于过滤已经看过的笔记和统计实验效果
+type FeedImpression struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint       `gorm:"not null;index:idx_feed_impression_user,priority:1" json:"user_id"`  // 用户 ID
+	NoteID     uint       `gorm:"not null;index" json:"note_id"`                                      // 笔记 ID
+	Source     string     `gorm:"type:varchar(20);not null" json:"source"`                            // 主要召回来源
+	Experiment string     `gorm:"type:varchar(50);not null;default:'';index" json:"experiment"`       // 实验名，未进入实验为空
+	Variant    string     `gorm:"type:varchar(50);not null;default:''" json:"variant"`                // 实验分组
+	ShownAt    time.Time  `gorm:"not null;index:idx_feed_impression_user,priority:2" json:"shown_at"` // 曝光时间
+	ClickedAt  *time.Time `json:"clicked_at"`                                                         // 点击时间，未点击为空
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// GroupMessage 群聊消息
+type GroupMessage struct {
+	MessageID uint       `gorm:"primaryKey;autoIncrement" json:"message_id"`
+	GroupID   uint       `gorm:"not null;index" json:"group_id"`            // 群组 ID
+	SenderID  uint       `gorm:"not null;default:0" json:"sender_id"`       // 发送者 ID，系统消息为 0
+	MsgType   string     `gorm:"type:varchar(20);not null" json:"msg_type"` // 消息类型：text/system
+	Content   string     `gorm:"type:text" json:"content"`                  // 消息内容
+	Mentions  string     `gorm:"type:text" json:"mentions"`                 // 被 @ 的用户 ID 列表（JSON 数组）
+	IsPinned  bool       `gorm:"not null;default:false" json:"is_pinned"`   // 是否置顶
+	PinnedBy  uint       `gorm:"default:0" json:"pinned_by"`                // 置顶操作人 ID
+	PinnedAt  *time.Time `json:"pinned_at"`                                 // 置顶时间
+	CreatedAt time.Time  `json:"created_at"`
+}
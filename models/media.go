@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// 媒体文件状态
+const (
+	MediaStatusPending   = "pending"   // 已签发直传地址，等待客户端上传并回调
+	MediaStatusConfirmed = "confirmed" // 服务端已确认对象存在，可以用于发布笔记；长期未被引用的会被回收
+	MediaStatusAttached  = "attached"  // 已被笔记或头像引用
+)
+
+// 媒体文件类型
+const (
+	MediaKindImage  = "image"
+	MediaKindVideo  = "video"
+	MediaKindAvatar = "avatar"
+)
+
+// Media 上传到对象存储的媒体文件登记，发布笔记时只接受本人上传且已确认的文件
+type Media struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	OwnerID     uint       `gorm:"not null;index" json:"owner_id"`           // 上传者 ID
+	Key         string     `gorm:"size:512;not null;uniqueIndex" json:"key"` // 对象路径
+	URL         string     `gorm:"size:1024;not null" json:"url"`            // 访问地址
+	Kind        string     `gorm:"size:20;not null" json:"kind"`             // image/video
+	ContentType string     `gorm:"size:100" json:"content_type"`             // 内容类型
+	Size        int64      `gorm:"not null;default:0" json:"size"`           // 文件大小（字节），待上传时为声明的大小
+	Hash        string     `gorm:"size:64;index" json:"hash"`                // 内容的 SHA-256（十六进制），待上传时为空
+	Status      string     `gorm:"size:20;not null;index" json:"status"`     // 状态
+	NoteID      uint       `gorm:"not null;default:0;index" json:"note_id"`  // 引用该文件的笔记 ID，未被笔记引用时为 0
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`                     // 直传地址的过期时间
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Mention 笔记/评论正文中的 @ 提及，存用户 ID 而不是用户名，改名后依然指向同一个人
+type Mention struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SourceType   string    `gorm:"type:varchar(10);not null;index:idx_mention_source" json:"source_type"` // 来源类型：note/comment
+	SourceID     uint      `gorm:"not null;index:idx_mention_source" json:"source_id"`                    // 笔记 ID 或评论 ID
+	MentionedUID uint      `gorm:"not null;index" json:"mentioned_uid"`                                   // 被提及的用户 ID
+	Offset       int       `gorm:"not null" json:"offset"`                                                // 在正文中的起始位置（按字符计，含 @）
+	Length       int       `gorm:"not null" json:"length"`                                                // 长度（按字符计，含 @）
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MentionEntity 返回给客户端的提及实体，Username 为当前用户名
+type MentionEntity struct {
+	UID      uint   `json:"uid"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
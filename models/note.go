@@ -17,5 +17,9 @@ type Note struct {
 	CommentCounts    uint    `json:"comment_counts"`
 	IsFindingBuddy   int     `json:"is_finding_buddy"`  // 是否是找旅伴帖子 (0: 否, 1: 是)
 	BuddyDescription string  `json:"buddy_description"` // 找旅伴的需求描述
-	Score            float64 `json:"score"`
+	Score            float64 `json:"score"`             // 热度分 0~100，随时间衰减
+
+	TrendingScore float64  `gorm:"index" json:"trending_score"` // 窗口热度分 0~100，只看最近窗口内的互动
+	Latitude      *float64 `json:"latitude"`                    // 纬度，未标注位置时为空
+	Longitude     *float64 `json:"longitude"`                   // 经度，未标注位置时为空
 }
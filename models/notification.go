@@ -2,6 +2,16 @@ package models
 
 import "time"
 
+// NotificationTarget 通知指向的对象及其快照，快照在通知创建时写入，原内容修改或删除后通知仍可展示
+type NotificationTarget struct {
+	TargetType     string `gorm:"type:varchar(20)" json:"target_type"`      // 目标类型：note/comment/user/group
+	TargetID       uint   `gorm:"default:0" json:"target_id"`               // 目标 ID（笔记 ID/评论 ID/用户 ID/群组 ID）
+	NoteID         uint   `gorm:"default:0;index" json:"note_id"`           // 目标所属笔记 ID，评论类通知也会带上，方便跳转
+	TitleExcerpt   string `gorm:"type:varchar(100)" json:"title_excerpt"`   // 笔记标题摘要
+	CommentExcerpt string `gorm:"type:varchar(200)" json:"comment_excerpt"` // 评论/消息内容摘要
+	Thumbnail      string `gorm:"type:varchar(255)" json:"thumbnail"`       // 笔记缩略图
+}
+
 // Notification 表结构
 type Notification struct {
 	ID          uint       `gorm:"primaryKey;autoIncrement;" json:"id"`   // 主键 ID
@@ -13,4 +23,13 @@ type Notification struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   *time.Time `gorm:"index" json:"deleted_at"` // 可选，软删除字段
+
+	AggregateCount uint   `gorm:"not null;default:1" json:"aggregate_count"` // 聚合的参与人数
+	RecentActors   string `gorm:"type:varchar(255)" json:"recent_actors"`    // 最近的若干参与者 ID（JSON 数组，新的在前）
+
+	Delivery   string    `gorm:"type:varchar(10);not null;default:'instant'" json:"delivery"` // 投递方式：instant 实时推送/digest 仅进入摘要
+	DeliverAt  time.Time `gorm:"index" json:"deliver_at"`                                     // 推送/邮件的最早投递时间，免打扰时段内会顺延
+	PushStatus string    `gorm:"type:varchar(10);not null;default:'none';index" json:"-"`     // 移动端推送状态：none 不推送/pending 待推送/sending/sent/failed/skipped
+
+	NotificationTarget `gorm:"embedded"` // 通知目标及快照
 }
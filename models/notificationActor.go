@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// NotificationActor 聚合通知的参与者，一条聚合通知（如"某某等 12 人赞了你的笔记"）对应多条记录
+type NotificationActor struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	NotificationID uint      `gorm:"not null;index" json:"notification_id"` // 聚合通知 ID
+	ActorID        uint      `gorm:"not null;index" json:"actor_id"`        // 参与者用户 ID
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// 通知接收方式
+const (
+	NotifyModeAll       = "all"       // 全部接收并实时推送
+	NotifyModeFollowing = "following" // 只接收自己关注的人发起的通知
+	NotifyModeDigest    = "digest"    // 不实时推送，汇总到摘要邮件中
+	NotifyModeOff       = "off"       // 不接收
+)
+
+// NotificationPreference 用户按通知类型设置的接收方式，没有记录的类型按 all 处理
+type NotificationPreference struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID uint   `gorm:"not null;uniqueIndex:idx_user_notif_type" json:"user_id"`               // 用户 ID
+	Type   string `gorm:"type:varchar(20);not null;uniqueIndex:idx_user_notif_type" json:"type"` // 通知类型
+	Mode   string `gorm:"type:varchar(10);not null;default:'all'" json:"mode"`                   // 接收方式：all/following/digest/off
+}
+
+// 摘要邮件频率
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// NotificationSetting 用户的通知全局设置（免打扰时段、摘要邮件）
+// 免打扰时段内推送和邮件会顺延到时段结束后投递，站内通知照常写入
+type NotificationSetting struct {
+	UserID            uint   `gorm:"primaryKey;autoIncrement:false" json:"user_id"`                      // 用户 ID
+	QuietHoursEnabled bool   `gorm:"not null;default:false" json:"quiet_hours_enabled"`                  // 是否开启免打扰
+	QuietStart        string `gorm:"type:varchar(5);not null;default:'22:00'" json:"quiet_start"`        // 免打扰开始时间 HH:MM
+	QuietEnd          string `gorm:"type:varchar(5);not null;default:'08:00'" json:"quiet_end"`          // 免打扰结束时间 HH:MM，早于开始时间表示跨天
+	TimeZone          string `gorm:"type:varchar(64);not null;default:'Asia/Shanghai'" json:"time_zone"` // IANA 时区名
+	DigestFrequency   string `gorm:"type:varchar(10);not null;default:'off'" json:"digest_frequency"`    // 未读通知摘要邮件：off/daily/weekly
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SearchHistory 用户的搜索历史，同一查询只保留一条，重复搜索时刷新时间
+type SearchHistory struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_search_history_user_query;index:idx_search_history_user_time,priority:1" json:"user_id"` // 用户 ID
+	Query      string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_search_history_user_query" json:"query"`                                // 规范化后的查询
+	SearchedAt time.Time `gorm:"not null;index:idx_search_history_user_time,priority:2" json:"searched_at"`                                       // 最近一次搜索时间
+}
+
+// SearchSetting 用户的搜索设置，没有记录时默认记录搜索历史
+type SearchSetting struct {
+	UserID          uint `gorm:"primaryKey;autoIncrement:false" json:"user_id"`  // 用户 ID
+	HistoryDisabled bool `gorm:"not null;default:false" json:"history_disabled"` // 关闭后不再记录搜索历史，也不计入热搜
+}
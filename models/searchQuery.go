@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SearchQueryLog 搜索行为明细，用于按时间窗口统计热搜，定期清理
+// 同一用户在去重窗口内重复搜索同一查询只记一次
+type SearchQueryLog struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;index:idx_search_log_user_query,priority:1" json:"user_id"`                                                           // 搜索者 ID
+	Query     string    `gorm:"type:varchar(64);not null;index:idx_search_log_user_query,priority:2;index:idx_search_log_query_time,priority:1" json:"query"` // 规范化后的查询
+	CreatedAt time.Time `gorm:"not null;index;index:idx_search_log_query_time,priority:2" json:"created_at"`
+}
+
+// SearchQueryStat 查询的累计搜索次数和独立用户数，用于输入联想
+type SearchQueryStat struct {
+	Query          string    `gorm:"type:varchar(64);primaryKey" json:"query"` // 规范化后的查询
+	Count          int64     `gorm:"not null;default:0" json:"count"`          // 去重后的累计搜索次数
+	Users          int64     `gorm:"not null;default:0" json:"users"`          // 搜索过的独立用户数（按保留期内的明细判断）
+	LastSearchedAt time.Time `json:"last_searched_at"`
+}
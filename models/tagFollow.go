@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// TagFollow 用户关注的标签，关注的标签下的新笔记会进入个性化推荐
+type TagFollow struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tag_follow" json:"user_id"`                 // 用户 ID
+	TagID     string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_tag_follow" json:"tag_id"` // 标签 ID
+	CreatedAt time.Time `json:"created_at"`
+}
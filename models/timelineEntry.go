@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// TimelineEntry 关注流的物化时间线：作者发布笔记时写入每个粉丝的时间线
+type TimelineEntry struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_timeline_user_note,priority:1;index:idx_timeline_user_time,priority:1" json:"user_id"` // 时间线所属用户
+	NoteID    uint      `gorm:"not null;uniqueIndex:idx_timeline_user_note,priority:2;index" json:"note_id"`                                   // 笔记 ID
+	AuthorID  uint      `gorm:"not null;index" json:"author_id"`                                                                               // 笔记作者 ID
+	SortTime  int64     `gorm:"not null;index:idx_timeline_user_time,priority:2" json:"sort_time"`                                             // 排序时间，即笔记的更新时间
+	CreatedAt time.Time `json:"created_at"`
+}
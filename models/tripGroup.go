@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TripGroup 旅伴群组，由找旅伴帖子的作者创建，一个找旅伴帖子对应一个群组
+type TripGroup struct {
+	GroupID     uint      `gorm:"primaryKey;autoIncrement;autoIncrementStart:100001" json:"group_id"` // 群组 ID
+	NoteID      uint      `gorm:"not null;uniqueIndex" json:"note_id"`                                // 关联的找旅伴帖子 ID
+	OwnerID     uint      `gorm:"not null;index" json:"owner_id"`                                     // 群主 ID（帖子作者）
+	GroupName   string    `gorm:"type:varchar(100)" json:"group_name"`                                // 群组名称
+	MemberCount uint      `gorm:"default:0" json:"member_count"`                                      // 当前成员数
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TripGroupMember 群组成员，退出群组即删除对应记录
+type TripGroupMember struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID   uint      `gorm:"not null;uniqueIndex:idx_group_member" json:"group_id"`  // 群组 ID
+	Uid       uint      `gorm:"not null;uniqueIndex:idx_group_member;index" json:"uid"` // 成员用户 ID
+	JoinMsgID uint      `gorm:"not null;default:0" json:"join_msg_id"`                  // 入群系统消息 ID，成员只能看到此消息及之后的聊天记录
+	JoinedAt  time.Time `json:"joined_at"`
+}
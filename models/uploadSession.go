@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// 断点续传会话状态
+const (
+	UploadStatusUploading  = "uploading"  // 上传中
+	UploadStatusAssembling = "assembling" // 已收齐，正在后台校验并写入对象存储
+	UploadStatusCompleted  = "completed"  // 已合并写入对象存储
+	UploadStatusFailed     = "failed"     // 文件未通过校验，需重新上传
+)
+
+// UploadSession 大文件断点续传会话，分片先追加到服务器的临时文件，收齐后在后台写入对象存储
+type UploadSession struct {
+	ID          string    `gorm:"primaryKey;size:36" json:"id"`         // 会话 ID（UUID）
+	UserID      uint      `gorm:"not null;index" json:"user_id"`        // 上传者 ID
+	Filename    string    `gorm:"size:255" json:"filename"`             // 原始文件名
+	ContentType string    `gorm:"size:100" json:"content_type"`         // 文件类型
+	Size        int64     `gorm:"not null" json:"size"`                 // 文件总大小（字节）
+	Offset      int64     `gorm:"not null;default:0" json:"offset"`     // 已接收的字节数
+	Checksum    string    `gorm:"size:64" json:"checksum"`              // 整个文件的 SHA-256（十六进制），为空则不校验
+	Status      string    `gorm:"size:20;not null;index" json:"status"` // 会话状态
+	URL         string    `gorm:"size:512" json:"url"`                  // 合并完成后的访问地址
+	Error       string    `gorm:"size:255" json:"error"`                // 最近一次合并失败的原因
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`     // 过期时间，每次收到分片会顺延
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
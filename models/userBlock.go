@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// UserBlock 拉黑关系，Uid 拉黑了 BlockedUid；双方互相不可关注，也不会出现在对方的用户搜索和推荐中
+type UserBlock struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Uid        uint      `gorm:"not null;uniqueIndex:idx_user_block" json:"uid"`               // 拉黑者 ID
+	BlockedUid uint      `gorm:"not null;uniqueIndex:idx_user_block;index" json:"blocked_uid"` // 被拉黑者 ID
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,155 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsProductionHost  = "https://api.push.apple.com"
+	apnsDevelopmentHost = "https://api.sandbox.push.apple.com"
+	apnsTokenLifetime   = 50 * time.Minute // Apple 要求令牌 20~60 分钟内刷新
+)
+
+// APNsConfig APNs 基于令牌（.p8 密钥）的鉴权参数
+type APNsConfig struct {
+	KeyFile    string // .p8 私钥文件路径
+	KeyID      string
+	TeamID     string
+	Topic      string // 应用的 Bundle ID
+	Production bool
+}
+
+// APNsProvider 苹果推送，走 HTTP/2 接口
+type APNsProvider struct {
+	cfg    APNsConfig
+	key    *ecdsa.PrivateKey
+	host   string
+	client *http.Client
+
+	mu          sync.Mutex
+	bearer      string
+	bearerIssue time.Time
+}
+
+// NewAPNsProvider 读取私钥并创建 APNs 推送
+func NewAPNsProvider(cfg APNsConfig) (*APNsProvider, error) {
+	pemBytes, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 APNs 私钥失败: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析 APNs 私钥失败: %w", err)
+	}
+	host := apnsDevelopmentHost
+	if cfg.Production {
+		host = apnsProductionHost
+	}
+	return &APNsProvider{
+		cfg:  cfg,
+		key:  key,
+		host: host,
+		// TLS 连接上 net/http 会自动协商 HTTP/2
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Name 厂商名
+func (p *APNsProvider) Name() string {
+	return "apns"
+}
+
+// authToken 返回缓存的鉴权令牌，过期前重新签发
+func (p *APNsProvider) authToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.bearer != "" && time.Since(p.bearerIssue) < apnsTokenLifetime {
+		return p.bearer, nil
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.cfg.TeamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = p.cfg.KeyID
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+	p.bearer = signed
+	p.bearerIssue = now
+	return signed, nil
+}
+
+// Send 发送一条推送
+func (p *APNsProvider) Send(ctx context.Context, token string, msg Message) error {
+	aps := map[string]interface{}{
+		"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+		"badge": msg.Badge,
+		"sound": "default",
+	}
+	payload := map[string]interface{}{"aps": aps}
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+
+	bearer, err := p.authToken()
+	if err != nil {
+		return fmt.Errorf("%w: 签发 APNs 令牌失败: %v", ErrPermanent, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.host+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", p.cfg.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	_ = json.Unmarshal(respBody, &result)
+
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		result.Reason == "BadDeviceToken", result.Reason == "Unregistered", result.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("%w: apns %d %s", ErrInvalidToken, resp.StatusCode, result.Reason)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("apns %d %s", resp.StatusCode, result.Reason)
+	case result.Reason == "ExpiredProviderToken":
+		// 令牌过期，清掉缓存后可重试
+		p.mu.Lock()
+		p.bearer = ""
+		p.mu.Unlock()
+		return fmt.Errorf("apns %d %s", resp.StatusCode, result.Reason)
+	default:
+		return fmt.Errorf("%w: apns %d %s", ErrPermanent, resp.StatusCode, result.Reason)
+	}
+}
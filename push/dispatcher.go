@@ -0,0 +1,251 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// 推送状态，对应 Notification.PushStatus
+const (
+	StatusNone    = "none"
+	StatusPending = "pending"
+	StatusSending = "sending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// staleSendingAfter 处于 sending 状态超过该时长视为进程中途退出，重新放回待推送
+const staleSendingAfter = 10 * time.Minute
+
+// Dispatcher 推送分发器
+// 通知创建后调用 Notify 立即入队；免打扰顺延的、队列满丢弃的、进程重启遗留的通知由定时扫描补发。
+// 入队前通过 pending -> sending 的条件更新抢占，保证同一条通知只推送一次
+type Dispatcher struct {
+	providers  map[string]Provider
+	jobs       chan uint
+	workers    int
+	maxRetries int
+	backoff    time.Duration
+	sweep      time.Duration
+	sleep      func(time.Duration) // 重试前的等待，测试中替换以免真实等待
+}
+
+// DefaultDispatcher 全局推送分发器，未初始化时 Notify 为空操作
+var DefaultDispatcher *Dispatcher
+
+// NewDispatcher 创建推送分发器
+func NewDispatcher(providers []Provider, workers, queueSize, maxRetries int, backoff, sweep time.Duration) *Dispatcher {
+	if workers <= 0 {
+		workers = 4
+	}
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	if sweep <= 0 {
+		sweep = time.Minute
+	}
+	d := &Dispatcher{
+		providers:  make(map[string]Provider, len(providers)),
+		jobs:       make(chan uint, queueSize),
+		workers:    workers,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+		sweep:      sweep,
+		sleep:      time.Sleep,
+	}
+	for _, p := range providers {
+		d.providers[p.Name()] = p
+	}
+	return d
+}
+
+// Start 启动推送协程和定时扫描
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.workers; i++ {
+		go func() {
+			for id := range d.jobs {
+				d.process(id)
+			}
+		}()
+	}
+	go func() {
+		ticker := time.NewTicker(d.sweep)
+		defer ticker.Stop()
+		for range ticker.C {
+			d.sweepPending()
+		}
+	}()
+}
+
+// Enqueue 把通知放入推送队列，队列已满时留给定时扫描补发
+func (d *Dispatcher) Enqueue(notificationID uint) {
+	select {
+	case d.jobs <- notificationID:
+	default:
+		log.Printf("推送队列已满，通知 %d 等待定时补发", notificationID)
+	}
+}
+
+// Notify 通过全局分发器推送通知
+func Notify(notificationID uint) {
+	if DefaultDispatcher == nil {
+		return
+	}
+	DefaultDispatcher.Enqueue(notificationID)
+}
+
+// sweepPending 补发已到投递时间的待推送通知，并回收卡在 sending 状态的通知
+func (d *Dispatcher) sweepPending() {
+	now := time.Now()
+	global.Db.Model(&models.Notification{}).
+		Where("push_status = ? AND updated_at < ?", StatusSending, now.Add(-staleSendingAfter)).
+		Update("push_status", StatusPending)
+
+	var ids []uint
+	if err := global.Db.Model(&models.Notification{}).
+		Where("push_status = ? AND deliver_at <= ?", StatusPending, now).
+		Order("id").Limit(cap(d.jobs)).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("扫描待推送通知失败: %v", err)
+		return
+	}
+	for _, id := range ids {
+		d.Enqueue(id)
+	}
+}
+
+// process 推送一条通知到接收者的所有设备
+func (d *Dispatcher) process(notificationID uint) {
+	// 抢占：只有 pending 且已到投递时间的通知才推送，免打扰顺延的交给定时扫描
+	result := global.Db.Model(&models.Notification{}).
+		Where("id = ? AND push_status = ? AND deliver_at <= ?", notificationID, StatusPending, time.Now()).
+		Update("push_status", StatusSending)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	status := d.deliver(notificationID)
+	global.Db.Model(&models.Notification{}).Where("id = ?", notificationID).Update("push_status", status)
+}
+
+// deliver 组装推送内容并发往各设备，返回最终的推送状态
+func (d *Dispatcher) deliver(notificationID uint) string {
+	var notification models.Notification
+	if err := global.Db.First(&notification, "id = ?", notificationID).Error; err != nil {
+		return StatusFailed
+	}
+	// 用户已经在应用内看过了，不再打扰
+	if notification.IsRead {
+		return StatusSkipped
+	}
+
+	var tokens []models.DeviceToken
+	global.Db.Where("user_id = ?", notification.RecipientID).Find(&tokens)
+	if len(tokens) == 0 {
+		return StatusSkipped
+	}
+
+	msg := composeMessage(notification)
+	delivered := false
+	for _, token := range tokens {
+		provider, ok := d.providers[token.Provider]
+		if !ok {
+			continue
+		}
+		err := d.sendWithRetry(provider, token.Token, msg)
+		switch {
+		case err == nil:
+			delivered = true
+		case errors.Is(err, ErrInvalidToken):
+			global.Db.Delete(&models.DeviceToken{}, token.ID)
+			log.Printf("设备令牌已失效，已删除: 用户 %d %s", token.UserID, token.Provider)
+		default:
+			log.Printf("推送通知 %d 到 %s 设备失败: %v", notificationID, token.Provider, err)
+		}
+	}
+	if delivered {
+		return StatusSent
+	}
+	return StatusFailed
+}
+
+// sendWithRetry 发送推送，可重试错误按指数退避加随机抖动重试
+func (d *Dispatcher) sendWithRetry(provider Provider, token string, msg Message) error {
+	var err error
+	for attempt := 0; attempt <= d.maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := d.backoff << (attempt - 1)
+			delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+			d.sleep(delay)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err = provider.Send(ctx, token, msg)
+		cancel()
+		if err == nil || errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrPermanent) {
+			return err
+		}
+	}
+	return err
+}
+
+// composeMessage 根据通知类型生成推送文案
+func composeMessage(n models.Notification) Message {
+	var initiator models.User
+	global.Db.Select("username").First(&initiator, "user_id = ?", n.InitiatorID)
+	var recipient models.User
+	global.Db.Select("unread_noti_count").First(&recipient, "user_id = ?", n.RecipientID)
+
+	actor := initiator.Username
+	if n.AggregateCount > 1 {
+		actor = fmt.Sprintf("%s 等 %d 人", actor, n.AggregateCount)
+	}
+
+	msg := Message{
+		Title: "新消息",
+		Badge: int(recipient.UnreadNotiCount),
+		Data: map[string]string{
+			"notification_id": strconv.FormatUint(uint64(n.ID), 10),
+			"type":            n.Type,
+			"target_type":     n.TargetType,
+			"target_id":       strconv.FormatUint(uint64(n.TargetID), 10),
+			"note_id":         strconv.FormatUint(uint64(n.NoteID), 10),
+		},
+	}
+	switch n.Type {
+	case "comment":
+		msg.Title = actor + " 评论了你"
+		msg.Body = n.CommentExcerpt
+	case "mention", "chat_mention":
+		msg.Title = actor + " 提到了你"
+		msg.Body = n.CommentExcerpt
+	case "follow":
+		msg.Title = "新的关注"
+		msg.Body = actor + " 关注了你"
+	case "like":
+		msg.Title = "收到新的赞"
+		msg.Body = actor + " 赞了你的笔记《" + n.TitleExcerpt + "》"
+	case "collect":
+		msg.Title = "笔记被收藏"
+		msg.Body = actor + " 收藏了你的笔记《" + n.TitleExcerpt + "》"
+	case "comment_like":
+		msg.Title = "收到新的赞"
+		msg.Body = actor + " 赞了你的评论：" + n.CommentExcerpt
+	default:
+		msg.Body = actor + " 与你互动了"
+	}
+	return msg
+}
@@ -0,0 +1,171 @@
+package push
+
+import (
+	"testing"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/testutil"
+)
+
+// newTestDispatcher 创建只带假推送的分发器，记录每次重试前的等待时间而不真正等待
+func newTestDispatcher(t *testing.T, maxRetries int, backoff time.Duration) (*Dispatcher, *FakeProvider, *[]time.Duration) {
+	t.Helper()
+	testutil.OpenDB(t, &models.User{}, &models.Notification{}, &models.DeviceToken{})
+	fake := NewFakeProvider()
+	d := NewDispatcher([]Provider{fake}, 1, 16, maxRetries, backoff, time.Minute)
+	var delays []time.Duration
+	d.sleep = func(delay time.Duration) { delays = append(delays, delay) }
+	return d, fake, &delays
+}
+
+// seedNotification 给用户 1 登记设备并创建一条待推送的通知
+func seedNotification(t *testing.T, tokens ...string) models.Notification {
+	t.Helper()
+	global.Db.Create(&models.User{UserId: 1, Username: "alice", Password: "x", UnreadNotiCount: 3})
+	global.Db.Create(&models.User{UserId: 2, Username: "bob", Password: "x"})
+	for _, token := range tokens {
+		if err := global.Db.Create(&models.DeviceToken{UserID: 1, Platform: "fake", Provider: "fake", Token: token}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := models.Notification{
+		InitiatorID:    2,
+		RecipientID:    1,
+		Type:           "follow",
+		InitiatedAt:    time.Now(),
+		DeliverAt:      time.Now().Add(-time.Second),
+		AggregateCount: 1,
+		PushStatus:     StatusPending,
+	}
+	if err := global.Db.Create(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func pushStatus(t *testing.T, id uint) string {
+	t.Helper()
+	var n models.Notification
+	if err := global.Db.First(&n, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n.PushStatus
+}
+
+func TestProcessRetriesWithBackoff(t *testing.T) {
+	d, fake, delays := newTestDispatcher(t, 3, 100*time.Millisecond)
+	n := seedNotification(t, "device-a")
+	fake.FailNext("device-a", 2)
+
+	d.process(n.ID)
+
+	if status := pushStatus(t, n.ID); status != StatusSent {
+		t.Fatalf("重试后应推送成功，状态为 %s", status)
+	}
+	if sent := fake.Sent(); len(sent) != 1 || sent[0].Message.Body != "bob 关注了你" || sent[0].Message.Badge != 3 {
+		t.Fatalf("推送内容不正确: %+v", sent)
+	}
+	// 两次失败对应两次等待：指数增长，抖动不超过基准的一半
+	if len(*delays) != 2 {
+		t.Fatalf("应等待 2 次，实际 %v", *delays)
+	}
+	for i, delay := range *delays {
+		base := 100 * time.Millisecond << i
+		if delay < base || delay > base+base/2 {
+			t.Errorf("第 %d 次重试等待 %v，应在 [%v, %v] 内", i+1, delay, base, base+base/2)
+		}
+	}
+}
+
+func TestProcessGivesUpAfterMaxRetries(t *testing.T) {
+	d, fake, delays := newTestDispatcher(t, 2, 10*time.Millisecond)
+	n := seedNotification(t, "device-a")
+	fake.FailNext("device-a", 5)
+
+	d.process(n.ID)
+
+	if status := pushStatus(t, n.ID); status != StatusFailed {
+		t.Fatalf("超过重试次数应标记失败，状态为 %s", status)
+	}
+	if len(*delays) != 2 || len(fake.Sent()) != 0 {
+		t.Fatalf("应只重试 2 次且没有送达，等待 %v，送达 %d", *delays, len(fake.Sent()))
+	}
+
+	// 已不是 pending 的通知不会被再次推送
+	d.process(n.ID)
+	if len(*delays) != 2 {
+		t.Fatalf("失败的通知不应被重复处理")
+	}
+}
+
+func TestProcessPrunesInvalidTokens(t *testing.T) {
+	d, fake, delays := newTestDispatcher(t, 3, 10*time.Millisecond)
+	n := seedNotification(t, "device-stale", "device-ok")
+	fake.MarkInvalid("device-stale")
+
+	d.process(n.ID)
+
+	if status := pushStatus(t, n.ID); status != StatusSent {
+		t.Fatalf("有设备送达即为成功，状态为 %s", status)
+	}
+	if len(*delays) != 0 {
+		t.Errorf("失效令牌不应重试，实际等待 %v", *delays)
+	}
+	var tokens []string
+	global.Db.Model(&models.DeviceToken{}).Order("id").Pluck("token", &tokens)
+	if len(tokens) != 1 || tokens[0] != "device-ok" {
+		t.Fatalf("失效令牌应被删除，剩余 %v", tokens)
+	}
+	if sent := fake.Sent(); len(sent) != 1 || sent[0].Token != "device-ok" {
+		t.Fatalf("只应推送到有效设备: %+v", sent)
+	}
+}
+
+func TestSweepRecoversStaleSending(t *testing.T) {
+	d, _, _ := newTestDispatcher(t, 0, time.Millisecond)
+	now := time.Now()
+	stale := models.Notification{InitiatorID: 2, RecipientID: 1, Type: "like", InitiatedAt: now, DeliverAt: now.Add(-time.Hour),
+		PushStatus: StatusSending, UpdatedAt: now.Add(-staleSendingAfter - time.Minute)}
+	inFlight := models.Notification{InitiatorID: 2, RecipientID: 1, Type: "like", InitiatedAt: now, DeliverAt: now.Add(-time.Hour),
+		PushStatus: StatusSending, UpdatedAt: now.Add(-time.Minute)}
+	deferred := models.Notification{InitiatorID: 2, RecipientID: 1, Type: "like", InitiatedAt: now, DeliverAt: now.Add(time.Hour),
+		PushStatus: StatusPending}
+	for _, n := range []*models.Notification{&stale, &inFlight, &deferred} {
+		if err := global.Db.Create(n).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d.sweepPending()
+
+	if status := pushStatus(t, stale.ID); status != StatusPending {
+		t.Errorf("卡住的 sending 应回到 pending，状态为 %s", status)
+	}
+	if status := pushStatus(t, inFlight.ID); status != StatusSending {
+		t.Errorf("正在推送的通知不应被回收，状态为 %s", status)
+	}
+	var queued []uint
+	for len(d.jobs) > 0 {
+		queued = append(queued, <-d.jobs)
+	}
+	if len(queued) != 1 || queued[0] != stale.ID {
+		t.Fatalf("只应补发回收的通知，入队 %v", queued)
+	}
+}
+
+func TestFakePlatformRequiresFakeEnabled(t *testing.T) {
+	defer func() { fakeEnabled = false }()
+
+	fakeEnabled = false
+	if p := ProviderForPlatform("fake"); p != "" {
+		t.Errorf("未启用假推送时 fake 平台应不受支持，实际 %q", p)
+	}
+	if p := ProviderForPlatform("ios"); p != "apns" {
+		t.Errorf("ios 应使用 apns，实际 %q", p)
+	}
+	fakeEnabled = true
+	if p := ProviderForPlatform("fake"); p != "fake" {
+		t.Errorf("启用假推送后 fake 平台应可用，实际 %q", p)
+	}
+}
@@ -0,0 +1,70 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SentMessage FakeProvider 记录的一次推送
+type SentMessage struct {
+	Token   string
+	Message Message
+}
+
+// FakeProvider 本地假推送，只记录不发送，可指定失效令牌和临时失败次数，用于测试和本地开发
+type FakeProvider struct {
+	mu        sync.Mutex
+	sent      []SentMessage
+	invalid   map[string]bool
+	failTimes map[string]int
+}
+
+// NewFakeProvider 创建假推送
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		invalid:   make(map[string]bool),
+		failTimes: make(map[string]int),
+	}
+}
+
+// Name 厂商名
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Send 记录推送；失效令牌返回 ErrInvalidToken，设置了失败次数的令牌先返回可重试错误
+func (p *FakeProvider) Send(ctx context.Context, token string, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.invalid[token] {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, token)
+	}
+	if p.failTimes[token] > 0 {
+		p.failTimes[token]--
+		return fmt.Errorf("模拟推送失败: %s", token)
+	}
+	p.sent = append(p.sent, SentMessage{Token: token, Message: msg})
+	return nil
+}
+
+// MarkInvalid 把令牌标记为失效
+func (p *FakeProvider) MarkInvalid(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.invalid[token] = true
+}
+
+// FailNext 让该令牌接下来的 n 次推送返回可重试错误
+func (p *FakeProvider) FailNext(token string, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failTimes[token] = n
+}
+
+// Sent 返回已记录推送的副本
+func (p *FakeProvider) Sent() []SentMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]SentMessage(nil), p.sent...)
+}
@@ -0,0 +1,210 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	fcmScope       = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmDefaultAuth = "https://oauth2.googleapis.com/token"
+)
+
+// FCMConfig FCM HTTP v1 接口参数
+type FCMConfig struct {
+	CredentialsFile string // 服务账号 JSON 文件路径
+	ProjectID       string // 为空时取服务账号中的 project_id
+}
+
+// FCMProvider 谷歌 Firebase 推送，使用服务账号换取 OAuth2 访问令牌
+type FCMProvider struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMProvider 读取服务账号并创建 FCM 推送
+func NewFCMProvider(cfg FCMConfig) (*FCMProvider, error) {
+	raw, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 FCM 服务账号失败: %w", err)
+	}
+	var account struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("解析 FCM 服务账号失败: %w", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("解析 FCM 私钥失败: %w", err)
+	}
+
+	projectID := cfg.ProjectID
+	if projectID == "" {
+		projectID = account.ProjectID
+	}
+	tokenURI := account.TokenURI
+	if tokenURI == "" {
+		tokenURI = fcmDefaultAuth
+	}
+	return &FCMProvider{
+		projectID:   projectID,
+		clientEmail: account.ClientEmail,
+		tokenURI:    tokenURI,
+		key:         key,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Name 厂商名
+func (p *FCMProvider) Name() string {
+	return "fcm"
+}
+
+// token 返回缓存的访问令牌，快过期时用服务账号签名的 JWT 重新换取
+func (p *FCMProvider) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.accessToken != "" && time.Until(p.expiresAt) > time.Minute {
+		return p.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   p.clientEmail,
+		"scope": fcmScope,
+		"aud":   p.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("换取 FCM 访问令牌失败: %d %s", resp.StatusCode, body)
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	p.accessToken = result.AccessToken
+	p.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
+
+// Send 发送一条推送
+func (p *FCMProvider) Send(ctx context.Context, token string, msg Message) error {
+	accessToken, err := p.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]string, len(msg.Data)+1)
+	for k, v := range msg.Data {
+		data[k] = v
+	}
+	data["badge"] = strconv.Itoa(msg.Badge)
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        token,
+			"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+			"data":         data,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+
+	endpoint := "https://fcm.googleapis.com/v1/projects/" + p.projectID + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	_ = json.Unmarshal(respBody, &result)
+	errorCode := result.Error.Status
+	for _, detail := range result.Error.Details {
+		if detail.ErrorCode != "" {
+			errorCode = detail.ErrorCode
+		}
+	}
+
+	switch {
+	case errorCode == "UNREGISTERED" || resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: fcm %d %s", ErrInvalidToken, resp.StatusCode, errorCode)
+	case errorCode == "INVALID_ARGUMENT" && strings.Contains(result.Error.Message, "registration token"):
+		return fmt.Errorf("%w: fcm %d %s", ErrInvalidToken, resp.StatusCode, result.Error.Message)
+	case resp.StatusCode == http.StatusUnauthorized:
+		// 访问令牌失效，清掉缓存后可重试
+		p.mu.Lock()
+		p.accessToken = ""
+		p.mu.Unlock()
+		return fmt.Errorf("fcm %d %s", resp.StatusCode, errorCode)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("fcm %d %s", resp.StatusCode, errorCode)
+	default:
+		return fmt.Errorf("%w: fcm %d %s", ErrPermanent, resp.StatusCode, errorCode)
+	}
+}
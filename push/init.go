@@ -0,0 +1,51 @@
+package push
+
+import (
+	"log"
+	"time"
+)
+
+// Options 推送初始化参数
+type Options struct {
+	Workers       int
+	QueueSize     int
+	MaxRetries    int
+	BackoffMillis int
+	SweepSeconds  int
+	APNs          APNsConfig
+	FCM           FCMConfig
+	Fake          bool // 启用本地假推送（只记录不发送），仅用于开发和测试环境
+}
+
+// Init 按配置创建各厂商适配器并启动全局分发器
+// 证书未配置或加载失败的厂商只打日志跳过，不影响服务启动；本地假推送需显式开启
+func Init(opts Options) {
+	var providers []Provider
+	fakeEnabled = opts.Fake
+	if opts.Fake {
+		log.Printf("已启用本地假推送，推送只记录不发送，请勿在生产环境开启")
+		providers = append(providers, NewFakeProvider())
+	}
+	if opts.APNs.KeyFile != "" {
+		if p, err := NewAPNsProvider(opts.APNs); err != nil {
+			log.Printf("APNs 推送未启用: %v", err)
+		} else {
+			providers = append(providers, p)
+		}
+	}
+	if opts.FCM.CredentialsFile != "" {
+		if p, err := NewFCMProvider(opts.FCM); err != nil {
+			log.Printf("FCM 推送未启用: %v", err)
+		} else {
+			providers = append(providers, p)
+		}
+	}
+
+	if len(providers) == 0 {
+		log.Printf("没有可用的推送厂商，移动端推送不会发出")
+	}
+
+	DefaultDispatcher = NewDispatcher(providers, opts.Workers, opts.QueueSize, opts.MaxRetries,
+		time.Duration(opts.BackoffMillis)*time.Millisecond, time.Duration(opts.SweepSeconds)*time.Second)
+	DefaultDispatcher.Start()
+}
@@ -0,0 +1,47 @@
+package push
+
+//移动端推送：按设备所属厂商选择 Provider（APNs/FCM/本地假实现），由 Dispatcher 异步投递
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidToken 设备令牌已失效（卸载应用、令牌过期等），调用方应删除该令牌
+var ErrInvalidToken = errors.New("设备令牌无效")
+
+// ErrPermanent 不可重试的错误（请求格式错误、鉴权配置错误等）
+var ErrPermanent = errors.New("推送请求被拒绝")
+
+// Message 推送内容
+type Message struct {
+	Title string
+	Body  string
+	Badge int               // 角标数，即未读消息数
+	Data  map[string]string // 附带给客户端的自定义数据，如通知 ID、跳转目标
+}
+
+// Provider 推送厂商适配器
+// Send 返回的错误若包装了 ErrInvalidToken 表示需要删除令牌，包装了 ErrPermanent 表示不必重试，其余错误按退避策略重试
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, token string, msg Message) error
+}
+
+// 设备平台对应的推送厂商
+var platformProviders = map[string]string{
+	"ios":     "apns",
+	"android": "fcm",
+}
+
+// fakeEnabled 是否启用本地假推送，只在开发/测试配置中开启
+var fakeEnabled bool
+
+// ProviderForPlatform 返回设备平台对应的推送厂商名，不支持的平台返回空字符串；
+// fake 平台只在启用假推送时可用，避免生产环境的推送被只记录不发送的假实现吞掉
+func ProviderForPlatform(platform string) string {
+	if platform == "fake" && fakeEnabled {
+		return "fake"
+	}
+	return platformProviders[platform]
+}
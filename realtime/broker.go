@@ -0,0 +1,49 @@
+package realtime
+
+import "sync"
+
+// Broker 消息总线接口
+// 单实例部署使用 LocalBroker；多实例部署时实现基于 Redis/NATS 等的适配器，
+// 每个实例都订阅总线，收到事件后只投递给本机上的连接
+type Broker interface {
+	Publish(event Event) error
+	Subscribe(handler func(event Event)) error
+	Close() error
+}
+
+// LocalBroker 进程内 broker，发布时同步回调所有订阅者
+type LocalBroker struct {
+	mu       sync.RWMutex
+	handlers []func(event Event)
+}
+
+// NewLocalBroker 创建进程内 broker
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{}
+}
+
+// Publish 发布事件
+func (b *LocalBroker) Publish(event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(event)
+	}
+	return nil
+}
+
+// Subscribe 订阅事件
+func (b *LocalBroker) Subscribe(handler func(event Event)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+// Close 关闭 broker
+func (b *LocalBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = nil
+	return nil
+}
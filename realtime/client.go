@@ -0,0 +1,48 @@
+package realtime
+
+import "sync"
+
+// Client 一个推送连接（WebSocket 或 SSE）
+type Client struct {
+	UserID    uint
+	hub       *Hub
+	send      chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newClient(hub *Hub, userID uint, bufferSize int) *Client {
+	return &Client{
+		UserID: userID,
+		hub:    hub,
+		send:   make(chan Event, bufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// enqueue 非阻塞地放入发送缓冲区，缓冲区满时返回 false
+func (c *Client) enqueue(event Event) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+	select {
+	case c.send <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 关闭连接，可重复调用
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// Done 连接关闭时被关闭的 channel
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
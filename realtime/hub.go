@@ -0,0 +1,143 @@
+package realtime
+
+//实时推送：进程内发布订阅中心，按用户维护连接，消息经 Broker 分发后投递到本机连接
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventNotification        = "notification"         // 新通知或聚合通知更新
+	EventNotificationRemoved = "notification_removed" // 通知被撤回（如取消点赞后聚合人数归零）
+	EventUnreadCount         = "unread_count"         // 未读数变化
+	EventChatMessage         = "chat_message"         // 群聊消息
+)
+
+// Event 推送给客户端的事件，Data 预先序列化，方便通过消息总线跨实例传输
+type Event struct {
+	Type   string          `json:"type"`
+	UserID uint            `json:"-"` // 接收者 ID
+	Data   json.RawMessage `json:"data"`
+	Time   int64           `json:"time"`
+}
+
+// Hub 连接管理中心
+type Hub struct {
+	mu         sync.RWMutex
+	clients    map[uint]map[*Client]struct{} // 用户 ID -> 该用户的所有连接
+	broker     Broker
+	bufferSize int
+	heartbeat  time.Duration
+}
+
+// DefaultHub 全局推送中心，未初始化时所有推送都是空操作
+var DefaultHub *Hub
+
+// NewHub 创建推送中心并订阅 broker 上的事件
+func NewHub(broker Broker, bufferSize int, heartbeat time.Duration) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 64
+	}
+	if heartbeat <= 0 {
+		heartbeat = 30 * time.Second
+	}
+	h := &Hub{
+		clients:    make(map[uint]map[*Client]struct{}),
+		broker:     broker,
+		bufferSize: bufferSize,
+		heartbeat:  heartbeat,
+	}
+	if err := broker.Subscribe(h.deliver); err != nil {
+		log.Printf("订阅推送消息失败: %v", err)
+	}
+	return h
+}
+
+// InitHub 使用进程内 broker 初始化全局推送中心
+func InitHub(bufferSize int, heartbeatSeconds int) {
+	DefaultHub = NewHub(NewLocalBroker(), bufferSize, time.Duration(heartbeatSeconds)*time.Second)
+}
+
+// Heartbeat 心跳间隔
+func (h *Hub) Heartbeat() time.Duration {
+	return h.heartbeat
+}
+
+// Register 为用户登记一个新连接
+func (h *Hub) Register(userID uint) *Client {
+	client := newClient(h, userID, h.bufferSize)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*Client]struct{})
+	}
+	h.clients[userID][client] = struct{}{}
+	return client
+}
+
+// Unregister 移除连接，连接关闭时调用
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	if conns, ok := h.clients[client.UserID]; ok {
+		delete(conns, client)
+		if len(conns) == 0 {
+			delete(h.clients, client.UserID)
+		}
+	}
+	h.mu.Unlock()
+	client.Close()
+}
+
+// OnlineCount 用户当前在本实例上的连接数
+func (h *Hub) OnlineCount(userID uint) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID])
+}
+
+// Publish 向指定用户发布事件，事件先交给 broker，由 broker 回调投递
+func (h *Hub) Publish(userID uint, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return h.broker.Publish(Event{
+		Type:   eventType,
+		UserID: userID,
+		Data:   payload,
+		Time:   time.Now().Unix(),
+	})
+}
+
+// deliver 把事件投递到本实例上该用户的所有连接
+// 发送缓冲区已满说明客户端消费过慢，直接断开，客户端重连后重新拉取即可
+func (h *Hub) deliver(event Event) {
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.clients[event.UserID] {
+		if !client.enqueue(event) {
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range slow {
+		log.Printf("用户 %d 的推送连接消费过慢，已断开", client.UserID)
+		h.Unregister(client)
+	}
+}
+
+// Publish 通过全局推送中心向用户发布事件
+func Publish(userID uint, eventType string, data interface{}) {
+	if DefaultHub == nil {
+		return
+	}
+	if err := DefaultHub.Publish(userID, eventType, data); err != nil {
+		log.Printf("推送事件失败: %v", err)
+	}
+}
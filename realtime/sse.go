@@ -0,0 +1,48 @@
+package realtime
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ServeSSE 以 Server-Sent Events 方式推送该用户的事件（WebSocket 不可用时的降级方案），阻塞直到连接断开
+func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request, userID uint) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("当前连接不支持流式响应")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	client := h.Register(userID)
+	defer h.Unregister(client)
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-client.send:
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			// 注释行作为心跳，防止代理断开空闲连接
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-client.Done():
+			return nil
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
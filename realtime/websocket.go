@@ -0,0 +1,120 @@
+package realtime
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second // 单次写超时
+	maxMessageSize = 4096             // 客户端上行消息最大长度（只用于保活，不处理业务）
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// CORS 对 WebSocket 握手不起作用，而令牌可以放在 query 里，必须在这里校验来源，
+	// 否则任意网站都能以用户身份建立连接
+	CheckOrigin: checkOrigin,
+}
+
+// allowedOrigins 允许建立 WebSocket 连接的来源（scheme://host[:port]），"*" 表示不限制
+var allowedOrigins = map[string]bool{}
+
+// SetAllowedOrigins 设置允许的跨域来源，未设置时只允许同源
+func SetAllowedOrigins(origins []string) {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		origin = strings.TrimSpace(origin)
+		if origin != "*" {
+			origin = strings.ToLower(strings.TrimRight(origin, "/"))
+		}
+		if origin != "" {
+			allowed[origin] = true
+		}
+	}
+	allowedOrigins = allowed
+}
+
+// checkOrigin 没有 Origin 头的不是浏览器发起的连接，直接放行；浏览器连接要求同源或在允许列表中
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || allowedOrigins["*"] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return allowedOrigins[strings.ToLower(u.Scheme+"://"+u.Host)]
+}
+
+// ServeWebSocket 升级为 WebSocket 连接并持续推送该用户的事件，阻塞直到连接断开
+func (h *Hub) ServeWebSocket(w http.ResponseWriter, r *http.Request, userID uint) error {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	client := h.Register(userID)
+	defer h.Unregister(client)
+
+	go h.readPump(conn, client)
+	h.writePump(conn, client)
+	return nil
+}
+
+// readPump 读取客户端消息以处理 pong 和断开，超过两个心跳周期没有响应则断开
+func (h *Hub) readPump(conn *websocket.Conn, client *Client) {
+	defer client.Close()
+
+	pongWait := 2 * h.heartbeat
+	conn.SetReadLimit(maxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		// 客户端的任何消息都视为存活
+		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	}
+}
+
+// writePump 发送事件与心跳 ping
+func (h *Hub) writePump(conn *websocket.Conn, client *Client) {
+	ticker := time.NewTicker(h.heartbeat)
+	defer func() {
+		ticker.Stop()
+		_ = conn.Close()
+	}()
+
+	for {
+		select {
+		case event := <-client.send:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-client.Done():
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(writeWait))
+			return
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package realtime
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	defer SetAllowedOrigins(nil)
+
+	cases := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"非浏览器客户端", nil, "", true},
+		{"同源", nil, "http://api.example.com", true},
+		{"未配置的跨域来源", nil, "https://evil.example", false},
+		{"允许列表中的来源", []string{"https://app.example.com/"}, "https://APP.example.com", true},
+		{"端口不同", []string{"https://app.example.com"}, "https://app.example.com:8443", false},
+		{"不限制来源", []string{"*"}, "https://evil.example", true},
+		{"无效的 Origin", []string{"https://app.example.com"}, "null", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			SetAllowedOrigins(c.allowed)
+			r := httptest.NewRequest("GET", "http://api.example.com/api/realtime/ws", nil)
+			if c.origin != "" {
+				r.Header.Set("Origin", c.origin)
+			}
+			if got := checkOrigin(r); got != c.want {
+				t.Errorf("checkOrigin(%q) = %v, want %v", c.origin, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package resumable
+
+//大文件断点续传，协议参照 tus：先创建上传会话，再按偏移量逐片 PATCH，断线后查询已接收的偏移量接着传。
+//分片先追加到服务器的临时文件，可带 SHA-256 校验，校验不通过的分片会被丢弃；
+//收齐后在后台校验整个文件并写入对象存储（大文件分片上传），客户端查询进度直到完成。
+//长时间没有新分片的会话过期后连同临时文件一起清理
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/media"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound          = errors.New("上传会话不存在")
+	ErrExpired           = errors.New("上传会话已过期")
+	ErrCompleted         = errors.New("文件已上传完成")
+	ErrOffsetMismatch    = errors.New("偏移量与已接收的字节数不一致")
+	ErrChecksumMismatch  = errors.New("分片校验失败")
+	ErrChecksumAlgorithm = errors.New("不支持的校验算法，仅支持 sha256")
+	ErrFileChecksum      = errors.New("文件校验失败，请重新上传")
+	ErrTooLarge          = errors.New("文件或分片大小超出限制")
+	ErrInvalidVideo      = errors.New("不支持的视频格式，仅支持 mp4 和 mov")
+	ErrForbidden         = errors.New("只能操作自己的上传会话")
+	ErrAssembling        = errors.New("文件正在合并，请稍后查询进度")
+	ErrFailed            = errors.New("文件未通过校验，请重新创建上传")
+)
+
+// Options 断点续传参数
+type Options struct {
+	TempDir        string // 分片临时文件目录，不能放在本地存储对外提供访问的目录下
+	SessionHours   int    // 会话在最后一次收到分片后保留的小时数
+	MaxSizeMB      int64  // 单个文件大小上限（MB）
+	MaxChunkMB     int64  // 单个分片大小上限（MB）
+	CleanupMinutes int    // 清理过期会话的间隔（分钟）
+}
+
+var opts = Options{
+	TempDir:        "./upload_tmp",
+	SessionHours:   24,
+	MaxSizeMB:      20 * 1024,
+	MaxChunkMB:     64,
+	CleanupMinutes: 30,
+}
+
+// videoTypes 允许的视频扩展名及其内容类型
+var videoTypes = map[string]string{
+	".mp4": "video/mp4",
+	".mov": "video/quicktime",
+}
+
+// locks 同一会话的分片串行写入
+var locks sync.Map
+
+// assembling 正在后台合并的会话数，测试中用来等待合并结束
+var assembling sync.WaitGroup
+
+// Init 设置参数、创建临时目录并启动过期会话清理，需在数据库初始化之后调用
+func Init(o Options) {
+	if o.TempDir != "" {
+		opts.TempDir = o.TempDir
+	}
+	if o.SessionHours > 0 {
+		opts.SessionHours = o.SessionHours
+	}
+	if o.MaxSizeMB > 0 {
+		opts.MaxSizeMB = o.MaxSizeMB
+	}
+	if o.MaxChunkMB > 0 {
+		opts.MaxChunkMB = o.MaxChunkMB
+	}
+	if o.CleanupMinutes > 0 {
+		opts.CleanupMinutes = o.CleanupMinutes
+	}
+	if err := os.MkdirAll(opts.TempDir, 0o755); err != nil {
+		log.Printf("创建上传临时目录失败: %v", err)
+	}
+	resumeAssembly()
+	go cleanupLoop()
+}
+
+// resumeAssembly 重新合并上次退出时还没合并完的会话
+func resumeAssembly() {
+	var sessions []models.UploadSession
+	if err := global.Db.Where("status = ?", models.UploadStatusAssembling).Find(&sessions).Error; err != nil {
+		log.Printf("查询合并中的上传会话失败: %v", err)
+		return
+	}
+	for _, session := range sessions {
+		assembling.Add(1)
+		go assemble(session)
+	}
+}
+
+// MaxSize 单个文件大小上限（字节）
+func MaxSize() int64 {
+	return opts.MaxSizeMB << 20
+}
+
+func lock(id string) func() {
+	v, _ := locks.LoadOrStore(id, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+func partPath(id string) string {
+	return filepath.Join(opts.TempDir, id+".part")
+}
+
+// Create 创建视频上传会话，checksum 为整个文件的 SHA-256（十六进制），可为空
+func Create(userID uint, filename string, size int64, checksum string) (*models.UploadSession, error) {
+	contentType, ok := videoTypes[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return nil, ErrInvalidVideo
+	}
+	if size <= 0 || size > MaxSize() {
+		return nil, ErrTooLarge
+	}
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if checksum != "" {
+		if b, err := hex.DecodeString(checksum); err != nil || len(b) != sha256.Size {
+			return nil, ErrChecksumAlgorithm
+		}
+	}
+
+	session := &models.UploadSession{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		Size:        size,
+		Checksum:    checksum,
+		Status:      models.UploadStatusUploading,
+		ExpiresAt:   time.Now().Add(time.Duration(opts.SessionHours) * time.Hour),
+	}
+	f, err := os.Create(partPath(session.ID))
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	f.Close()
+	if err := global.Db.Create(session).Error; err != nil {
+		os.Remove(partPath(session.ID))
+		return nil, err
+	}
+	return session, nil
+}
+
+// Get 查询会话，过期的会话视为不存在
+func Get(id string) (*models.UploadSession, error) {
+	var session models.UploadSession
+	if err := global.Db.First(&session, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if session.Status == models.UploadStatusUploading && time.Now().After(session.ExpiresAt) {
+		return &session, ErrExpired
+	}
+	return &session, nil
+}
+
+// parseChecksum 解析 tus 格式的校验头："sha256 <base64>"
+func parseChecksum(header string) ([]byte, error) {
+	if header == "" {
+		return nil, nil
+	}
+	algorithm, value, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(algorithm, "sha256") {
+		return nil, ErrChecksumAlgorithm
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(sum) != sha256.Size {
+		return nil, ErrChecksumMismatch
+	}
+	return sum, nil
+}
+
+// WriteChunk 从 offset 处追加一个分片，返回更新后的会话；收齐后会话进入合并中，在后台写入对象存储。
+// 偏移量不一致时返回的会话带有当前已接收的字节数，客户端据此续传。
+// 没有带校验的分片在连接中断时保留已收到的部分，带校验的分片要么整片接收要么整片丢弃
+func WriteChunk(id string, offset int64, body io.Reader, checksumHeader string) (*models.UploadSession, error) {
+	expected, err := parseChecksum(checksumHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := lock(id)
+	defer unlock()
+
+	session, err := Get(id)
+	if err != nil {
+		return session, err
+	}
+	switch session.Status {
+	case models.UploadStatusCompleted:
+		return session, ErrCompleted
+	case models.UploadStatusAssembling:
+		return session, ErrAssembling
+	case models.UploadStatusFailed:
+		return session, ErrFailed
+	}
+	if offset != session.Offset {
+		return session, ErrOffsetMismatch
+	}
+	// 上次合并写入对象存储失败时，客户端带着末尾偏移量重试即可重新合并
+	if session.Offset == session.Size {
+		return session, startAssembly(session)
+	}
+
+	f, err := os.OpenFile(partPath(id), os.O_WRONLY, 0)
+	if err != nil {
+		return session, fmt.Errorf("打开临时文件失败: %v", err)
+	}
+	defer f.Close()
+	// 丢掉上一次异常中断时可能残留在偏移量之后的数据
+	if err := f.Truncate(offset); err != nil {
+		return session, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return session, err
+	}
+
+	limit := min(session.Size-offset, opts.MaxChunkMB<<20)
+	h := sha256.New()
+	n, copyErr := io.Copy(io.MultiWriter(f, h), io.LimitReader(body, limit))
+	if copyErr == nil {
+		// 分片超出剩余大小或分片上限
+		if extra, _ := io.ReadFull(body, make([]byte, 1)); extra > 0 {
+			f.Truncate(offset)
+			return session, ErrTooLarge
+		}
+	}
+	if copyErr != nil && (expected != nil || n == 0) {
+		f.Truncate(offset)
+		return session, copyErr
+	}
+	if expected != nil && !bytes.Equal(h.Sum(nil), expected) {
+		f.Truncate(offset)
+		return session, ErrChecksumMismatch
+	}
+
+	session.Offset = offset + n
+	session.ExpiresAt = time.Now().Add(time.Duration(opts.SessionHours) * time.Hour)
+	if err := global.Db.Model(session).Updates(map[string]interface{}{
+		"offset":     session.Offset,
+		"expires_at": session.ExpiresAt,
+	}).Error; err != nil {
+		f.Truncate(offset)
+		session.Offset = offset
+		return session, err
+	}
+	if copyErr != nil {
+		return session, copyErr
+	}
+	if session.Offset == session.Size {
+		f.Close()
+		return session, startAssembly(session)
+	}
+	return session, nil
+}
+
+// startAssembly 把会话标记为合并中并在后台合并，最后一个分片的请求不用等整个文件写入对象存储。调用方需持有会话锁
+func startAssembly(session *models.UploadSession) error {
+	if err := global.Db.Model(session).Updates(map[string]interface{}{
+		"status": models.UploadStatusAssembling,
+		"error":  "",
+	}).Error; err != nil {
+		return err
+	}
+	session.Status, session.Error = models.UploadStatusAssembling, ""
+	assembling.Add(1)
+	go assemble(*session)
+	return nil
+}
+
+// assemble 后台合并：校验通过则写入对象存储并完成会话。内容或校验不通过时会话标记为失败并删除临时文件；
+// 写入对象存储失败时会话退回上传中，客户端带着末尾偏移量重新 PATCH 即可重试
+func assemble(session models.UploadSession) {
+	defer assembling.Done()
+
+	url, hash, err := store(&session)
+	unlock := lock(session.ID)
+	defer unlock()
+
+	var updates map[string]interface{}
+	switch {
+	case err == nil:
+		updates = map[string]interface{}{"status": models.UploadStatusCompleted, "url": url, "error": ""}
+	case errors.Is(err, ErrInvalidVideo), errors.Is(err, ErrFileChecksum):
+		updates = map[string]interface{}{"status": models.UploadStatusFailed, "error": err.Error()}
+	default:
+		log.Printf("合并上传会话 %s 失败: %v", session.ID, err)
+		updates = map[string]interface{}{"status": models.UploadStatusUploading, "error": "写入存储失败，请重新提交最后一个分片"}
+	}
+	if err := global.Db.Model(&models.UploadSession{}).Where("id = ?", session.ID).Updates(updates).Error; err != nil {
+		log.Printf("更新上传会话 %s 失败: %v", session.ID, err)
+		return
+	}
+	if updates["status"] == models.UploadStatusUploading {
+		return // 保留临时文件等待重试
+	}
+	if err == nil {
+		if err := media.Register(session.UserID, url, models.MediaKindVideo, session.ContentType, session.Size, hash); err != nil {
+			log.Printf("登记视频 %s 失败: %v", url, err)
+		}
+	}
+	if err := os.Remove(partPath(session.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("删除上传临时文件失败: %v", err)
+	}
+}
+
+// store 校验收齐的文件并写入对象存储，返回访问地址和文件的 SHA-256
+func store(session *models.UploadSession) (string, string, error) {
+	f, err := os.Open(partPath(session.ID))
+	if err != nil {
+		return "", "", fmt.Errorf("打开临时文件失败: %v", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(f, head); err != nil || !media.IsVideoHeader(head) {
+		return "", "", ErrInvalidVideo
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	hash, err := media.Hash(f)
+	if err != nil {
+		return "", "", err
+	}
+	if session.Checksum != "" && hash != session.Checksum {
+		return "", "", ErrFileChecksum
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	key := storage.NewKey("note_videos", session.Filename)
+	if err := storage.Default().Put(key, f, session.Size, session.ContentType); err != nil {
+		return "", "", fmt.Errorf("视频写入对象存储失败: %v", err)
+	}
+	return storage.Default().URL(key), hash, nil
+}
+
+// discard 删除会话和临时文件
+func discard(id string) {
+	if err := os.Remove(partPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("删除上传临时文件失败: %v", err)
+	}
+	if err := global.Db.Delete(&models.UploadSession{}, "id = ?", id).Error; err != nil {
+		log.Printf("删除上传会话失败: %v", err)
+	}
+}
+
+// Abort 取消上传，只能取消自己的会话
+func Abort(id string, userID uint) error {
+	unlock := lock(id)
+	defer unlock()
+
+	session, err := Get(id)
+	if err != nil && !errors.Is(err, ErrExpired) {
+		return err
+	}
+	if session.UserID != userID {
+		return ErrForbidden
+	}
+	switch session.Status {
+	case models.UploadStatusCompleted:
+		return ErrCompleted
+	case models.UploadStatusAssembling:
+		return ErrAssembling
+	}
+	discard(id)
+	locks.Delete(id)
+	return nil
+}
+
+// cleanupLoop 定期清理过期会话
+func cleanupLoop() {
+	ticker := time.NewTicker(time.Duration(opts.CleanupMinutes) * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		cleanup()
+	}
+}
+
+// cleanup 删除过期会话及其临时文件；已完成和失败的会话记录也在过期后删除，合并中的会话等合并结束
+func cleanup() {
+	var ids []string
+	if err := global.Db.Model(&models.UploadSession{}).
+		Where("expires_at < ? AND status <> ?", time.Now(), models.UploadStatusAssembling).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("查询过期上传会话失败: %v", err)
+		return
+	}
+	for _, id := range ids {
+		unlock := lock(id)
+		discard(id)
+		unlock()
+		locks.Delete(id)
+	}
+	if len(ids) > 0 {
+		log.Printf("清理过期上传会话 %d 个", len(ids))
+	}
+}
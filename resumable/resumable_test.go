@@ -0,0 +1,301 @@
+package resumable
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/storage"
+	"travel-from-sysu-backend/testutil"
+)
+
+// setup 准备内存数据库、临时目录和本地存储，返回存储目录
+func setup(t *testing.T) string {
+	t.Helper()
+	testutil.OpenDB(t, &models.UploadSession{}, &models.Media{})
+	previous := opts
+	opts.TempDir = t.TempDir()
+	t.Cleanup(func() { opts = previous })
+
+	dir := t.TempDir()
+	if err := storage.Init(storage.Config{
+		Driver: "local",
+		Local:  storage.LocalConfig{Dir: dir, BaseURL: "http://localhost:3000", Secret: "test-secret"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// video 生成以 ftyp 开头的 n 字节视频内容
+func video(n int) []byte {
+	data := bytes.Repeat([]byte{0x5a}, n)
+	copy(data, "\x00\x00\x00\x18ftypisom")
+	return data
+}
+
+func chunkChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256 " + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func fileChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// disconnect 读完底层数据后返回 ErrUnexpectedEOF，模拟分片传到一半连接中断
+type disconnect struct {
+	r io.Reader
+}
+
+func (d *disconnect) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func newSession(t *testing.T, data []byte, checksum string) *models.UploadSession {
+	t.Helper()
+	session, err := Create(1, "trip.mp4", int64(len(data)), checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func partSize(t *testing.T, id string) int64 {
+	t.Helper()
+	info, err := os.Stat(partPath(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestWriteChunkOffsetMismatch(t *testing.T) {
+	setup(t)
+	data := video(100)
+	session := newSession(t, data, "")
+
+	if _, err := WriteChunk(session.ID, 0, bytes.NewReader(data[:40]), ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, offset := range []int64{0, 20, 60} {
+		got, err := WriteChunk(session.ID, offset, bytes.NewReader(data[offset:]), "")
+		if !errors.Is(err, ErrOffsetMismatch) {
+			t.Fatalf("offset %d: 期望 ErrOffsetMismatch，得到 %v", offset, err)
+		}
+		if got.Offset != 40 {
+			t.Fatalf("offset %d: 返回的会话偏移量应为 40，得到 %d", offset, got.Offset)
+		}
+	}
+	if n := partSize(t, session.ID); n != 40 {
+		t.Fatalf("偏移量不一致的分片不应写入，临时文件大小 %d", n)
+	}
+}
+
+func TestWriteChunkChecksum(t *testing.T) {
+	setup(t)
+	data := video(100)
+	session := newSession(t, data, "")
+
+	if _, err := WriteChunk(session.ID, 0, bytes.NewReader(data[:50]), chunkChecksum(data[10:60])); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("期望 ErrChecksumMismatch，得到 %v", err)
+	}
+	if _, err := WriteChunk(session.ID, 0, bytes.NewReader(data[:50]), "md5 xxxx"); !errors.Is(err, ErrChecksumAlgorithm) {
+		t.Fatalf("期望 ErrChecksumAlgorithm，得到 %v", err)
+	}
+	got, err := Get(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Offset != 0 || partSize(t, session.ID) != 0 {
+		t.Fatalf("校验不通过的分片应整片丢弃，偏移量 %d，临时文件 %d", got.Offset, partSize(t, session.ID))
+	}
+
+	got, err = WriteChunk(session.ID, 0, bytes.NewReader(data[:50]), chunkChecksum(data[:50]))
+	if err != nil || got.Offset != 50 {
+		t.Fatalf("校验通过的分片应写入: offset=%d err=%v", got.Offset, err)
+	}
+}
+
+func TestWriteChunkDisconnect(t *testing.T) {
+	setup(t)
+	data := video(100)
+	session := newSession(t, data, "")
+
+	// 不带校验的分片断线时保留已收到的部分
+	got, err := WriteChunk(session.ID, 0, &disconnect{bytes.NewReader(data[:30])}, "")
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("期望返回读取错误，得到 %v", err)
+	}
+	if got.Offset != 30 || partSize(t, session.ID) != 30 {
+		t.Fatalf("应保留已收到的 30 字节，偏移量 %d，临时文件 %d", got.Offset, partSize(t, session.ID))
+	}
+
+	// 带校验的分片断线时整片丢弃
+	got, err = WriteChunk(session.ID, 30, &disconnect{bytes.NewReader(data[30:50])}, chunkChecksum(data[30:80]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("期望返回读取错误，得到 %v", err)
+	}
+	if got.Offset != 30 || partSize(t, session.ID) != 30 {
+		t.Fatalf("带校验的分片中断后应截断到 30，偏移量 %d，临时文件 %d", got.Offset, partSize(t, session.ID))
+	}
+
+	// 异常退出残留在偏移量之后的数据在下一个分片写入前截掉
+	f, err := os.OpenFile(partPath(session.ID), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("garbage"))
+	f.Close()
+	if _, err := WriteChunk(session.ID, 30, bytes.NewReader(data[30:60]), ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := partSize(t, session.ID); n != 60 {
+		t.Fatalf("残留数据应被截掉，临时文件大小 %d", n)
+	}
+	content, _ := os.ReadFile(partPath(session.ID))
+	if !bytes.Equal(content, data[:60]) {
+		t.Fatal("临时文件内容与已上传的数据不一致")
+	}
+}
+
+func TestAssembleInBackground(t *testing.T) {
+	dir := setup(t)
+	data := video(100)
+	session := newSession(t, data, fileChecksum(data))
+
+	if _, err := WriteChunk(session.ID, 0, bytes.NewReader(data[:60]), ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := WriteChunk(session.ID, 60, bytes.NewReader(data[60:]), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.UploadStatusAssembling {
+		t.Fatalf("最后一个分片后应进入合并中，得到 %s", got.Status)
+	}
+	assembling.Wait()
+
+	got, err = Get(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.UploadStatusCompleted || got.URL == "" {
+		t.Fatalf("合并后应完成并带有 URL: status=%s url=%q", got.Status, got.URL)
+	}
+	if _, err := os.Stat(partPath(session.ID)); !os.IsNotExist(err) {
+		t.Fatal("合并完成后应删除临时文件")
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "note_videos", "*"))
+	if len(matches) != 1 {
+		t.Fatalf("对象存储中应有一个视频，得到 %v", matches)
+	}
+	stored, _ := os.ReadFile(matches[0])
+	if !bytes.Equal(stored, data) {
+		t.Fatal("存储的视频内容不一致")
+	}
+	if _, err := WriteChunk(session.ID, 100, bytes.NewReader(nil), ""); !errors.Is(err, ErrCompleted) {
+		t.Fatalf("完成后再写入应返回 ErrCompleted，得到 %v", err)
+	}
+}
+
+func TestAssembleFileChecksumFails(t *testing.T) {
+	setup(t)
+	data := video(100)
+	other := append([]byte(nil), data...)
+	other[99] ^= 0xff
+	session := newSession(t, data, fileChecksum(other))
+
+	if _, err := WriteChunk(session.ID, 0, bytes.NewReader(data), ""); err != nil {
+		t.Fatal(err)
+	}
+	assembling.Wait()
+
+	got, err := Get(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.UploadStatusFailed || got.Error != ErrFileChecksum.Error() {
+		t.Fatalf("整个文件校验不通过应标记失败: status=%s error=%q", got.Status, got.Error)
+	}
+	if _, err := os.Stat(partPath(session.ID)); !os.IsNotExist(err) {
+		t.Fatal("校验失败后应删除临时文件")
+	}
+	if _, err := WriteChunk(session.ID, 100, bytes.NewReader(nil), ""); !errors.Is(err, ErrFailed) {
+		t.Fatalf("失败的会话不能再写入，得到 %v", err)
+	}
+}
+
+func TestAssembleStorageFailureRetry(t *testing.T) {
+	dir := setup(t)
+	data := video(100)
+	session := newSession(t, data, "")
+
+	// 用同名文件挡住视频目录，让写入对象存储失败
+	blocker := filepath.Join(dir, "note_videos")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteChunk(session.ID, 0, bytes.NewReader(data), ""); err != nil {
+		t.Fatal(err)
+	}
+	assembling.Wait()
+
+	got, err := Get(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.UploadStatusUploading || got.Error == "" || got.Offset != 100 {
+		t.Fatalf("写入存储失败应退回上传中: status=%s error=%q offset=%d", got.Status, got.Error, got.Offset)
+	}
+	if n := partSize(t, session.ID); n != 100 {
+		t.Fatalf("写入存储失败应保留临时文件，大小 %d", n)
+	}
+
+	os.Remove(blocker)
+	if _, err := WriteChunk(session.ID, 100, bytes.NewReader(nil), ""); err != nil {
+		t.Fatal(err)
+	}
+	assembling.Wait()
+	got, err = Get(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.UploadStatusCompleted || got.Error != "" {
+		t.Fatalf("重试后应完成: status=%s error=%q", got.Status, got.Error)
+	}
+}
+
+func TestWriteWhileAssembling(t *testing.T) {
+	setup(t)
+	data := video(100)
+	session := newSession(t, data, "")
+
+	if _, err := WriteChunk(session.ID, 0, bytes.NewReader(data), ""); err != nil {
+		t.Fatal(err)
+	}
+	// 后台合并何时结束不确定，等它结束后把状态改回合并中再验证
+	assembling.Wait()
+	if err := global.Db.Model(&models.UploadSession{}).Where("id = ?", session.ID).
+		Update("status", models.UploadStatusAssembling).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteChunk(session.ID, 100, bytes.NewReader(nil), ""); !errors.Is(err, ErrAssembling) {
+		t.Fatalf("合并中写入应返回 ErrAssembling，得到 %v", err)
+	}
+	if err := Abort(session.ID, 1); !errors.Is(err, ErrAssembling) {
+		t.Fatalf("合并中不能取消，得到 %v", err)
+	}
+}
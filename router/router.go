@@ -4,6 +4,7 @@ package router
 
 import (
 	"travel-from-sysu-backend/controllers"
+	"travel-from-sysu-backend/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,6 +37,7 @@ func SetupRouter() *gin.Engine {
 		note.POST("/collect", controllers.Collect)
 		note.POST("/uncollect", controllers.Uncollect)
 		note.GET("/getNoteById", controllers.GetNoteByID)
+		note.POST("/impression", controllers.RecordNoteImpressions) // 信息流卡片浏览上报
 		note.GET("/getNotesByCreatorId", controllers.GetNotesByCreatorID)
 		note.GET("/getUserFoNotes", controllers.GetFoNotes)
 		note.GET("/getNotesByUpdateTime", controllers.GetNotesByUpdateTime)
@@ -44,6 +46,7 @@ func SetupRouter() *gin.Engine {
 		note.GET("/getNotesByLikes", controllers.GetNotesByLikes)
 		note.GET("/getNotesByCollects", controllers.GetNotesByCollects)
 		note.GET("/getHotRecommendations", controllers.GetHotRecommendations)
+		note.GET("/hotScorePreview", controllers.PreviewHotScores) // 热度试算
 		note.GET("/getNotesByTag", controllers.GetNotesByTag)
 		note.GET("/getNotesByKeywords", controllers.GetNoteByKeywords)
 		note.GET("getIfUserFollow", controllers.GetIfUserFollow)
@@ -58,6 +61,12 @@ func SetupRouter() *gin.Engine {
 		user.GET("/getFollowees", controllers.GetFolloweesWithPagination)
 		user.GET("/getFollowers", controllers.GetFollowersWithPagination)
 		user.GET("/getUserNoteCounts", controllers.GetNoteCountsByID)
+		user.GET("/mentionSuggest", controllers.SuggestMentionUsers) // @ 输入联想
+		user.GET("/search", controllers.SearchUsers)                 // 用户搜索
+		user.GET("/suggestions", controllers.GetUserSuggestions)     // 可能认识的人
+		user.POST("/block", controllers.BlockUser)                   // 拉黑
+		user.POST("/unblock", controllers.UnblockUser)               // 取消拉黑
+		user.GET("/blocked", controllers.GetBlockedUsers)            // 拉黑列表
 	}
 	comment := r.Group("/api/comment")
 	{
@@ -82,6 +91,84 @@ func SetupRouter() *gin.Engine {
 		notification.GET("/read_comments", controllers.GetReadCommentNotifications)                  // 获取已读评论消息
 		notification.GET("/read_likes-and-collects", controllers.GetReadLikeAndCollectNotifications) // 获取已读点赞+收藏消息
 		notification.GET("/read_follows", controllers.GetReadFollowNotifications)                    // 获取已读关注消息
+
+		// 统一收件箱与状态管理
+		notification.GET("/inbox", controllers.GetNotificationInbox)                      // 统一收件箱（支持类型过滤）
+		notification.GET("/unread_breakdown", controllers.GetUnreadNotificationBreakdown) // 按分类统计未读数
+		notification.POST("/mark_read", controllers.MarkNotificationsRead)                // 标记一条或多条为已读
+		notification.POST("/mark_all_read", controllers.MarkAllNotificationsRead)         // 全部（或某分类）标记为已读
+		notification.POST("/delete", controllers.DeleteNotifications)                     // 删除通知
+
+		// 通知偏好与免打扰
+		notification.GET("/preferences", controllers.GetNotificationPreferences)     // 获取通知偏好
+		notification.POST("/preferences", controllers.UpdateNotificationPreferences) // 更新通知偏好
+		notification.GET("/digest/unsubscribe", controllers.UnsubscribeDigest)       // 摘要邮件一键退订（浏览器）
+		notification.POST("/digest/unsubscribe", controllers.UnsubscribeDigest)      // 摘要邮件一键退订（RFC 8058）
+
+		// 移动端推送设备
+		notification.POST("/device/register", controllers.RegisterDevice)     // 登记推送设备
+		notification.POST("/device/unregister", controllers.UnregisterDevice) // 注销推送设备
+	}
+	group := r.Group("/api/group")
+	{
+		// 旅伴群组相关路由
+		group.POST("/create", controllers.CreateTripGroup)
+		group.POST("/join", controllers.JoinTripGroup)
+		group.POST("/leave", controllers.LeaveTripGroup)
+		group.GET("/getGroupInfo", controllers.GetTripGroupInfo)
+		group.GET("/getMembers", controllers.GetTripGroupMembers)
+
+		// 群聊相关路由
+		group.POST("/sendMessage", controllers.SendGroupMessage)
+		group.GET("/getMessages", controllers.GetGroupMessages)
+		group.POST("/pinMessage", controllers.PinGroupMessage)
+		group.POST("/unpinMessage", controllers.UnpinGroupMessage)
+		group.GET("/getPinnedMessages", controllers.GetPinnedGroupMessages)
+	}
+	feedGroup := r.Group("/api/feed")
+	{
+		feedGroup.GET("/recommend", controllers.GetRecommendFeed)             // 个性化推荐流
+		feedGroup.POST("/notInterested", controllers.MarkNotInterested)       // 不感兴趣（笔记/作者/标签）
+		feedGroup.POST("/undoNotInterested", controllers.UndoNotInterested)   // 撤销不感兴趣
+		feedGroup.POST("/followTag", controllers.FollowTag)                   // 关注标签
+		feedGroup.POST("/unfollowTag", controllers.UnfollowTag)               // 取消关注标签
+		feedGroup.GET("/followedTags", controllers.GetFollowedTags)           // 关注的标签
+		feedGroup.POST("/click", controllers.RecordFeedClick)                 // 点击上报
+		feedGroup.GET("/experimentStats", controllers.GetFeedExperimentStats) // 各实验分组的点击率和点赞率
+	}
+	searchGroup := r.Group("/api/search")
+	{
+		searchGroup.GET("/history", controllers.GetSearchHistory)             // 最近搜索
+		searchGroup.POST("/history/delete", controllers.DeleteSearchHistory)  // 删除一条或全部搜索历史
+		searchGroup.POST("/history/setting", controllers.UpdateSearchSetting) // 开关搜索历史
+		searchGroup.GET("/suggest", controllers.SuggestSearchQueries)         // 输入联想
+		searchGroup.GET("/trending", controllers.GetTrendingSearches)         // 热搜榜
+	}
+	creator := r.Group("/api/creator")
+	{
+		creator.GET("/overview", controllers.GetCreatorOverview)   // 账号数据总览
+		creator.GET("/noteStats", controllers.GetCreatorNoteStats) // 单篇笔记数据
+		creator.GET("/topNotes", controllers.GetCreatorTopNotes)   // 笔记排行
+	}
+	upload := r.Group("/api/upload")
+	{
+		upload.POST("/video", controllers.CreateVideoUpload)       // 创建视频断点续传会话
+		upload.HEAD("/video/:id", controllers.HeadVideoUpload)     // 查询已接收的字节数
+		upload.GET("/video/:id", controllers.GetVideoUpload)       // 查询上传进度
+		upload.PATCH("/video/:id", controllers.PatchVideoUpload)   // 追加分片
+		upload.DELETE("/video/:id", controllers.DeleteVideoUpload) // 取消上传
+		upload.POST("/presign", controllers.PresignUpload)         // 签发直传对象存储的凭证
+		upload.POST("/complete", controllers.CompleteUpload)       // 直传完成回调
+	}
+	media := r.Group("/api/media")
+	{
+		media.GET("/gcReport", controllers.GetMediaGCReport) // 未引用文件回收的试运行报告
+	}
+	storage.Mount(r) // 本地存储时提供文件访问和签名直传
+	realtime := r.Group("/api/realtime")
+	{
+		realtime.GET("/ws", controllers.ServeRealtimeWebSocket) // WebSocket 推送
+		realtime.GET("/sse", controllers.ServeRealtimeSSE)      // SSE 推送（WebSocket 降级）
 	}
 	return r
 }
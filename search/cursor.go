@@ -0,0 +1,55 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+)
+
+// ErrInvalidCursor 游标无法解析，或不属于当前查询
+var ErrInvalidCursor = errors.New("无效的游标")
+
+// cursorPayload 游标内容：上一页最后一条的 (排序键, ID)，以及请求指纹
+type cursorPayload struct {
+	Query uint32  `json:"q"`
+	Key   float64 `json:"k"`
+	ID    uint    `json:"i"`
+}
+
+// fingerprint 请求指纹，换了查询条件的旧游标直接拒绝
+func fingerprint(query string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(query))
+	return h.Sum32()
+}
+
+// EncodeCursor 生成不透明游标
+func EncodeCursor(query string, hit Hit) string {
+	raw, _ := json.Marshal(cursorPayload{Query: fingerprint(query), Key: hit.Key, ID: hit.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor 解析游标
+func DecodeCursor(query string, cursor string) (key float64, id uint, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Query != fingerprint(query) {
+		return 0, 0, ErrInvalidCursor
+	}
+	return payload.Key, payload.ID, nil
+}
+
+// After 返回排在游标之后的命中（hits 需已按排序键降序、ID 降序排列）
+// 按 (排序键, ID) 比较而不是按下标跳过，翻页期间索引有增删也不会重复或漏掉排在前面的结果
+func After(hits []Hit, key float64, id uint) []Hit {
+	for i, hit := range hits {
+		if hit.Key < key || (hit.Key == key && hit.ID < id) {
+			return hits[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package search
+
+//笔记全文检索：启动时从数据库全量建立内存倒排索引，发布/更新/删除笔记时增量维护，热度分定时刷新
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotReady 索引尚未初始化
+var ErrNotReady = errors.New("搜索服务未就绪")
+
+// loadBatchSize 建索引时每批读取的笔记数
+const loadBatchSize = 500
+
+// Options 检索初始化参数
+type Options struct {
+	ScoreWeight    float64 // 热度分在最终得分中的权重，0 表示只按相关度排序
+	RefreshMinutes int     // 热度分刷新间隔（分钟）
+	SnippetLength  int     // 摘要长度（字符）
+}
+
+// indexedColumns 建索引需要读取的列
+var indexedColumns = []string{
+	"note_id", "note_title", "note_content", "note_tag_list", "buddy_description", "note_type",
+	"is_finding_buddy", "note_creator_id", "note_update_time", "latitude", "longitude",
+	"score", "like_counts", "collect_counts",
+}
+
+// Engine 笔记检索引擎
+type Engine struct {
+	index         *Index
+	snippetLength int
+}
+
+// DefaultEngine 全局检索引擎，未初始化时为 nil，增量维护函数均可安全调用
+var DefaultEngine *Engine
+
+// Init 从数据库建立索引并启动热度分刷新，需在数据库初始化之后调用
+func Init(opts Options) {
+	seg := NewSegmenter()
+
+	// 标签名加入词典，保证标签能被整体切出
+	var tagNames []string
+	if err := global.Db.Model(&models.Tag{}).Pluck("t_name", &tagNames).Error; err != nil {
+		log.Printf("加载标签词典失败: %v", err)
+	}
+	seg.AddWords(tagNames...)
+
+	engine := &Engine{
+		index:         NewIndex(seg, opts.ScoreWeight),
+		snippetLength: opts.SnippetLength,
+	}
+	if engine.snippetLength <= 0 {
+		engine.snippetLength = 80
+	}
+
+	var batch []models.Note
+	err := global.Db.Model(&models.Note{}).
+		Select(indexedColumns).
+		FindInBatches(&batch, loadBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, note := range batch {
+				engine.index.Upsert(documentOf(note))
+			}
+			return nil
+		}).Error
+	if err != nil {
+		log.Printf("建立笔记索引失败: %v", err)
+	}
+	log.Printf("笔记索引已建立，共 %d 篇", engine.index.Len())
+
+	DefaultEngine = engine
+
+	interval := time.Duration(opts.RefreshMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	go engine.refreshScores(interval)
+}
+
+// refreshScores 定时从数据库同步热度分和点赞/收藏数
+func (e *Engine) refreshScores(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		var rows []models.Note
+		if err := global.Db.Model(&models.Note{}).
+			Select("note_id", "score", "like_counts", "collect_counts").Find(&rows).Error; err != nil {
+			log.Printf("刷新索引热度分失败: %v", err)
+			continue
+		}
+		stats := make(map[uint]Stats, len(rows))
+		for _, row := range rows {
+			stats[row.NoteID] = statsOf(row)
+		}
+		e.index.UpdateStats(stats)
+	}
+}
+
+// statsOf 笔记的热度分和互动计数
+func statsOf(note models.Note) Stats {
+	return Stats{
+		Score:         note.Score,
+		LikeCounts:    note.LikeCounts,
+		CollectCounts: note.CollectCounts,
+	}
+}
+
+// documentOf 笔记转为索引文档
+func documentOf(note models.Note) Document {
+	var tags []string
+	if note.NoteTagList != "" {
+		tags = strings.Split(note.NoteTagList, ",")
+	}
+	var location *GeoPoint
+	if note.Latitude != nil && note.Longitude != nil {
+		location = &GeoPoint{Lat: *note.Latitude, Lng: *note.Longitude}
+	}
+	return Document{
+		ID:               note.NoteID,
+		Title:            note.NoteTitle,
+		Content:          note.NoteContent,
+		Tags:             tags,
+		BuddyDescription: note.BuddyDescription,
+		Meta: Meta{
+			NoteType:       note.NoteType,
+			IsFindingBuddy: note.IsFindingBuddy == 1,
+			CreatorID:      note.NoteCreatorID,
+			UpdateTime:     note.NoteUpdateTime,
+			Location:       location,
+			Stats:          statsOf(note),
+		},
+	}
+}
+
+// IndexNote 新增或更新笔记的索引，发布和编辑笔记后调用
+func IndexNote(note models.Note) {
+	if DefaultEngine == nil {
+		return
+	}
+	// 新标签加入词典，之后的文档才能整体切出该标签
+	if note.NoteTagList != "" {
+		DefaultEngine.index.Segmenter().AddWords(strings.Split(note.NoteTagList, ",")...)
+	}
+	DefaultEngine.index.Upsert(documentOf(note))
+}
+
+// RemoveNote 从索引中删除笔记
+func RemoveNote(noteID uint) {
+	if DefaultEngine == nil {
+		return
+	}
+	DefaultEngine.index.Remove(noteID)
+}
+
+// Result 一页搜索结果
+type Result struct {
+	Hits       []Hit
+	Terms      []string // 查询分词结果，用于高亮
+	Total      int      // 过滤后的命中总数
+	Facets     Facets   // 过滤后命中集合上的分面统计
+	NextCursor string   // 下一页游标，没有更多时为空
+}
+
+// Query 检索笔记，cursor 为上一页返回的游标，首页传空
+func Query(req Request, cursor string, limit int) (Result, error) {
+	if DefaultEngine == nil {
+		return Result{}, ErrNotReady
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	key := req.fingerprintKey()
+
+	hits, facets := DefaultEngine.index.Search(req)
+	result := Result{
+		Terms:  DefaultEngine.index.QueryTerms(req.Query),
+		Total:  len(hits),
+		Facets: facets,
+	}
+	if cursor != "" {
+		lastKey, lastID, err := DecodeCursor(key, cursor)
+		if err != nil {
+			return Result{}, err
+		}
+		hits = After(hits, lastKey, lastID)
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		result.NextCursor = EncodeCursor(key, hits[len(hits)-1])
+	}
+	result.Hits = hits
+	return result, nil
+}
+
+// HighlightTitle 高亮标题中的查询词
+func (r Result) HighlightTitle(title string) string {
+	return Highlight(title, r.Terms)
+}
+
+// Snippet 截取正文中命中查询词的片段
+func (r Result) Snippet(content string) string {
+	length := 80
+	if DefaultEngine != nil {
+		length = DefaultEngine.snippetLength
+	}
+	return Snippet(content, r.Terms, length)
+}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"sort"
+	"time"
+)
+
+// topTagFacets 标签分面返回的标签数
+const topTagFacets = 10
+
+// FacetCount 分面中的一项
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets 命中集合上的分面统计
+type Facets struct {
+	Types  []FacetCount `json:"types"`  // 按笔记类型
+	Tags   []FacetCount `json:"tags"`   // 出现最多的标签
+	Months []FacetCount `json:"months"` // 按更新月份（YYYY-MM），新的在前
+}
+
+// facetCounter 分面计数器
+type facetCounter struct {
+	types  map[string]int
+	tags   map[string]int
+	months map[string]int
+}
+
+func newFacetCounter() *facetCounter {
+	return &facetCounter{
+		types:  make(map[string]int),
+		tags:   make(map[string]int),
+		months: make(map[string]int),
+	}
+}
+
+func (c *facetCounter) add(entry *docEntry) {
+	c.types[entry.meta.NoteType]++
+	for _, tag := range entry.tags {
+		c.tags[tag]++
+	}
+	if entry.meta.UpdateTime > 0 {
+		c.months[time.Unix(entry.meta.UpdateTime, 0).Format("2006-01")]++
+	}
+}
+
+// sortedCounts 按数量降序（同数按值升序）排列
+func sortedCounts(counts map[string]int) []FacetCount {
+	result := make([]FacetCount, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
+
+func (c *facetCounter) result() Facets {
+	tags := sortedCounts(c.tags)
+	if len(tags) > topTagFacets {
+		tags = tags[:topTagFacets]
+	}
+	months := sortedCounts(c.months)
+	sort.Slice(months, func(i, j int) bool { return months[i].Value > months[j].Value })
+	return Facets{
+		Types:  sortedCounts(c.types),
+		Tags:   tags,
+		Months: months,
+	}
+}
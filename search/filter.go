@@ -0,0 +1,141 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 排序方式
+const (
+	SortRelevance = "relevance" // 相关度（融合热度分），查询为空时等同于 hot
+	SortNewest    = "newest"    // 最新更新
+	SortLikes     = "likes"     // 点赞最多
+	SortCollects  = "collects"  // 收藏最多
+	SortHot       = "hot"       // 热度分最高
+)
+
+// SortModes 支持的排序方式
+var SortModes = []string{SortRelevance, SortNewest, SortLikes, SortCollects, SortHot}
+
+// earthRadiusKm 地球平均半径（千米）
+const earthRadiusKm = 6371.0
+
+// GeoPoint 经纬度坐标（度）
+type GeoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+// DistanceKm 两点间的球面距离（千米）
+func DistanceKm(a, b GeoPoint) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(b.Lat - a.Lat)
+	dLng := toRad(b.Lng - a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.Lat))*math.Cos(toRad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Filter 检索过滤条件，零值表示不限
+type Filter struct {
+	NoteType  string
+	BuddyOnly bool     // 只看找旅伴帖子
+	Tags      []string // 需同时包含的标签
+	AuthorID  uint
+	From      int64     // 更新时间下限（含），Unix 时间戳
+	To        int64     // 更新时间上限（含），Unix 时间戳
+	Near      *GeoPoint // 位置中心，与 RadiusKm 一起使用
+	RadiusKm  float64
+	MinLikes  int
+}
+
+// Request 一次检索请求
+type Request struct {
+	Query  string
+	Filter Filter
+	Sort   string
+}
+
+// match 文档是否满足过滤条件
+func (f Filter) match(entry *docEntry) bool {
+	meta := entry.meta
+	if f.NoteType != "" && meta.NoteType != f.NoteType {
+		return false
+	}
+	if f.BuddyOnly && !meta.IsFindingBuddy {
+		return false
+	}
+	if f.AuthorID != 0 && meta.CreatorID != f.AuthorID {
+		return false
+	}
+	if f.From != 0 && meta.UpdateTime < f.From {
+		return false
+	}
+	if f.To != 0 && meta.UpdateTime > f.To {
+		return false
+	}
+	if f.MinLikes > 0 && meta.LikeCounts < f.MinLikes {
+		return false
+	}
+	if f.Near != nil {
+		if meta.Location == nil || DistanceKm(*f.Near, *meta.Location) > f.RadiusKm {
+			return false
+		}
+	}
+	for _, want := range normalizeTags(f.Tags) {
+		found := false
+		for _, tag := range entry.tags {
+			if tag == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortKey 计算命中在指定排序方式下的排序键
+func sortKey(mode string, hit Hit, entry *docEntry, emptyQuery bool) float64 {
+	switch mode {
+	case SortNewest:
+		return float64(entry.meta.UpdateTime)
+	case SortLikes:
+		return float64(entry.meta.LikeCounts)
+	case SortCollects:
+		return float64(entry.meta.CollectCounts)
+	case SortHot:
+		return entry.meta.Score
+	default:
+		if emptyQuery {
+			return entry.meta.Score
+		}
+		return hit.Relevance
+	}
+}
+
+// fingerprintKey 请求的规范化表示，用于游标校验：查询词、过滤条件或排序方式变化后旧游标失效
+func (r Request) fingerprintKey() string {
+	tags := normalizeTags(r.Filter.Tags)
+	sort.Strings(tags)
+	near := ""
+	if r.Filter.Near != nil {
+		near = fmt.Sprintf("%g,%g,%g", r.Filter.Near.Lat, r.Filter.Near.Lng, r.Filter.RadiusKm)
+	}
+	return strings.Join([]string{
+		strings.TrimSpace(r.Query),
+		r.Sort,
+		r.Filter.NoteType,
+		fmt.Sprint(r.Filter.BuddyOnly),
+		strings.Join(tags, ","),
+		fmt.Sprint(r.Filter.AuthorID),
+		fmt.Sprint(r.Filter.From),
+		fmt.Sprint(r.Filter.To),
+		near,
+		fmt.Sprint(r.Filter.MinLikes),
+	}, "\x00")
+}
@@ -0,0 +1,411 @@
+package search
+
+//搜索历史、输入联想与热搜：搜索行为按用户去重后写入明细表和累计表，明细定期清理
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxQueryLength 记录的查询最大长度（字符），与表字段长度一致
+const maxQueryLength = 64
+
+// maxTrendingQueryLength 超过该长度的查询不进入热搜和联想
+const maxTrendingQueryLength = 30
+
+// HistoryOptions 搜索历史与热搜参数
+type HistoryOptions struct {
+	HistoryLimit     int      // 每个用户保留的搜索历史条数
+	DedupeMinutes    int      // 同一用户重复搜索同一查询在该时间内只计一次
+	RetentionDays    int      // 搜索明细保留天数，需不少于最大热搜窗口的两倍
+	TrendingMinUsers int      // 进入热搜所需的最少独立用户数
+	SuggestMinCount  int      // 进入输入联想所需的最少累计搜索次数
+	SuggestMinUsers  int      // 进入输入联想所需的最少独立用户数，避免个人搜索的隐私内容出现在别人的联想里
+	Blocklist        []string // 屏蔽词，包含这些词的查询不进入热搜和联想
+}
+
+var historyOpts = HistoryOptions{
+	HistoryLimit:     50,
+	DedupeMinutes:    60,
+	RetentionDays:    15,
+	TrendingMinUsers: 3,
+	SuggestMinCount:  3,
+	SuggestMinUsers:  3,
+}
+
+// InitHistory 设置参数并启动搜索明细清理，需在数据库初始化之后调用
+func InitHistory(opts HistoryOptions) {
+	if opts.HistoryLimit > 0 {
+		historyOpts.HistoryLimit = opts.HistoryLimit
+	}
+	if opts.DedupeMinutes > 0 {
+		historyOpts.DedupeMinutes = opts.DedupeMinutes
+	}
+	if opts.RetentionDays > 0 {
+		historyOpts.RetentionDays = opts.RetentionDays
+	}
+	if opts.TrendingMinUsers > 0 {
+		historyOpts.TrendingMinUsers = opts.TrendingMinUsers
+	}
+	if opts.SuggestMinCount > 0 {
+		historyOpts.SuggestMinCount = opts.SuggestMinCount
+	}
+	if opts.SuggestMinUsers > 0 {
+		historyOpts.SuggestMinUsers = opts.SuggestMinUsers
+	}
+	for _, word := range opts.Blocklist {
+		if word = NormalizeQuery(word); word != "" {
+			historyOpts.Blocklist = append(historyOpts.Blocklist, word)
+		}
+	}
+	go cleanupQueryLogs()
+}
+
+// cleanupQueryLogs 每小时删除过期的搜索明细，分批删除避免长时间锁表
+func cleanupQueryLogs() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		cutoff := time.Now().AddDate(0, 0, -historyOpts.RetentionDays)
+		for {
+			result := global.Db.Where("created_at < ?", cutoff).Limit(1000).Delete(&models.SearchQueryLog{})
+			if result.Error != nil {
+				log.Printf("清理搜索明细失败: %v", result.Error)
+				break
+			}
+			if result.RowsAffected < 1000 {
+				break
+			}
+		}
+	}
+}
+
+// NormalizeQuery 规范化查询：去首尾空白、合并连续空白、转小写并截断
+func NormalizeQuery(query string) string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	if runes := []rune(query); len(runes) > maxQueryLength {
+		query = strings.TrimSpace(string(runes[:maxQueryLength]))
+	}
+	return query
+}
+
+// IsNoise 判断规范化后的查询是否为噪声：过长、没有文字、单字符重复、链接或包含屏蔽词
+func IsNoise(query string) bool {
+	runes := []rune(query)
+	if len(runes) == 0 || len(runes) > maxTrendingQueryLength {
+		return true
+	}
+	hasText := false
+	repeated := len(runes) >= 3
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			hasText = true
+		}
+		if i > 0 && r != runes[0] {
+			repeated = false
+		}
+	}
+	if !hasText || repeated {
+		return true
+	}
+	if strings.Contains(query, "http") || strings.Contains(query, "www.") {
+		return true
+	}
+	for _, word := range historyOpts.Blocklist {
+		if strings.Contains(query, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryEnabled 用户是否开启了搜索历史
+func HistoryEnabled(userID uint) bool {
+	var setting models.SearchSetting
+	if err := global.Db.Where("user_id = ?", userID).Limit(1).Find(&setting).Error; err != nil {
+		return true
+	}
+	return !setting.HistoryDisabled
+}
+
+// RecordQuery 记录一次搜索：更新用户搜索历史，并在去重后计入热搜明细和累计次数
+// 关闭了搜索历史的用户不做任何记录
+func RecordQuery(userID uint, query string) error {
+	query = NormalizeQuery(query)
+	if userID == 0 || query == "" || !HistoryEnabled(userID) {
+		return nil
+	}
+
+	now := time.Now()
+	return global.Db.Transaction(func(tx *gorm.DB) error {
+		history := models.SearchHistory{UserID: userID, Query: query, SearchedAt: now}
+		if err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"searched_at"}),
+		}).Create(&history).Error; err != nil {
+			return err
+		}
+
+		// 只保留最近的 HistoryLimit 条
+		var staleIDs []uint
+		if err := tx.Model(&models.SearchHistory{}).
+			Where("user_id = ?", userID).
+			Order("searched_at DESC, id DESC").
+			Offset(historyOpts.HistoryLimit).
+			Limit(100).
+			Pluck("id", &staleIDs).Error; err != nil {
+			return err
+		}
+		if len(staleIDs) > 0 {
+			if err := tx.Delete(&models.SearchHistory{}, staleIDs).Error; err != nil {
+				return err
+			}
+		}
+
+		if IsNoise(query) {
+			return nil
+		}
+
+		// 去重窗口内已经计过的不再重复计数，防止单个用户刷热搜
+		var recent int64
+		if err := tx.Model(&models.SearchQueryLog{}).
+			Where("user_id = ? AND query = ? AND created_at > ?", userID, query,
+				now.Add(-time.Duration(historyOpts.DedupeMinutes)*time.Minute)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return nil
+		}
+
+		// 明细中还没有该用户搜过这个查询的记录时，独立用户数加一
+		var searched int64
+		if err := tx.Model(&models.SearchQueryLog{}).
+			Where("user_id = ? AND query = ?", userID, query).
+			Count(&searched).Error; err != nil {
+			return err
+		}
+		var newUser int64
+		if searched == 0 {
+			newUser = 1
+		}
+
+		if err := tx.Create(&models.SearchQueryLog{UserID: userID, Query: query, CreatedAt: now}).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"count":            gorm.Expr("count + 1"),
+				"users":            gorm.Expr("users + ?", newUser),
+				"last_searched_at": now,
+			}),
+		}).Create(&models.SearchQueryStat{Query: query, Count: 1, Users: 1, LastSearchedAt: now}).Error
+	})
+}
+
+// RecentSearches 用户最近的搜索，新的在前
+func RecentSearches(userID uint, limit int) ([]models.SearchHistory, error) {
+	var histories []models.SearchHistory
+	err := global.Db.Where("user_id = ?", userID).
+		Order("searched_at DESC, id DESC").
+		Limit(limit).
+		Find(&histories).Error
+	return histories, err
+}
+
+// DeleteHistory 删除用户的一条搜索历史，query 为空时清空全部
+// 对应的热搜明细一并删除；累计次数是匿名汇总，不做扣减
+func DeleteHistory(userID uint, query string) error {
+	return global.Db.Transaction(func(tx *gorm.DB) error {
+		historyQuery := tx.Where("user_id = ?", userID)
+		logQuery := tx.Where("user_id = ?", userID)
+		if query != "" {
+			query = NormalizeQuery(query)
+			historyQuery = historyQuery.Where("query = ?", query)
+			logQuery = logQuery.Where("query = ?", query)
+		}
+		if err := historyQuery.Delete(&models.SearchHistory{}).Error; err != nil {
+			return err
+		}
+		return logQuery.Delete(&models.SearchQueryLog{}).Error
+	})
+}
+
+// SetHistoryEnabled 开启或关闭搜索历史，关闭时清空已有记录
+func SetHistoryEnabled(userID uint, enabled bool) error {
+	setting := models.SearchSetting{UserID: userID, HistoryDisabled: !enabled}
+	if err := global.Db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&setting).Error; err != nil {
+		return err
+	}
+	if enabled {
+		return nil
+	}
+	return DeleteHistory(userID, "")
+}
+
+// 联想词来源
+const (
+	SuggestFromHistory = "history" // 自己的搜索历史
+	SuggestFromQuery   = "query"   // 大家常搜的查询
+	SuggestFromTag     = "tag"     // 标签名
+)
+
+// Suggestion 一条输入联想
+type Suggestion struct {
+	Text   string `json:"text"`
+	Source string `json:"source"`
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Suggest 按前缀给出联想词：先是自己的搜索历史，再是热门查询，最后是标签名。
+// 热门查询需要达到最少独立用户数，单个用户反复搜索的内容不会出现在别人的联想里
+func Suggest(prefix string, userID uint, limit int) ([]Suggestion, error) {
+	prefix = NormalizeQuery(prefix)
+	if prefix == "" {
+		return nil, nil
+	}
+	pattern := escapeLike(prefix) + "%"
+
+	var histories []string
+	if userID != 0 {
+		if err := global.Db.Model(&models.SearchHistory{}).
+			Where("user_id = ? AND query LIKE ?", userID, pattern).
+			Order("searched_at DESC").
+			Limit(3).
+			Pluck("query", &histories).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var queries []string
+	if err := global.Db.Model(&models.SearchQueryStat{}).
+		Where("query LIKE ? AND count >= ? AND users >= ?", pattern, historyOpts.SuggestMinCount, historyOpts.SuggestMinUsers).
+		Order("count DESC, last_searched_at DESC").
+		Limit(limit*2).
+		Pluck("query", &queries).Error; err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	if err := global.Db.Model(&models.Tag{}).
+		Where("t_name LIKE ?", pattern).
+		Order("use_count DESC").
+		Limit(limit).
+		Pluck("t_name", &tags).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	suggestions := make([]Suggestion, 0, limit)
+	add := func(text, source string) {
+		key := NormalizeQuery(text)
+		if len(suggestions) >= limit || key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		suggestions = append(suggestions, Suggestion{Text: text, Source: source})
+	}
+	for _, text := range histories {
+		add(text, SuggestFromHistory)
+	}
+	for _, text := range queries {
+		if !IsNoise(text) {
+			add(text, SuggestFromQuery)
+		}
+	}
+	for _, text := range tags {
+		add(text, SuggestFromTag)
+	}
+	return suggestions, nil
+}
+
+// TrendingQuery 一条热搜
+type TrendingQuery struct {
+	Query    string  `json:"query"`
+	Users    int64   `json:"users"`    // 当前窗口内搜索过的独立用户数
+	Previous int64   `json:"previous"` // 上一个等长窗口内的独立用户数
+	Score    float64 `json:"score"`    // 热度：用户数乘以增长系数
+	Rising   bool    `json:"rising"`   // 是否比上一窗口上升
+}
+
+// queryUsers 查询在时间段内的独立用户数
+type queryUsers struct {
+	Query string
+	Users int64
+}
+
+// Trending 统计最近 window 时长内的热搜：按独立用户数计数，独立用户过少的查询和噪声查询不参与，
+// 并与上一个等长窗口比较，上升快的查询排名靠前
+func Trending(window time.Duration, limit int) ([]TrendingQuery, error) {
+	now := time.Now()
+	start := now.Add(-window)
+
+	var current []queryUsers
+	if err := global.Db.Model(&models.SearchQueryLog{}).
+		Select("query, COUNT(DISTINCT user_id) AS users").
+		Where("created_at >= ?", start).
+		Group("query").
+		Having("COUNT(DISTINCT user_id) >= ?", historyOpts.TrendingMinUsers).
+		Order("users DESC").
+		Limit(limit * 3).
+		Scan(&current).Error; err != nil {
+		return nil, err
+	}
+	if len(current) == 0 {
+		return []TrendingQuery{}, nil
+	}
+
+	queries := make([]string, 0, len(current))
+	for _, row := range current {
+		queries = append(queries, row.Query)
+	}
+	var previous []queryUsers
+	if err := global.Db.Model(&models.SearchQueryLog{}).
+		Select("query, COUNT(DISTINCT user_id) AS users").
+		Where("query IN ? AND created_at >= ? AND created_at < ?", queries, start.Add(-window), start).
+		Group("query").
+		Scan(&previous).Error; err != nil {
+		return nil, err
+	}
+	previousUsers := make(map[string]int64, len(previous))
+	for _, row := range previous {
+		previousUsers[row.Query] = row.Users
+	}
+
+	trending := make([]TrendingQuery, 0, len(current))
+	for _, row := range current {
+		if IsNoise(row.Query) {
+			continue
+		}
+		prev := previousUsers[row.Query]
+		trending = append(trending, TrendingQuery{
+			Query:    row.Query,
+			Users:    row.Users,
+			Previous: prev,
+			Score:    float64(row.Users) * math.Log2(2+float64(row.Users)/float64(prev+1)),
+			Rising:   row.Users > prev,
+		})
+	}
+	sort.Slice(trending, func(i, j int) bool {
+		if trending[i].Score != trending[j].Score {
+			return trending[i].Score > trending[j].Score
+		}
+		return trending[i].Query < trending[j].Query
+	})
+	if len(trending) > limit {
+		trending = trending[:limit]
+	}
+	return trending, nil
+}
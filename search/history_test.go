@@ -0,0 +1,55 @@
+package search
+
+import (
+	"testing"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/testutil"
+)
+
+func TestSuggestRequiresDistinctUsers(t *testing.T) {
+	testutil.OpenDB(t, &models.SearchHistory{}, &models.SearchSetting{}, &models.SearchQueryLog{}, &models.SearchQueryStat{}, &models.Tag{})
+	previous := historyOpts
+	historyOpts.DedupeMinutes = 0 // 每次搜索都计数，模拟跨过去重窗口的多次搜索
+	t.Cleanup(func() { historyOpts = previous })
+
+	suggested := func(prefix string) bool {
+		suggestions, err := Suggest(prefix, 99, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range suggestions {
+			if s.Source == SuggestFromQuery {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 单个用户反复搜索的内容不进入别人的联想
+	for i := 0; i < 5; i++ {
+		if err := RecordQuery(1, "张三 13800000000"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if suggested("张三") {
+		t.Fatal("只有一个用户搜索过的查询不应出现在联想中")
+	}
+
+	for _, uid := range []uint{2, 3} {
+		if err := RecordQuery(uid, "珠海 长隆"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordQuery(2, "珠海 长隆"); err != nil {
+		t.Fatal(err)
+	}
+	if suggested("珠海") {
+		t.Fatal("独立用户数不足时不应出现在联想中")
+	}
+	if err := RecordQuery(4, "珠海 长隆"); err != nil {
+		t.Fatal(err)
+	}
+	if !suggested("珠海") {
+		t.Fatal("达到独立用户数后应出现在联想中")
+	}
+}
@@ -0,0 +1,263 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 各字段的权重，标题命中比正文命中更相关
+const (
+	weightTitle   = 3.0
+	weightTags    = 2.0
+	weightBuddy   = 1.5
+	weightContent = 1.0
+)
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Document 待索引的笔记
+type Document struct {
+	ID               uint
+	Title            string
+	Content          string
+	Tags             []string
+	BuddyDescription string
+	Meta
+}
+
+// Meta 用于过滤、排序和分面统计的笔记属性
+type Meta struct {
+	NoteType       string
+	IsFindingBuddy bool
+	CreatorID      uint
+	UpdateTime     int64     // Unix 时间戳
+	Location       *GeoPoint // 未标注位置时为 nil
+	Stats
+}
+
+// Stats 会随互动变化的计数，由定时任务批量刷新
+type Stats struct {
+	Score         float64 // 笔记热度分（0~100）
+	LikeCounts    int
+	CollectCounts uint
+}
+
+// docEntry 索引中的文档信息
+type docEntry struct {
+	length float64            // 加权后的文档长度
+	terms  map[string]float64 // 词 -> 加权词频，删除文档时用来清理倒排表
+	tags   []string           // 规范化后的标签，用于过滤和分面
+	meta   Meta
+}
+
+// Hit 一条搜索结果
+type Hit struct {
+	ID        uint
+	Relevance float64 // BM25 得分与热度分融合后的相关度
+	Key       float64 // 当前排序方式下的排序键，结果按 (Key, ID) 降序排列
+}
+
+// Index 内存倒排索引，并发安全
+type Index struct {
+	mu          sync.RWMutex
+	seg         *Segmenter
+	postings    map[string]map[uint]float64 // 词 -> 文档 ID -> 加权词频
+	docs        map[uint]*docEntry
+	totalLength float64
+	scoreWeight float64 // 热度分在最终得分中的权重
+}
+
+// NewIndex 创建空索引
+func NewIndex(seg *Segmenter, scoreWeight float64) *Index {
+	return &Index{
+		seg:         seg,
+		postings:    make(map[string]map[uint]float64),
+		docs:        make(map[uint]*docEntry),
+		scoreWeight: scoreWeight,
+	}
+}
+
+// Segmenter 索引使用的分词器
+func (idx *Index) Segmenter() *Segmenter {
+	return idx.seg
+}
+
+// analyze 对文档各字段分词并按字段权重累计词频
+func (idx *Index) analyze(doc Document) (map[string]float64, float64) {
+	terms := make(map[string]float64)
+	var length float64
+	add := func(text string, weight float64) {
+		for _, token := range idx.seg.TokenizeForIndex(text) {
+			terms[token.Text] += weight
+			length += weight
+		}
+	}
+	add(doc.Title, weightTitle)
+	add(doc.Content, weightContent)
+	add(doc.BuddyDescription, weightBuddy)
+	for _, tag := range normalizeTags(doc.Tags) {
+		// 标签整体作为一个词，同时分词以支持部分匹配
+		terms[tag] += weightTags
+		add(tag, weightTags)
+	}
+	return terms, length
+}
+
+// normalizeTags 去空白、转小写并去重
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// Upsert 新增或更新文档
+func (idx *Index) Upsert(doc Document) {
+	terms, length := idx.analyze(doc)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(doc.ID)
+	for term, tf := range terms {
+		posting := idx.postings[term]
+		if posting == nil {
+			posting = make(map[uint]float64)
+			idx.postings[term] = posting
+		}
+		posting[doc.ID] = tf
+	}
+	idx.docs[doc.ID] = &docEntry{length: length, terms: terms, tags: normalizeTags(doc.Tags), meta: doc.Meta}
+	idx.totalLength += length
+}
+
+// Remove 删除文档
+func (idx *Index) Remove(id uint) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+func (idx *Index) removeLocked(id uint) {
+	entry, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for term := range entry.terms {
+		if posting := idx.postings[term]; posting != nil {
+			delete(posting, id)
+			if len(posting) == 0 {
+				delete(idx.postings, term)
+			}
+		}
+	}
+	idx.totalLength -= entry.length
+	delete(idx.docs, id)
+}
+
+// UpdateStats 批量更新文档的热度分和互动计数，不存在的文档忽略
+func (idx *Index) UpdateStats(stats map[uint]Stats) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for id, st := range stats {
+		if entry, ok := idx.docs[id]; ok {
+			entry.meta.Stats = st
+		}
+	}
+}
+
+// Len 索引中的文档数
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// QueryTerms 查询分词后去重的词
+func (idx *Index) QueryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range idx.seg.Tokenize(query) {
+		if !seen[token.Text] {
+			seen[token.Text] = true
+			terms = append(terms, token.Text)
+		}
+	}
+	return terms
+}
+
+// Search 按 BM25 计算相关度并融合热度分，经过滤后按排序方式降序（同值按 ID 降序）返回全部命中，
+// 同时统计命中集合上的分面。查询为空时不计算相关度，过滤条件下的全部笔记都算命中
+func (idx *Index) Search(req Request) ([]Hit, Facets) {
+	terms := idx.QueryTerms(req.Query)
+	if strings.TrimSpace(req.Query) != "" && len(terms) == 0 {
+		return nil, Facets{}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := float64(len(idx.docs))
+	if n == 0 {
+		return nil, Facets{}
+	}
+
+	var relevance map[uint]float64
+	if len(terms) > 0 {
+		avgLength := idx.totalLength / n
+		relevance = make(map[uint]float64)
+		for _, term := range terms {
+			posting := idx.postings[term]
+			if len(posting) == 0 {
+				continue
+			}
+			df := float64(len(posting))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for id, tf := range posting {
+				norm := 1 - bm25B + bm25B*idx.docs[id].length/avgLength
+				relevance[id] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			}
+		}
+	} else {
+		relevance = make(map[uint]float64, len(idx.docs))
+		for id := range idx.docs {
+			relevance[id] = 0
+		}
+	}
+
+	facets := newFacetCounter()
+	hits := make([]Hit, 0, len(relevance))
+	for id, rel := range relevance {
+		entry := idx.docs[id]
+		if !req.Filter.match(entry) {
+			continue
+		}
+		facets.add(entry)
+		score := entry.meta.Score
+		if score < 0 {
+			score = 0
+		}
+		hit := Hit{ID: id, Relevance: rel * (1 + idx.scoreWeight*score/100)}
+		hit.Key = sortKey(req.Sort, hit, entry, len(terms) == 0)
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Key != hits[j].Key {
+			return hits[i].Key > hits[j].Key
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	return hits, facets.result()
+}
@@ -0,0 +1,347 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestIndex 用内置词典建立索引并写入文档
+func newTestIndex(scoreWeight float64, docs ...Document) *Index {
+	idx := NewIndex(NewSegmenter(), scoreWeight)
+	for _, doc := range docs {
+		idx.Upsert(doc)
+	}
+	return idx
+}
+
+// hitIDs 命中的文档 ID，保持顺序
+func hitIDs(hits []Hit) []uint {
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		name     string
+		text     string
+		forIndex bool
+		want     []Token
+	}{
+		{"词典长词优先", "广州塔夜景", false, []Token{{"广州塔", 0, 3}, {"夜景", 3, 5}}},
+		{"中英文数字混排", "GoPro拍夜景2024", false, []Token{{"gopro", 0, 5}, {"拍", 5, 6}, {"夜景", 6, 8}, {"2024", 8, 12}}},
+		{"标点和空格分隔", "Hello, 世界!", false, []Token{{"hello", 0, 5}, {"世界", 7, 9}}},
+		{"词典外连续单字按二元组", "食堂阿姨", false, []Token{{"食堂", 0, 2}, {"堂阿", 1, 3}, {"阿姨", 2, 4}}},
+		{"单个停用字不入索引", "夜市的拍照", false, []Token{{"夜市", 0, 2}, {"拍照", 3, 5}}},
+		{"全角和大小写", "ＶＬＯＧ记录", false, []Token{{"ｖｌｏｇ", 0, 4}, {"记录", 4, 6}}},
+		{"索引模式输出长词内的短词", "广州塔", true, []Token{{"广州塔", 0, 3}, {"广州", 0, 2}}},
+		{"空文本", "", false, nil},
+	}
+	seg := NewSegmenter()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []Token
+			if c.forIndex {
+				got = seg.TokenizeForIndex(c.text)
+			} else {
+				got = seg.Tokenize(c.text)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("tokenize(%q) = %v, want %v", c.text, got, c.want)
+			}
+		})
+	}
+}
+
+func TestAddWords(t *testing.T) {
+	seg := NewSegmenter()
+	if n := seg.AddWords("网红打卡点", "广州", "x", "  "); n != 1 {
+		t.Fatalf("只应新增 1 个词，实际 %d", n)
+	}
+	got := seg.Tokenize("网红打卡点")
+	want := []Token{{"网红打卡点", 0, 5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("新词应整体切出: %v", got)
+	}
+}
+
+func TestSearchBM25Ordering(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		docs  []Document
+		want  []uint
+	}{
+		{
+			name:  "标题命中高于正文命中",
+			query: "夜景",
+			docs: []Document{
+				{ID: 1, Title: "周末去哪儿", Content: "晚上可以看夜景"},
+				{ID: 2, Title: "广州塔夜景", Content: "晚上可以去看看"},
+				{ID: 3, Title: "早茶", Content: "点心很好吃"},
+			},
+			want: []uint{2, 1},
+		},
+		{
+			name:  "同样命中时短文档更相关",
+			query: "夜景",
+			docs: []Document{
+				{ID: 1, Content: "夜景 拍照 美食 攻略 酒店 民宿 住宿 自驾 徒步 露营"},
+				{ID: 2, Content: "夜景 拍照"},
+			},
+			want: []uint{2, 1},
+		},
+		{
+			name:  "罕见词的权重更高",
+			query: "珠海 夜景",
+			docs: []Document{
+				{ID: 1, Content: "夜景"},
+				{ID: 2, Content: "珠海"},
+				{ID: 3, Content: "夜景"},
+				{ID: 4, Content: "夜景"},
+			},
+			want: []uint{2, 4, 3, 1},
+		},
+		{
+			name:  "相同得分按 ID 降序",
+			query: "美食",
+			docs: []Document{
+				{ID: 5, Content: "美食"},
+				{ID: 9, Content: "美食"},
+				{ID: 7, Content: "美食"},
+			},
+			want: []uint{9, 7, 5},
+		},
+		{
+			name:  "查询词都不在索引中",
+			query: "滑雪",
+			docs:  []Document{{ID: 1, Content: "美食"}},
+			want:  []uint{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hits, _ := newTestIndex(0, c.docs...).Search(Request{Query: c.query})
+			if got := hitIDs(hits); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Search(%q) = %v, want %v", c.query, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSearchBlendsScore(t *testing.T) {
+	docs := []Document{
+		{ID: 1, Content: "美食", Meta: Meta{Stats: Stats{Score: 90}}},
+		{ID: 2, Content: "美食", Meta: Meta{Stats: Stats{Score: 10}}},
+	}
+	hits, _ := newTestIndex(0.5, docs...).Search(Request{Query: "美食"})
+	if got := hitIDs(hits); !reflect.DeepEqual(got, []uint{1, 2}) {
+		t.Fatalf("相关度相同时热度高的在前: %v", got)
+	}
+	if hits[0].Relevance <= hits[1].Relevance {
+		t.Errorf("热度分应提高相关度: %+v", hits)
+	}
+
+	// 更新热度分后排序随之变化
+	idx := newTestIndex(0.5, docs...)
+	idx.UpdateStats(map[uint]Stats{2: {Score: 100}})
+	hits, _ = idx.Search(Request{Query: "美食"})
+	if got := hitIDs(hits); !reflect.DeepEqual(got, []uint{2, 1}) {
+		t.Errorf("刷新热度分后应重新排序: %v", got)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	hit := Hit{ID: 100023, Key: 3.25}
+	cursor := EncodeCursor("q\x00relevance", hit)
+	key, id, err := DecodeCursor("q\x00relevance", cursor)
+	if err != nil || key != hit.Key || id != hit.ID {
+		t.Fatalf("DecodeCursor = (%v, %v, %v), want (%v, %v, nil)", key, id, err, hit.Key, hit.ID)
+	}
+
+	cases := []struct {
+		name   string
+		query  string
+		cursor string
+	}{
+		{"查询条件变化", "q\x00newest", cursor},
+		{"不是 base64", "q\x00relevance", "!!!"},
+		{"不是 JSON", "q\x00relevance", "bm90LWpzb24"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, _, err := DecodeCursor(c.query, c.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("应返回 ErrInvalidCursor，实际 %v", err)
+			}
+		})
+	}
+}
+
+// withEngine 临时替换全局检索引擎
+func withEngine(t *testing.T, idx *Index) {
+	t.Helper()
+	previous := DefaultEngine
+	DefaultEngine = &Engine{index: idx, snippetLength: 80}
+	t.Cleanup(func() { DefaultEngine = previous })
+}
+
+func TestQueryPagination(t *testing.T) {
+	idx := newTestIndex(0)
+	for id := uint(1); id <= 7; id++ {
+		idx.Upsert(Document{ID: id, Content: "美食", Meta: Meta{NoteType: "图文", UpdateTime: int64(1000 + id)}})
+	}
+	withEngine(t, idx)
+
+	// page 按游标翻完所有结果，inserted 在拿到第一页后调用
+	page := func(req Request, inserted func()) []uint {
+		var seen []uint
+		cursor := ""
+		for {
+			result, err := Query(req, cursor, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen = append(seen, hitIDs(result.Hits)...)
+			if cursor == "" && inserted != nil {
+				inserted()
+			}
+			if result.NextCursor == "" {
+				return seen
+			}
+			cursor = result.NextCursor
+		}
+	}
+
+	req := Request{Query: "美食", Sort: SortRelevance}
+	if got, want := page(req, nil), []uint{7, 6, 5, 4, 3, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("按相关度翻页 %v，want %v", got, want)
+	}
+
+	// 按时间排序时，翻页期间新发布的笔记排在最前，不影响后续页
+	newest := Request{Query: "美食", Sort: SortNewest}
+	got := page(newest, func() {
+		idx.Upsert(Document{ID: 8, Content: "美食", Meta: Meta{UpdateTime: 2000}})
+	})
+	if want := []uint{7, 6, 5, 4, 3, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("翻页期间新增笔记后 %v，want %v", got, want)
+	}
+
+	// 换了查询条件不能沿用旧游标
+	first, _ := Query(req, "", 3)
+	other := req
+	other.Filter.NoteType = "视频"
+	if _, err := Query(other, first.NextCursor, 3); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("过滤条件变化后应拒绝旧游标，实际 %v", err)
+	}
+	if _, err := Query(newest, first.NextCursor, 3); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("排序方式变化后应拒绝旧游标，实际 %v", err)
+	}
+}
+
+// unix 上海时区某天中午的时间戳
+func unix(year int, month time.Month, day int) int64 {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.FixedZone("CST", 8*3600)).Unix()
+}
+
+// filterDocs 过滤和分面测试用的笔记
+func filterDocs() []Document {
+	sysu := &GeoPoint{Lat: 23.0965, Lng: 113.2985}   // 中山大学广州校区
+	tower := &GeoPoint{Lat: 23.1066, Lng: 113.3245}  // 广州塔，约 3km
+	zhuhai := &GeoPoint{Lat: 22.3526, Lng: 113.5867} // 珠海校区，约 88km
+	return []Document{
+		{ID: 1, Title: "广州塔夜景", Tags: []string{"夜景", "广州"}, Meta: Meta{NoteType: "图文", CreatorID: 10, UpdateTime: unix(2024, 5, 1), Location: tower, Stats: Stats{LikeCounts: 50, CollectCounts: 5, Score: 30}}},
+		{ID: 2, Title: "珠海海边夜景", Tags: []string{"夜景", "海边"}, Meta: Meta{NoteType: "视频", CreatorID: 11, UpdateTime: unix(2024, 6, 2), Location: zhuhai, Stats: Stats{LikeCounts: 5, CollectCounts: 40, Score: 80}}},
+		{ID: 3, Title: "周末夜景找旅伴", Tags: []string{"夜景", "广州", "周末"}, Meta: Meta{NoteType: "图文", CreatorID: 10, IsFindingBuddy: true, UpdateTime: unix(2024, 6, 20), Location: sysu, Stats: Stats{LikeCounts: 20, CollectCounts: 10, Score: 10}}},
+		{ID: 4, Title: "食堂攻略", Tags: []string{"美食"}, Meta: Meta{NoteType: "图文", CreatorID: 12, UpdateTime: unix(2024, 4, 9), Stats: Stats{LikeCounts: 99}}},
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	idx := newTestIndex(0, filterDocs()...)
+	cases := []struct {
+		name   string
+		filter Filter
+		want   []uint
+	}{
+		{"不限", Filter{}, []uint{3, 2, 1}},
+		{"笔记类型", Filter{NoteType: "视频"}, []uint{2}},
+		{"只看找旅伴", Filter{BuddyOnly: true}, []uint{3}},
+		{"作者", Filter{AuthorID: 10}, []uint{3, 1}},
+		{"标签需全部包含且忽略大小写", Filter{Tags: []string{"广州", " 周末 "}}, []uint{3}},
+		{"时间范围含边界", Filter{From: unix(2024, 5, 1), To: unix(2024, 6, 2)}, []uint{2, 1}},
+		{"最少点赞", Filter{MinLikes: 20}, []uint{3, 1}},
+		{"附近 5km", Filter{Near: &GeoPoint{Lat: 23.0965, Lng: 113.2985}, RadiusKm: 5}, []uint{3, 1}},
+		{"组合条件无结果", Filter{NoteType: "视频", AuthorID: 10}, []uint{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 按时间倒序排列，结果顺序与相关度无关
+			hits, _ := idx.Search(Request{Query: "夜景", Filter: c.filter, Sort: SortNewest})
+			if got := hitIDs(hits); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("过滤 %+v 得到 %v，want %v", c.filter, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSearchSortModes(t *testing.T) {
+	idx := newTestIndex(0, filterDocs()...)
+	cases := []struct {
+		sort  string
+		query string
+		want  []uint
+	}{
+		{SortNewest, "夜景", []uint{3, 2, 1}},
+		{SortLikes, "夜景", []uint{1, 3, 2}},
+		{SortCollects, "夜景", []uint{2, 3, 1}},
+		{SortHot, "夜景", []uint{2, 1, 3}},
+		{SortRelevance, "", []uint{2, 1, 3, 4}}, // 查询为空时按热度分
+	}
+	for _, c := range cases {
+		t.Run(c.sort, func(t *testing.T) {
+			hits, _ := idx.Search(Request{Query: c.query, Sort: c.sort})
+			if got := hitIDs(hits); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("按 %s 排序得到 %v，want %v", c.sort, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	idx := newTestIndex(0, filterDocs()...)
+
+	_, facets := idx.Search(Request{Query: "夜景"})
+	want := Facets{
+		Types:  []FacetCount{{"图文", 2}, {"视频", 1}},
+		Tags:   []FacetCount{{"夜景", 3}, {"广州", 2}, {"周末", 1}, {"海边", 1}},
+		Months: []FacetCount{{"2024-06", 2}, {"2024-05", 1}},
+	}
+	if !reflect.DeepEqual(facets, want) {
+		t.Errorf("分面 %+v，want %+v", facets, want)
+	}
+
+	// 分面只统计满足过滤条件的命中
+	_, facets = idx.Search(Request{Query: "夜景", Filter: Filter{NoteType: "图文"}})
+	want = Facets{
+		Types:  []FacetCount{{"图文", 2}},
+		Tags:   []FacetCount{{"夜景", 2}, {"广州", 2}, {"周末", 1}},
+		Months: []FacetCount{{"2024-06", 1}, {"2024-05", 1}},
+	}
+	if !reflect.DeepEqual(facets, want) {
+		t.Errorf("过滤后的分面 %+v，want %+v", facets, want)
+	}
+
+	// 标签分面只保留出现最多的若干个
+	many := newTestIndex(0)
+	for id := uint(1); id <= 15; id++ {
+		many.Upsert(Document{ID: id, Content: "美食", Tags: []string{fmt.Sprintf("tag%02d", id)}})
+	}
+	if _, facets := many.Search(Request{Query: "美食"}); len(facets.Tags) != topTagFacets {
+		t.Errorf("标签分面应截断为 %d 个，实际 %d", topTagFacets, len(facets.Tags))
+	}
+}
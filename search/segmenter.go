@@ -0,0 +1,179 @@
+package search
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed dict.txt
+var builtinDict string
+
+// maxWordLen 词典词的最大长度（按字符计），更长的词不参与匹配
+const maxWordLen = 8
+
+// stopChars 单字停用词，不进入索引
+var stopChars = map[string]bool{
+	"的": true, "了": true, "是": true, "在": true, "和": true, "也": true, "就": true,
+	"都": true, "而": true, "及": true, "与": true, "着": true, "或": true, "吗": true,
+	"呢": true, "吧": true, "啊": true, "哦": true, "嗯": true, "个": true, "之": true,
+}
+
+// Token 分词结果，Start/End 为在原文中的字符（rune）位置，End 不含
+type Token struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Segmenter 基于词典的中文分词器
+// 中文按正向最大匹配切词，词典外的连续单字按二元组切分；英文数字按连续串切分并转小写
+type Segmenter struct {
+	mu   sync.RWMutex
+	dict map[string]bool
+}
+
+// NewSegmenter 创建加载了内置词典的分词器
+func NewSegmenter() *Segmenter {
+	s := &Segmenter{dict: make(map[string]bool)}
+	for _, line := range strings.Split(builtinDict, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s.dict[strings.ToLower(line)] = true
+	}
+	return s
+}
+
+// AddWords 向词典加入新词（如标签名），返回实际新增的个数
+func (s *Segmenter) AddWords(words ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := 0
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		n := len([]rune(w))
+		if n < 2 || n > maxWordLen || s.dict[w] {
+			continue
+		}
+		s.dict[w] = true
+		added++
+	}
+	return added
+}
+
+// isHan 是否为汉字
+func isHan(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+// isWordChar 是否为英文字母或数字
+func isWordChar(r rune) bool {
+	return !isHan(r) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Tokenize 精确模式分词，用于查询
+func (s *Segmenter) Tokenize(text string) []Token {
+	return s.tokenize(text, false)
+}
+
+// TokenizeForIndex 搜索模式分词，用于建索引：长词额外输出其中包含的词典短词，
+// 这样 "广州塔" 既能被 "广州塔" 也能被 "广州" 搜到
+func (s *Segmenter) TokenizeForIndex(text string) []Token {
+	return s.tokenize(text, true)
+}
+
+func (s *Segmenter) tokenize(text string, forIndex bool) []Token {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tokens []Token
+	for i := 0; i < len(lower); {
+		switch {
+		case isWordChar(lower[i]):
+			j := i
+			for j < len(lower) && isWordChar(lower[j]) {
+				j++
+			}
+			tokens = append(tokens, Token{Text: string(lower[i:j]), Start: i, End: j})
+			i = j
+		case isHan(lower[i]):
+			j := i
+			for j < len(lower) && isHan(lower[j]) {
+				j++
+			}
+			tokens = append(tokens, s.segmentHan(lower[i:j], i, forIndex)...)
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// segmentHan 对一段连续汉字做正向最大匹配，offset 为该段在原文中的起点
+func (s *Segmenter) segmentHan(run []rune, offset int, forIndex bool) []Token {
+	var tokens []Token
+	var pending []rune // 连续的词典外单字
+	pendingStart := 0
+
+	flush := func() {
+		switch {
+		case len(pending) == 1:
+			if w := string(pending); !stopChars[w] {
+				tokens = append(tokens, Token{Text: w, Start: offset + pendingStart, End: offset + pendingStart + 1})
+			}
+		case len(pending) > 1:
+			for k := 0; k+1 < len(pending); k++ {
+				tokens = append(tokens, Token{
+					Text:  string(pending[k : k+2]),
+					Start: offset + pendingStart + k,
+					End:   offset + pendingStart + k + 2,
+				})
+			}
+		}
+		pending = pending[:0]
+	}
+
+	for i := 0; i < len(run); {
+		matched := 0
+		for l := maxWordLen; l >= 2; l-- {
+			if i+l <= len(run) && s.dict[string(run[i:i+l])] {
+				matched = l
+				break
+			}
+		}
+		if matched == 0 {
+			if len(pending) == 0 {
+				pendingStart = i
+			}
+			pending = append(pending, run[i])
+			i++
+			continue
+		}
+
+		flush()
+		tokens = append(tokens, Token{Text: string(run[i : i+matched]), Start: offset + i, End: offset + i + matched})
+		if forIndex && matched > 2 {
+			// 长词内部的词典短词
+			for a := i; a < i+matched; a++ {
+				for b := a + 2; b <= i+matched && b-a < matched; b++ {
+					if s.dict[string(run[a:b])] {
+						tokens = append(tokens, Token{Text: string(run[a:b]), Start: offset + a, End: offset + b})
+					}
+				}
+			}
+		}
+		i += matched
+	}
+	flush()
+	return tokens
+}
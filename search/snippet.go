@@ -0,0 +1,134 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 高亮标签
+const (
+	highlightOpen  = "<em>"
+	highlightClose = "</em>"
+)
+
+// span 命中区间（按字符计，End 不含）
+type span struct {
+	start, end int
+}
+
+// matchSpans 找出 text 中所有查询词出现的位置，重叠区间合并
+func matchSpans(runes []rune, terms []string) []span {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	var spans []span
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				spans = append(spans, span{i, i + len(t)})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := []span{spans[0]}
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.start <= last.end {
+			if s.end > last.end {
+				last.end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// render 对 runes[from:to] 做 HTML 转义，并用高亮标签包住命中区间
+func render(runes []rune, spans []span, from, to int) string {
+	var b strings.Builder
+	pos := from
+	for _, s := range spans {
+		if s.end <= from || s.start >= to {
+			continue
+		}
+		start, end := s.start, s.end
+		if start < from {
+			start = from
+		}
+		if end > to {
+			end = to
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:start])))
+		b.WriteString(highlightOpen)
+		b.WriteString(html.EscapeString(string(runes[start:end])))
+		b.WriteString(highlightClose)
+		pos = end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:to])))
+	return b.String()
+}
+
+// Highlight 返回整段文本的高亮结果（用于标题）
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	return render(runes, matchSpans(runes, terms), 0, len(runes))
+}
+
+// Snippet 截取命中最密集处附近约 length 个字符并高亮，没有命中时返回开头部分
+func Snippet(text string, terms []string, length int) string {
+	runes := []rune(text)
+	if length <= 0 {
+		length = 80
+	}
+	spans := matchSpans(runes, terms)
+	if len(runes) <= length {
+		return render(runes, spans, 0, len(runes))
+	}
+
+	// 选择窗口内命中数最多的起点，窗口从命中前留一点上下文
+	from := 0
+	if len(spans) > 0 {
+		best, bestCount := spans[0].start, 0
+		for i, s := range spans {
+			count := 0
+			for _, t := range spans[i:] {
+				if t.end-s.start > length {
+					break
+				}
+				count++
+			}
+			if count > bestCount {
+				best, bestCount = s.start, count
+			}
+		}
+		from = best - length/5
+		if from < 0 {
+			from = 0
+		}
+	}
+	to := from + length
+	if to > len(runes) {
+		to = len(runes)
+		from = to - length
+	}
+
+	result := render(runes, spans, from, to)
+	if from > 0 {
+		result = "…" + result
+	}
+	if to < len(runes) {
+		result += "…"
+	}
+	return result
+}
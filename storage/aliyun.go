@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/joho/godotenv"
+)
+
+// AliyunConfig 阿里云 OSS 配置，为空的项从 .env 中的 OSS_* 变量读取
+type AliyunConfig struct {
+	Endpoint        string
+	Bucket          string
+	AccessKeyID     string
+	AccessKeySecret string
+}
+
+// aliyunStorage 阿里云 OSS 驱动
+type aliyunStorage struct {
+	cfg     AliyunConfig
+	bucket  *oss.Bucket
+	baseURL string
+}
+
+// NewAliyun 创建阿里云 OSS 驱动
+func NewAliyun(cfg AliyunConfig) (Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.AccessKeySecret == "" {
+		// 兼容只在 .env 中配置的部署，文件不存在时忽略
+		_ = godotenv.Load()
+		fallback := func(v *string, env string) {
+			if *v == "" {
+				*v = os.Getenv(env)
+			}
+		}
+		fallback(&cfg.Bucket, "OSS_BUCKET_NAME")
+		fallback(&cfg.Endpoint, "OSS_ENDPOINT")
+		fallback(&cfg.AccessKeyID, "OSS_ACCESS_KEY_ID")
+		fallback(&cfg.AccessKeySecret, "OSS_ACCESS_KEY_SECRET")
+	}
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.AccessKeySecret == "" {
+		return nil, errors.New("阿里云 OSS 需要配置 Endpoint、Bucket、AccessKeyID 和 AccessKeySecret")
+	}
+
+	client, err := oss.New(cfg.Endpoint, cfg.AccessKeyID, cfg.AccessKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("创建 OSS 客户端失败: %v", err)
+	}
+	bucket, err := client.Bucket(cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("获取 OSS Bucket 失败: %v", err)
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(cfg.Endpoint, "https://"), "http://")
+	return &aliyunStorage{
+		cfg:     cfg,
+		bucket:  bucket,
+		baseURL: fmt.Sprintf("https://%s.%s/", cfg.Bucket, host),
+	}, nil
+}
+
+func (s *aliyunStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	var options []oss.Option
+	if contentType != "" {
+		options = append(options, oss.ContentType(contentType))
+	}
+	if size > multipartThreshold {
+		return s.putMultipart(key, r, size, options)
+	}
+	if size >= 0 {
+		options = append(options, oss.ContentLength(size))
+	}
+	return s.bucket.PutObject(key, r, options...)
+}
+
+// putMultipart 分片上传大对象，任一分片失败时取消整个上传，OSS 上不留下残片
+func (s *aliyunStorage) putMultipart(key string, r io.Reader, size int64, options []oss.Option) error {
+	imur, err := s.bucket.InitiateMultipartUpload(key, options...)
+	if err != nil {
+		return err
+	}
+	ps := partSize(size)
+	parts := make([]oss.UploadPart, 0, (size+ps-1)/ps)
+	for offset, number := int64(0), 1; offset < size; offset, number = offset+ps, number+1 {
+		n := min(ps, size-offset)
+		part, err := s.bucket.UploadPart(imur, io.LimitReader(r, n), n, number)
+		if err != nil {
+			if abortErr := s.bucket.AbortMultipartUpload(imur); abortErr != nil {
+				log.Printf("取消 OSS 分片上传 %s 失败: %v", key, abortErr)
+			}
+			return fmt.Errorf("上传第 %d 个分片失败: %v", number, err)
+		}
+		parts = append(parts, part)
+	}
+	if _, err := s.bucket.CompleteMultipartUpload(imur, parts); err != nil {
+		if abortErr := s.bucket.AbortMultipartUpload(imur); abortErr != nil {
+			log.Printf("取消 OSS 分片上传 %s 失败: %v", key, abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *aliyunStorage) Delete(key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	return s.bucket.DeleteObject(key)
+}
+
+func (s *aliyunStorage) Stat(key string) (ObjectInfo, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	header, err := s.bucket.GetObjectDetailedMeta(key)
+	if err != nil {
+		if isOSSNotFound(err) {
+			return ObjectInfo{}, ErrNotFound
+		}
+		return ObjectInfo{}, err
+	}
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	return ObjectInfo{
+		Key:         key,
+		Size:        size,
+		ContentType: header.Get("Content-Type"),
+		ETag:        strings.Trim(header.Get("ETag"), `"`),
+	}, nil
+}
+
+func (s *aliyunStorage) Open(key string) (io.ReadCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.bucket.GetObject(key)
+	if err != nil {
+		if isOSSNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+// isOSSNotFound 是否为对象不存在的错误
+func isOSSNotFound(err error) bool {
+	var serviceErr oss.ServiceError
+	return errors.As(err, &serviceErr) && serviceErr.StatusCode == http.StatusNotFound
+}
+
+// PresignPost OSS 表单上传（PostObject），签名为对 Base64 编码后的策略做 HMAC-SHA1
+func (s *aliyunStorage) PresignPost(key, contentType string, maxSize int64, expires time.Duration) (PostPolicy, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return PostPolicy{}, err
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": time.Now().Add(expires).UTC().Format("2006-01-02T15:04:05.000Z"),
+		"conditions": []interface{}{
+			map[string]string{"bucket": s.cfg.Bucket},
+			[]interface{}{"eq", "$key", key},
+			[]interface{}{"eq", "$Content-Type", contentType},
+			[]interface{}{"content-length-range", 1, maxSize},
+		},
+	})
+	if err != nil {
+		return PostPolicy{}, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(policy)
+	mac := hmac.New(sha1.New, []byte(s.cfg.AccessKeySecret))
+	mac.Write([]byte(encoded))
+	return PostPolicy{
+		URL: s.baseURL,
+		Fields: map[string]string{
+			"key":                   key,
+			"Content-Type":          contentType,
+			"OSSAccessKeyId":        s.cfg.AccessKeyID,
+			"policy":                encoded,
+			"Signature":             base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+			"success_action_status": "200",
+		},
+	}, nil
+}
+
+func (s *aliyunStorage) SignURL(method, key string, expires time.Duration, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	var options []oss.Option
+	if method == http.MethodPut && contentType != "" {
+		options = append(options, oss.ContentType(contentType))
+	}
+	return s.bucket.SignURL(key, oss.HTTPMethod(method), int64(expires.Seconds()), options...)
+}
+
+func (s *aliyunStorage) URL(key string) string {
+	return s.baseURL + key
+}
+
+func (s *aliyunStorage) KeyFromURL(url string) (string, bool) {
+	if !strings.HasPrefix(url, s.baseURL) {
+		return "", false
+	}
+	key, err := cleanKey(strings.SplitN(strings.TrimPrefix(url, s.baseURL), "?", 2)[0])
+	return key, err == nil
+}
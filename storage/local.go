@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocalConfig 本地文件系统配置，文件由服务自身在 Prefix 路由下提供访问
+type LocalConfig struct {
+	Dir     string // 存放目录，默认 ./uploads
+	BaseURL string // 对外访问的服务地址，如 http://localhost:3000
+	Prefix  string // 路由前缀，默认 /files
+	Secret  string // 签名 URL 使用的密钥
+}
+
+// localStorage 本地文件系统驱动，开发环境和单机部署使用
+type localStorage struct {
+	dir     string
+	baseURL string
+	prefix  string
+	secret  []byte
+}
+
+// NewLocal 创建本地文件系统驱动
+func NewLocal(cfg LocalConfig) (Storage, error) {
+	if cfg.Dir == "" {
+		cfg.Dir = "./uploads"
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "/files"
+	}
+	if cfg.Secret == "" {
+		return nil, errors.New("本地存储需要配置 Secret 用于签名 URL")
+	}
+	dir, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %v", err)
+	}
+	return &localStorage{
+		dir:     dir,
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		prefix:  "/" + strings.Trim(cfg.Prefix, "/"),
+		secret:  []byte(cfg.Secret),
+	}, nil
+}
+
+// path 对象在磁盘上的路径
+func (s *localStorage) path(key string) (string, string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return key, filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *localStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	_, p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	// 先写临时文件再改名，读到的对象要么是旧的要么是完整的新对象
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && n != size {
+		return fmt.Errorf("写入大小不一致: 期望 %d，实际 %d", size, n)
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *localStorage) Delete(key string) error {
+	_, p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) Stat(key string) (ObjectInfo, error) {
+	key, p, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ObjectInfo{}, ErrNotFound
+		}
+		return ObjectInfo{}, err
+	}
+	if fi.IsDir() {
+		return ObjectInfo{}, ErrNotFound
+	}
+	return ObjectInfo{
+		Key:         key,
+		Size:        fi.Size(),
+		ContentType: detectFileType(p),
+		ETag:        fmt.Sprintf("%x-%x", fi.ModTime().UnixNano(), fi.Size()),
+	}, nil
+}
+
+func (s *localStorage) Open(key string) (io.ReadCloser, error) {
+	_, p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// detectFileType 先按扩展名判断类型，判断不了再读文件头
+func detectFileType(p string) string {
+	if t := mime.TypeByExtension(filepath.Ext(p)); t != "" {
+		return t
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return http.DetectContentType(head[:n])
+}
+
+// sign 对方法、路径、过期时间和内容类型做 HMAC
+func (s *localStorage) sign(method, key string, expires int64, contentType string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%s", method, key, expires, contentType)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *localStorage) SignURL(method, key string, expires time.Duration, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if method != http.MethodPut {
+		contentType = ""
+	}
+	exp := time.Now().Add(expires).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(exp, 10))
+	q.Set("signature", s.sign(method, key, exp, contentType))
+	return s.URL(key) + "?" + q.Encode(), nil
+}
+
+// PresignPost 本地表单直传，由 Mount 注册的 POST 路由校验签名、类型和大小
+func (s *localStorage) PresignPost(key, contentType string, maxSize int64, expires time.Duration) (PostPolicy, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return PostPolicy{}, err
+	}
+	exp := time.Now().Add(expires).Unix()
+	return PostPolicy{
+		URL: s.baseURL + s.prefix,
+		Fields: map[string]string{
+			"key":          key,
+			"Content-Type": contentType,
+			"expires":      strconv.FormatInt(exp, 10),
+			"max_size":     strconv.FormatInt(maxSize, 10),
+			"signature":    s.sign(http.MethodPost, key, exp, contentType+"\n"+strconv.FormatInt(maxSize, 10)),
+		},
+	}, nil
+}
+
+func (s *localStorage) URL(key string) string {
+	return s.baseURL + s.prefix + "/" + key
+}
+
+func (s *localStorage) KeyFromURL(u string) (string, bool) {
+	base := s.baseURL + s.prefix + "/"
+	if !strings.HasPrefix(u, base) {
+		return "", false
+	}
+	key, err := cleanKey(strings.SplitN(strings.TrimPrefix(u, base), "?", 2)[0])
+	return key, err == nil
+}
+
+// Mount 注册文件访问和签名直传的路由
+func (s *localStorage) Mount(r gin.IRouter) {
+	r.GET(s.prefix+"/*key", s.serve)
+	r.HEAD(s.prefix+"/*key", s.serve)
+	r.PUT(s.prefix+"/*key", s.upload)
+	r.POST(s.prefix, s.postUpload)
+}
+
+// serve 提供文件下载，文件对外公开，签名参数会被忽略
+func (s *localStorage) serve(ctx *gin.Context) {
+	_, p, err := s.path(ctx.Param("key"))
+	if err != nil {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	if fi, err := os.Stat(p); err != nil || fi.IsDir() {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+	ctx.File(p)
+}
+
+// upload 处理签名 URL 的 PUT 直传
+func (s *localStorage) upload(ctx *gin.Context) {
+	key, err := cleanKey(ctx.Param("key"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "失败", "code": 400, "error": "无效的对象路径"})
+		return
+	}
+	exp, err := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "失败", "code": 403, "error": "签名已过期"})
+		return
+	}
+	contentType := ctx.GetHeader("Content-Type")
+	expected := s.sign(http.MethodPut, key, exp, contentType)
+	if !hmac.Equal([]byte(expected), []byte(ctx.Query("signature"))) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "失败", "code": 403, "error": "签名无效"})
+		return
+	}
+	if err := s.Put(key, ctx.Request.Body, ctx.Request.ContentLength, contentType); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "失败", "code": 500, "error": "写入文件失败"})
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// postUpload 处理表单直传
+func (s *localStorage) postUpload(ctx *gin.Context) {
+	maxSize, err := strconv.ParseInt(ctx.Request.FormValue("max_size"), 10, 64)
+	if err != nil || maxSize <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "失败", "code": 400, "error": "缺少 max_size"})
+		return
+	}
+	key, err := cleanKey(ctx.Request.FormValue("key"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "失败", "code": 400, "error": "无效的对象路径"})
+		return
+	}
+	exp, err := strconv.ParseInt(ctx.Request.FormValue("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "失败", "code": 403, "error": "签名已过期"})
+		return
+	}
+	contentType := ctx.Request.FormValue("Content-Type")
+	expected := s.sign(http.MethodPost, key, exp, contentType+"\n"+strconv.FormatInt(maxSize, 10))
+	if !hmac.Equal([]byte(expected), []byte(ctx.Request.FormValue("signature"))) {
+		ctx.JSON(http.StatusForbidden, gin.H{"status": "失败", "code": 403, "error": "签名无效"})
+		return
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "失败", "code": 400, "error": "未找到文件"})
+		return
+	}
+	if file.Size <= 0 || file.Size > maxSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "失败", "code": 400, "error": "文件大小超出限制"})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "失败", "code": 500, "error": "读取文件失败"})
+		return
+	}
+	defer src.Close()
+	if err := s.Put(key, src, file.Size, contentType); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "失败", "code": 500, "error": "写入文件失败"})
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestLocal 在临时目录创建本地驱动，并注册它的路由
+func newTestLocal(t *testing.T) (*localStorage, *gin.Engine) {
+	t.Helper()
+	s, err := NewLocal(LocalConfig{Dir: t.TempDir(), BaseURL: "http://localhost:3000", Secret: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	s.(Mounter).Mount(r)
+	return s.(*localStorage), r
+}
+
+func TestCleanKey(t *testing.T) {
+	cases := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"notes/a.jpg", "notes/a.jpg", true},
+		{"/notes//a.jpg", "notes/a.jpg", true},
+		{" notes/./a.jpg ", "notes/a.jpg", true},
+		{"notes/a..b.jpg", "notes/a..b.jpg", true},
+		{"../etc/passwd", "", false},
+		{"notes/../../etc/passwd", "", false},
+		{"notes/../a.jpg", "", false},
+		{`notes\..\a.jpg`, "", false},
+		{"..", "", false},
+		{"", "", false},
+		{"/", "", false},
+	}
+	for _, c := range cases {
+		got, err := cleanKey(c.key)
+		if c.ok && (err != nil || got != c.want) {
+			t.Errorf("cleanKey(%q) = (%q, %v)，want %q", c.key, got, err, c.want)
+		}
+		if !c.ok && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("cleanKey(%q) 应返回 ErrInvalidKey，实际 (%q, %v)", c.key, got, err)
+		}
+	}
+}
+
+func TestLocalPutStatDelete(t *testing.T) {
+	s, _ := newTestLocal(t)
+	const key = "notes/2024/a.txt"
+
+	if _, err := s.Stat(key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("写入前 Stat 应返回 ErrNotFound，实际 %v", err)
+	}
+	if err := s.Put(key, strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.Stat(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Key != key || info.Size != 5 || !strings.HasPrefix(info.ContentType, "text/plain") {
+		t.Errorf("Stat = %+v", info)
+	}
+	r, err := s.Open(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r)
+	r.Close()
+	if string(body) != "hello" {
+		t.Errorf("Open 读到 %q", body)
+	}
+
+	// 大小不一致时不覆盖原对象
+	if err := s.Put(key, strings.NewReader("hi"), 5, "text/plain"); err == nil {
+		t.Error("写入大小不一致应返回错误")
+	}
+	if info, _ := s.Stat(key); info.Size != 5 {
+		t.Errorf("写入失败后原对象被修改: %+v", info)
+	}
+
+	if err := s.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stat(key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后 Stat 应返回 ErrNotFound，实际 %v", err)
+	}
+	if err := s.Delete(key); err != nil {
+		t.Errorf("删除不存在的对象不应报错: %v", err)
+	}
+
+	// 目录穿越的路径一律拒绝
+	if err := s.Put("../escape.txt", strings.NewReader("x"), 1, "text/plain"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put ../ 应返回 ErrInvalidKey，实际 %v", err)
+	}
+	if _, err := s.Stat("../escape.txt"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Stat ../ 应返回 ErrInvalidKey，实际 %v", err)
+	}
+}
+
+func TestLocalKeyFromURL(t *testing.T) {
+	s, _ := newTestLocal(t)
+	u := s.URL("notes/a.jpg")
+	if key, ok := s.KeyFromURL(u + "?x=1"); !ok || key != "notes/a.jpg" {
+		t.Errorf("KeyFromURL(%q) = (%q, %v)", u, key, ok)
+	}
+	for _, foreign := range []string{"https://oss.example.com/notes/a.jpg", s.baseURL + s.prefix + "/../a.jpg"} {
+		if _, ok := s.KeyFromURL(foreign); ok {
+			t.Errorf("KeyFromURL(%q) 不应解析成功", foreign)
+		}
+	}
+}
+
+// put 向签名 URL 发起 PUT 请求，返回状态码
+func put(t *testing.T, r http.Handler, signed, contentType, body string) int {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPut, u.RequestURI(), strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestLocalSignedPut(t *testing.T) {
+	s, r := newTestLocal(t)
+	const key = "uploads/v.mp4"
+
+	signed, err := s.SignURL(http.MethodPut, key, time.Minute, "video/mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(signed, "signature=", "signature=0", 1)
+	other, _ := s.SignURL(http.MethodPut, "uploads/other.mp4", time.Minute, "video/mp4")
+	expired, _ := s.SignURL(http.MethodPut, key, -time.Second, "video/mp4")
+
+	cases := []struct {
+		name        string
+		url         string
+		contentType string
+		want        int
+	}{
+		{"签名被篡改", tampered, "video/mp4", http.StatusForbidden},
+		{"内容类型与签名不符", signed, "image/png", http.StatusForbidden},
+		{"签名属于其他对象", strings.Replace(other, "other.mp4", "v.mp4", 1), "video/mp4", http.StatusForbidden},
+		{"签名已过期", expired, "video/mp4", http.StatusForbidden},
+		{"目录穿越", strings.Replace(signed, "/uploads/", "/uploads/%2e%2e/", 1), "video/mp4", http.StatusBadRequest},
+		{"有效签名", signed, "video/mp4", http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := put(t, r, c.url, c.contentType, "data"); got != c.want {
+				t.Errorf("PUT 返回 %d，want %d", got, c.want)
+			}
+		})
+	}
+
+	info, err := s.Stat(key)
+	if err != nil || info.Size != 4 {
+		t.Errorf("有效签名应写入对象: %+v, %v", info, err)
+	}
+}
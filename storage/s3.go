@@ -0,0 +1,518 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config S3 兼容存储配置，AWS S3 和 MinIO 都可以使用
+type S3Config struct {
+	Endpoint        string // 如 https://s3.us-east-1.amazonaws.com 或 http://127.0.0.1:9000
+	Region          string // 默认 us-east-1
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool   // MinIO 一般需要开启
+	PublicBaseURL   string // 对外访问地址（如 CDN），为空时使用 Endpoint 拼出的地址
+}
+
+const (
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3UnsignedBody   = "UNSIGNED-PAYLOAD"
+	s3TimeFormat     = "20060102T150405Z"
+	s3DateFormat     = "20060102"
+	s3MaxPresignTime = 7 * 24 * time.Hour
+	// s3RequestTimeout 单个请求的超时；大对象分片上传，一个请求最多带一个分片
+	s3RequestTimeout = 10 * time.Minute
+)
+
+// s3Storage S3 兼容存储驱动，请求使用 SigV4 签名
+type s3Storage struct {
+	cfg     S3Config
+	scheme  string
+	host    string
+	baseURL string
+	client  *http.Client
+}
+
+// NewS3 创建 S3 兼容存储驱动
+func NewS3(cfg S3Config) (Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("S3 存储需要配置 Endpoint、Bucket、AccessKeyID 和 SecretAccessKey")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if !strings.Contains(cfg.Endpoint, "://") {
+		cfg.Endpoint = "https://" + cfg.Endpoint
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的 S3 Endpoint: %s", cfg.Endpoint)
+	}
+
+	s := &s3Storage{
+		cfg:    cfg,
+		scheme: endpoint.Scheme,
+		host:   endpoint.Host,
+		// 不设整体超时，否则大文件和慢速下载会被中途掐断；上传请求在 send 中按请求单独限时
+		client: &http.Client{Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 2 * time.Minute,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConnsPerHost:   16,
+		}},
+	}
+	if !cfg.PathStyle {
+		s.host = cfg.Bucket + "." + endpoint.Host
+	}
+	s.baseURL = strings.TrimRight(cfg.PublicBaseURL, "/") + "/"
+	if cfg.PublicBaseURL == "" {
+		s.baseURL = s.scheme + "://" + s.host + s.bucketPath() + "/"
+	}
+	return s, nil
+}
+
+// bucketPath 路径风格时 URL 中 bucket 的部分
+func (s *s3Storage) bucketPath() string {
+	if s.cfg.PathStyle {
+		return "/" + s.cfg.Bucket
+	}
+	return ""
+}
+
+// objectPath 对象的规范 URI
+func (s *s3Storage) objectPath(key string) string {
+	return s.bucketPath() + "/" + uriEncode(key, false)
+}
+
+func (s *s3Storage) Put(key string, r io.Reader, size int64, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		// S3 的 PUT 必须带 Content-Length，大小未知时先读到内存
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if size > multipartThreshold {
+		return s.putMultipart(key, r, size, header)
+	}
+	resp, err := s.send(http.MethodPut, key, nil, r, size, header)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+// putMultipart 分片上传大对象（CreateMultipartUpload/UploadPart/CompleteMultipartUpload），
+// 任一步失败时取消上传，存储上不留下残片
+func (s *s3Storage) putMultipart(key string, r io.Reader, size int64, header http.Header) error {
+	resp, err := s.send(http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&initiated); err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("创建 S3 分片上传失败: %v", err)
+	}
+
+	if err := s.uploadParts(key, initiated.UploadID, r, size); err != nil {
+		abort, abortErr := s.send(http.MethodDelete, key, url.Values{"uploadId": {initiated.UploadID}}, nil, 0, nil)
+		if abortErr == nil && abort.StatusCode != http.StatusNoContent {
+			abortErr = s3Error(abort)
+		}
+		if abortErr != nil {
+			log.Printf("取消 S3 分片上传 %s 失败: %v", key, abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// s3Part 已上传的分片，合并时按编号列出
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// uploadParts 逐个上传分片并合并
+func (s *s3Storage) uploadParts(key, uploadID string, r io.Reader, size int64) error {
+	ps := partSize(size)
+	parts := make([]s3Part, 0, (size+ps-1)/ps)
+	for offset, number := int64(0), 1; offset < size; offset, number = offset+ps, number+1 {
+		n := min(ps, size-offset)
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		resp, err := s.send(http.MethodPut, key, query, io.LimitReader(r, n), n, nil)
+		if err != nil {
+			return fmt.Errorf("上传第 %d 个分片失败: %v", number, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("上传第 %d 个分片失败: %v", number, s3Error(resp))
+		}
+		parts = append(parts, s3Part{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := s.send(http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body), int64(len(body)), nil)
+	if err != nil {
+		return err
+	}
+	// 合并失败时 S3 也可能返回 200，错误写在响应体里
+	var result struct {
+		XMLName xml.Name
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || xml.Unmarshal(data, &result) != nil || result.XMLName.Local == "Error" {
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		return fmt.Errorf("合并分片失败: %v", s3Error(resp))
+	}
+	return nil
+}
+
+func (s *s3Storage) Delete(key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.send(http.MethodDelete, key, nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (s *s3Storage) Stat(key string) (ObjectInfo, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp, err := s.send(http.MethodHead, key, nil, nil, 0, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ObjectInfo{}, s3Error(resp)
+	}
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	return ObjectInfo{
+		Key:         key,
+		Size:        size,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+	}, nil
+}
+
+func (s *s3Storage) Open(key string) (io.ReadCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	// 下载按需流式读取，不限制整体时长
+	resp, err := s.do(context.Background(), http.MethodGet, key, nil, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+	return resp.Body, nil
+}
+
+// PresignPost S3 表单上传（POST Object），策略用 SigV4 签名密钥直接签
+func (s *s3Storage) PresignPost(key, contentType string, maxSize int64, expires time.Duration) (PostPolicy, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return PostPolicy{}, err
+	}
+	if expires <= 0 || expires > s3MaxPresignTime {
+		return PostPolicy{}, fmt.Errorf("签名有效期需要在 0 到 %v 之间", s3MaxPresignTime)
+	}
+	now := time.Now().UTC()
+	credential := s.cfg.AccessKeyID + "/" + s.scope(now)
+	amzDate := now.Format(s3TimeFormat)
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(expires).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": []interface{}{
+			map[string]string{"bucket": s.cfg.Bucket},
+			map[string]string{"key": key},
+			map[string]string{"Content-Type": contentType},
+			[]interface{}{"content-length-range", 1, maxSize},
+			map[string]string{"x-amz-algorithm": s3Algorithm},
+			map[string]string{"x-amz-credential": credential},
+			map[string]string{"x-amz-date": amzDate},
+		},
+	})
+	if err != nil {
+		return PostPolicy{}, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(policy)
+	return PostPolicy{
+		URL: s.scheme + "://" + s.host + s.bucketPath() + "/",
+		Fields: map[string]string{
+			"key":              key,
+			"Content-Type":     contentType,
+			"x-amz-algorithm":  s3Algorithm,
+			"x-amz-credential": credential,
+			"x-amz-date":       amzDate,
+			"policy":           encoded,
+			"x-amz-signature":  hex.EncodeToString(hmacSHA256(s.signingKey(now), encoded)),
+		},
+	}, nil
+}
+
+func (s *s3Storage) SignURL(method, key string, expires time.Duration, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > s3MaxPresignTime {
+		return "", fmt.Errorf("签名有效期需要在 0 到 %v 之间", s3MaxPresignTime)
+	}
+	if method != http.MethodPut {
+		contentType = ""
+	}
+	return s.presign(method, key, expires, contentType, time.Now()), nil
+}
+
+func (s *s3Storage) URL(key string) string {
+	return s.baseURL + key
+}
+
+func (s *s3Storage) KeyFromURL(u string) (string, bool) {
+	if !strings.HasPrefix(u, s.baseURL) {
+		return "", false
+	}
+	raw := strings.SplitN(strings.TrimPrefix(u, s.baseURL), "?", 2)[0]
+	if unescaped, err := url.PathUnescape(raw); err == nil {
+		raw = unescaped
+	}
+	key, err := cleanKey(raw)
+	return key, err == nil
+}
+
+// presign 生成查询参数签名的 URL，now 单独传入便于核对签名
+func (s *s3Storage) presign(method, key string, expires time.Duration, contentType string, now time.Time) string {
+	now = now.UTC()
+	amzDate := now.Format(s3TimeFormat)
+	scope := s.scope(now)
+
+	signedHeaders := "host"
+	canonicalHeaders := "host:" + s.host + "\n"
+	if contentType != "" {
+		signedHeaders = "content-type;host"
+		canonicalHeaders = "content-type:" + contentType + "\n" + canonicalHeaders
+	}
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.cfg.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+
+	canonicalQuery := canonicalQueryString(query)
+	canonicalRequest := strings.Join([]string{
+		method,
+		s.objectPath(key),
+		canonicalQuery,
+		canonicalHeaders,
+		signedHeaders,
+		s3UnsignedBody,
+	}, "\n")
+	signature := s.signature(now, canonicalRequest)
+
+	return s.scheme + "://" + s.host + s.objectPath(key) + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// send 发送限时 s3RequestTimeout 的请求并读出响应体，返回的响应体可在超时后继续读取
+func (s *s3Storage) send(method, key string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+	resp, err := s.do(ctx, method, key, query, body, size, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// do 发送带 Authorization 头签名的请求，请求体不参与签名；query 为子资源参数（如分片上传的 uploadId）
+func (s *s3Storage) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.scheme+"://"+s.host+s.objectPath(key), body)
+	if err != nil {
+		return nil, err
+	}
+	// 路径已经按 SigV4 规则编码过，避免 net/url 再次编码
+	req.URL.RawPath = s.objectPath(key)
+	canonicalQuery := canonicalQueryString(query)
+	req.URL.RawQuery = canonicalQuery
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+
+	now := time.Now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+
+	names := []string{"host"}
+	values := map[string]string{"host": s.host}
+	for k := range req.Header {
+		lower := strings.ToLower(k)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			names = append(names, lower)
+			values[lower] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		s.objectPath(key),
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedBody,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.cfg.AccessKeyID, s.scope(now), signedHeaders, s.signature(now, canonicalRequest)))
+
+	return s.client.Do(req)
+}
+
+// scope 签名范围：日期/区域/服务/aws4_request
+func (s *s3Storage) scope(now time.Time) string {
+	return now.Format(s3DateFormat) + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature 按 SigV4 计算规范请求的签名
+func (s *s3Storage) signature(now time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3TimeFormat),
+		s.scope(now),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	return hex.EncodeToString(hmacSHA256(s.signingKey(now), stringToSign))
+}
+
+// signingKey 由密钥、日期、区域和服务逐级派生的签名密钥
+func (s *s3Storage) signingKey(now time.Time) []byte {
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), now.Format(s3DateFormat))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQueryString 按参数名排序并用 SigV4 规则编码
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode SigV4 的 URI 编码：只保留非保留字符，路径中的 / 不编码
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3Error 把错误响应转成 error
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 请求失败: %s %s", resp.Status, strings.TrimSpace(string(body)))
+}
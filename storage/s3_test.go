@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 内存中的 S3，只实现对象 PUT 和分片上传，并按 SigV4 核对每个请求的签名
+type fakeS3 struct {
+	t       *testing.T
+	s       *s3Storage
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+	uploads map[string]map[int][]byte // uploadId → 分片
+	aborted []string
+	puts    int    // 不带子资源的整对象 PUT 次数
+	failAt  int    // 上传到该编号的分片时返回 500
+	errBody string // 合并时返回 200 但带有的错误响应体
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	f := &fakeS3{t: t, objects: map[string][]byte{}, types: map[string]string{}, uploads: map[string]map[int][]byte{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	s, err := NewS3(S3Config{Endpoint: server.URL, Bucket: "travel", AccessKeyID: "AK", SecretAccessKey: "SK", PathStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.s = s.(*s3Storage)
+	return f
+}
+
+// withMultipart 临时调小分片上传的阈值和分片大小
+func withMultipart(t *testing.T, threshold, part int64) {
+	previousThreshold, previousPart := multipartThreshold, multipartPartSize
+	multipartThreshold, multipartPartSize = threshold, part
+	t.Cleanup(func() { multipartThreshold, multipartPartSize = previousThreshold, previousPart })
+}
+
+// verify 用收到的请求重建规范请求，核对签名，确保查询参数等都按实际发送的内容签过名
+func (f *fakeS3) verify(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	var signedHeaders, signature string
+	for _, part := range strings.Split(strings.TrimPrefix(auth, s3Algorithm+" "), ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "SignedHeaders":
+			signedHeaders = v
+		case "Signature":
+			signature = v
+		}
+	}
+	now, err := time.Parse(s3TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		canonicalQueryString(r.URL.Query()),
+		headers.String(),
+		signedHeaders,
+		s3UnsignedBody,
+	}, "\n")
+	return signature == f.s.signature(now, canonicalRequest)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.verify(r) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	key := strings.TrimPrefix(r.URL.Path, "/travel/")
+	q := r.URL.Query()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		id := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[id] = map[int][]byte{}
+		f.types[key] = r.Header.Get("Content-Type")
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		var number int
+		fmt.Sscan(q.Get("partNumber"), &number)
+		if number == f.failAt {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.uploads[q.Get("uploadId")][number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		if f.errBody != "" {
+			fmt.Fprint(w, f.errBody)
+			return
+		}
+		var complete struct {
+			Parts []s3Part `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		parts := f.uploads[q.Get("uploadId")]
+		var object bytes.Buffer
+		for i, p := range complete.Parts {
+			if p.PartNumber != i+1 || p.ETag != fmt.Sprintf(`"etag-%d"`, p.PartNumber) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			object.Write(parts[p.PartNumber])
+		}
+		f.objects[key] = object.Bytes()
+		delete(f.uploads, q.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		f.aborted = append(f.aborted, q.Get("uploadId"))
+		delete(f.uploads, q.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.puts++
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestS3PutSmallObject(t *testing.T) {
+	withMultipart(t, 64, 16)
+	f := newFakeS3(t)
+	if err := f.s.Put("notes/a.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if f.puts != 1 || string(f.objects["notes/a.txt"]) != "hello" || f.types["notes/a.txt"] != "text/plain" {
+		t.Errorf("小对象应单次 PUT: puts=%d objects=%q types=%v", f.puts, f.objects, f.types)
+	}
+}
+
+func TestS3PutMultipart(t *testing.T) {
+	withMultipart(t, 64, 16)
+	f := newFakeS3(t)
+	data := bytes.Repeat([]byte("0123456789"), 10) // 100 字节，分 7 片
+	if err := f.s.Put("note_videos/v.mp4", bytes.NewReader(data), int64(len(data)), "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+	if f.puts != 0 {
+		t.Errorf("大对象不应整体 PUT，实际 %d 次", f.puts)
+	}
+	if !bytes.Equal(f.objects["note_videos/v.mp4"], data) {
+		t.Errorf("合并后的对象内容不一致: %q", f.objects["note_videos/v.mp4"])
+	}
+	if f.types["note_videos/v.mp4"] != "video/mp4" {
+		t.Errorf("内容类型应在创建分片上传时设置，实际 %q", f.types["note_videos/v.mp4"])
+	}
+	if len(f.uploads) != 0 || len(f.aborted) != 0 {
+		t.Errorf("不应留下未完成的分片上传: %v %v", f.uploads, f.aborted)
+	}
+}
+
+func TestS3PutMultipartAbortsOnFailure(t *testing.T) {
+	withMultipart(t, 64, 16)
+	cases := []struct {
+		name    string
+		failAt  int
+		errBody string
+	}{
+		{"分片上传失败", 3, ""},
+		{"合并返回 200 但响应体是错误", 0, "<Error><Code>InternalError</Code></Error>"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeS3(t)
+			f.failAt, f.errBody = c.failAt, c.errBody
+			data := bytes.Repeat([]byte("x"), 100)
+			if err := f.s.Put("note_videos/v.mp4", bytes.NewReader(data), int64(len(data)), "video/mp4"); err == nil {
+				t.Fatal("应返回错误")
+			}
+			if _, ok := f.objects["note_videos/v.mp4"]; ok {
+				t.Error("失败时不应生成对象")
+			}
+			if len(f.aborted) != 1 || len(f.uploads) != 0 {
+				t.Errorf("失败时应取消分片上传: aborted=%v uploads=%v", f.aborted, f.uploads)
+			}
+		})
+	}
+}
+
+func TestPartSize(t *testing.T) {
+	withMultipart(t, 64<<20, 16<<20)
+	cases := []struct {
+		size int64
+		want int64
+	}{
+		{100 << 20, 16 << 20},
+		{20 << 30, 16 << 20},  // 1280 片
+		{200 << 30, 32 << 20}, // 16MB 会超过 10000 片
+	}
+	for _, c := range cases {
+		got := partSize(c.size)
+		if got != c.want || (c.size+got-1)/got > maxParts {
+			t.Errorf("partSize(%d) = %d，want %d", c.size, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package storage
+
+//对象存储抽象：按配置选择阿里云 OSS、S3 兼容存储（AWS S3/MinIO）或本地文件系统。
+//客户端在 Init 时创建一次，上传、删除、查询元信息、签名 URL 都通过 Default() 完成
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound 对象不存在
+	ErrNotFound = errors.New("对象不存在")
+	// ErrInvalidKey 对象路径非法（为空或包含 .. 路径段）
+	ErrInvalidKey = errors.New("无效的对象路径")
+	// ErrForeignURL URL 不属于当前存储
+	ErrForeignURL = errors.New("URL 不属于当前存储")
+)
+
+// ObjectInfo 对象元信息
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ETag        string
+}
+
+// PostPolicy 表单直传参数：以 multipart/form-data 向 URL 提交 Fields 中的全部字段，文件字段名为 file 且放在最后
+type PostPolicy struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Storage 对象存储驱动
+type Storage interface {
+	// Put 写入对象，size 未知时传 -1
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Delete 删除对象，对象不存在不视为错误
+	Delete(key string) error
+	// Stat 查询对象元信息，不存在时返回 ErrNotFound
+	Stat(key string) (ObjectInfo, error)
+	// Open 读取对象内容，不存在时返回 ErrNotFound
+	Open(key string) (io.ReadCloser, error)
+	// SignURL 生成有时效的签名 URL，method 为 GET 或 PUT；PUT 时 contentType 会参与签名
+	SignURL(method, key string, expires time.Duration, contentType string) (string, error)
+	// PresignPost 生成浏览器表单直传的 POST 策略，限定对象路径、内容类型和大小上限
+	PresignPost(key, contentType string, maxSize int64, expires time.Duration) (PostPolicy, error)
+	// URL 对象的公开访问地址
+	URL(key string) string
+	// KeyFromURL 从公开访问地址解析出对象路径
+	KeyFromURL(url string) (string, bool)
+}
+
+// Mounter 需要在 gin 上注册路由的驱动（本地文件系统）
+type Mounter interface {
+	Mount(r gin.IRouter)
+}
+
+// Config 存储配置
+type Config struct {
+	Driver string // local/aliyun/s3
+	Local  LocalConfig
+	Aliyun AliyunConfig
+	S3     S3Config
+}
+
+// 分片上传参数：OSS 和 S3 单次 PUT 最大 5GB，最多 10000 个分片，每片（最后一片除外）不小于 5MB。
+// 超过阈值的对象按分片逐个上传，单个请求只带一个分片，弱网下也不会因为整个文件传不完而超时
+var (
+	multipartThreshold int64 = 64 << 20
+	multipartPartSize  int64 = 16 << 20
+)
+
+const maxParts = 10000
+
+var current Storage
+
+// Init 按配置创建存储驱动，配置不完整时返回错误
+func Init(cfg Config) error {
+	var (
+		s   Storage
+		err error
+	)
+	switch strings.ToLower(cfg.Driver) {
+	case "", "local":
+		s, err = NewLocal(cfg.Local)
+	case "aliyun", "oss":
+		s, err = NewAliyun(cfg.Aliyun)
+	case "s3", "minio":
+		s, err = NewS3(cfg.S3)
+	default:
+		err = fmt.Errorf("未知的存储驱动: %s", cfg.Driver)
+	}
+	if err != nil {
+		return err
+	}
+	current = s
+	return nil
+}
+
+// Default 当前使用的存储驱动
+func Default() Storage {
+	return current
+}
+
+// Mount 当前驱动需要由服务自身提供文件访问时注册路由
+func Mount(r gin.IRouter) {
+	if m, ok := current.(Mounter); ok {
+		m.Mount(r)
+	}
+}
+
+// cleanKey 校验并规范化对象路径；含 .. 路径段的直接拒绝，而不是清理后继续使用
+func cleanKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	if key == "" || key == "." {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
+
+// partSize 分片大小，对象太大时翻倍以保证分片数不超过上限
+func partSize(size int64) int64 {
+	ps := multipartPartSize
+	for (size+ps-1)/ps > maxParts {
+		ps *= 2
+	}
+	return ps
+}
+
+// NewKey 在目录下生成唯一的对象路径，保留原文件的扩展名
+func NewKey(directory, filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return strings.Trim(directory, "/") + "/" + uuid.New().String() + ext
+}
+
+// Upload 把表单文件上传到 directory 下，返回公开访问地址
+func Upload(file *multipart.FileHeader, directory string) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer src.Close()
+
+	contentType := file.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(src, head)
+		contentType = http.DetectContentType(head[:n])
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return "", fmt.Errorf("读取文件失败: %v", err)
+		}
+	}
+
+	key := NewKey(directory, file.Filename)
+	if err := current.Put(key, src, file.Size, contentType); err != nil {
+		return "", fmt.Errorf("文件上传失败: %v", err)
+	}
+	return current.URL(key), nil
+}
+
+// DeleteURL 按公开访问地址删除对象
+func DeleteURL(url string) error {
+	key, ok := current.KeyFromURL(url)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrForeignURL, url)
+	}
+	return current.Delete(key)
+}
@@ -0,0 +1,58 @@
+package testutil
+
+//测试辅助：为单个测试创建独立的内存 SQLite 数据库并替换 global.Db，测试结束后恢复
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"travel-from-sysu-backend/global"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var dbSeq atomic.Int64
+
+// OpenDB 创建内存数据库并迁移给定的表，设置为 global.Db
+func OpenDB(t testing.TB, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:testdb%d?mode=memory&cache=shared", dbSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取数据库连接失败: %v", err)
+	}
+	// 内存库在最后一个连接关闭时销毁，保持一个连接常驻
+	sqlDB.SetMaxIdleConns(1)
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("迁移测试表失败: %v", err)
+	}
+
+	previous := global.Db
+	global.Db = db
+	t.Cleanup(func() {
+		global.Db = previous
+		sqlDB.Close()
+	})
+	return db
+}
+
+// CountQueries 统计 db 上执行的查询语句数（SELECT），返回读取计数的函数
+func CountQueries(db *gorm.DB) func() int64 {
+	var n atomic.Int64
+	db.Callback().Query().After("gorm:query").Register("testutil:count_queries", func(*gorm.DB) {
+		n.Add(1)
+	})
+	db.Callback().Row().After("gorm:row").Register("testutil:count_rows", func(*gorm.DB) {
+		n.Add(1)
+	})
+	return n.Load
+}
@@ -0,0 +1,173 @@
+package timeline
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// item 合并时间线和读扩散结果时的排序单元
+type item struct {
+	NoteID   uint
+	SortTime int64
+}
+
+// ErrInvalidCursor 游标格式不正确
+var ErrInvalidCursor = errors.New("无效的游标")
+
+// Cursor 关注流分页游标：上一页最后一条的 (排序时间, 笔记 ID)，零值表示从最新开始。
+// 同一秒内更新的笔记按 ID 区分，翻页时不会漏掉或重复
+type Cursor struct {
+	SortTime int64
+	NoteID   uint
+}
+
+// String 编码为 "排序时间_笔记ID"，零值编码为空字符串
+func (c Cursor) String() string {
+	if c.SortTime == 0 && c.NoteID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(c.SortTime, 10) + "_" + strconv.FormatUint(uint64(c.NoteID), 10)
+}
+
+// ParseCursor 解析 String 生成的游标；兼容旧版只有时间戳的游标，此时返回该时间之前的笔记
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	timePart, idPart, hasID := strings.Cut(s, "_")
+	sortTime, err := strconv.ParseInt(timePart, 10, 64)
+	if err != nil || sortTime < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	cursor := Cursor{SortTime: sortTime}
+	if hasID {
+		noteID, err := strconv.ParseUint(idPart, 10, 64)
+		if err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+		cursor.NoteID = uint(noteID)
+	}
+	return cursor, nil
+}
+
+// where 排在游标之后（更早）的条件，timeColumn 为排序时间所在的列
+func (c Cursor) where(query *gorm.DB, timeColumn string) *gorm.DB {
+	if c.SortTime == 0 && c.NoteID == 0 {
+		return query
+	}
+	return query.Where("("+timeColumn+" < ? OR ("+timeColumn+" = ? AND note_id < ?))", c.SortTime, c.SortTime, c.NoteID)
+}
+
+// Read 读取用户关注流中排在 after 之后的一页笔记，按更新时间倒序，同一时间按笔记 ID 倒序，并返回下一页的游标。
+// 时间线 + 关注的大号的最新笔记合并；时间线不足一页时，更早的部分退回按关注列表实时查询
+func Read(userID uint, after Cursor, limit int) ([]models.Note, Cursor, error) {
+	var entries []item
+	query := global.Db.Model(&models.TimelineEntry{}).
+		Select("note_id", "sort_time").
+		Where("user_id = ?", userID)
+	query = after.where(query, "sort_time")
+	if err := query.Order("sort_time DESC, note_id DESC").Limit(limit).Scan(&entries).Error; err != nil {
+		return nil, Cursor{}, err
+	}
+
+	candidates := entries
+	var bigAccounts []uint
+	if err := global.Db.Model(&models.Follower{}).
+		Joins("JOIN users ON users.user_id = followers.fid").
+		Where("followers.uid = ? AND users.fan_count >= ?", userID, opts.BigAccountFans).
+		Pluck("followers.fid", &bigAccounts).Error; err != nil {
+		return nil, Cursor{}, err
+	}
+	if len(bigAccounts) > 0 {
+		pulled, err := pull(bigAccounts, after, limit)
+		if err != nil {
+			return nil, Cursor{}, err
+		}
+		candidates = append(candidates, pulled...)
+	}
+
+	if len(entries) < limit {
+		var followees []uint
+		if err := global.Db.Model(&models.Follower{}).Where("uid = ?", userID).Pluck("fid", &followees).Error; err != nil {
+			return nil, Cursor{}, err
+		}
+		if len(followees) > 0 {
+			// 从时间线最后一条之后开始，没有时间线时从 after 开始
+			bound := after
+			if len(entries) > 0 {
+				last := entries[len(entries)-1]
+				bound = Cursor{SortTime: last.SortTime, NoteID: last.NoteID}
+			}
+			pulled, err := pull(followees, bound, limit)
+			if err != nil {
+				return nil, Cursor{}, err
+			}
+			candidates = append(candidates, pulled...)
+		}
+	}
+
+	page := mergePage(candidates, limit)
+	if len(page) == 0 {
+		return nil, Cursor{}, nil
+	}
+	ids := make([]uint, 0, len(page))
+	for _, it := range page {
+		ids = append(ids, it.NoteID)
+	}
+	var notes []models.Note
+	if err := global.Db.Where("note_id IN ?", ids).Find(&notes).Error; err != nil {
+		return nil, Cursor{}, err
+	}
+	byID := make(map[uint]models.Note, len(notes))
+	for _, note := range notes {
+		byID[note.NoteID] = note
+	}
+	ordered := make([]models.Note, 0, len(notes))
+	for _, id := range ids {
+		if note, ok := byID[id]; ok {
+			ordered = append(ordered, note)
+		}
+	}
+	// 游标取自时间线的排序时间而不是笔记当前的更新时间，两者不一致时也能接着翻页
+	last := page[len(page)-1]
+	return ordered, Cursor{SortTime: last.SortTime, NoteID: last.NoteID}, nil
+}
+
+// pull 读扩散：直接查询作者们排在 after 之后的笔记
+func pull(authorIDs []uint, after Cursor, limit int) ([]item, error) {
+	query := global.Db.Model(&models.Note{}).
+		Select("note_id", "note_update_time AS sort_time").
+		Where("note_creator_id IN ?", authorIDs)
+	query = after.where(query, "note_update_time")
+	var items []item
+	err := query.Order("note_update_time DESC, note_id DESC").Limit(limit).Scan(&items).Error
+	return items, err
+}
+
+// mergePage 去重后按时间倒序取一页
+func mergePage(candidates []item, limit int) []item {
+	seen := make(map[uint]bool, len(candidates))
+	merged := make([]item, 0, len(candidates))
+	for _, it := range candidates {
+		if !seen[it.NoteID] {
+			seen[it.NoteID] = true
+			merged = append(merged, it)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].SortTime != merged[j].SortTime {
+			return merged[i].SortTime > merged[j].SortTime
+		}
+		return merged[i].NoteID > merged[j].NoteID
+	})
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
@@ -0,0 +1,90 @@
+package timeline
+
+import (
+	"reflect"
+	"testing"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/testutil"
+)
+
+// setupFollow 用户 1 关注作者 2，作者 2 在同一秒内发布了 5 篇笔记
+func setupFollow(t *testing.T, materialize bool) {
+	t.Helper()
+	testutil.OpenDB(t, &models.User{}, &models.Follower{}, &models.Note{}, &models.TimelineEntry{})
+	global.Db.Create(&[]models.User{{UserId: 1, Username: "alice"}, {UserId: 2, Username: "bob"}})
+	global.Db.Create(&models.Follower{Uid: 1, Fid: 2})
+	for id := uint(100001); id <= 100005; id++ {
+		note := models.Note{NoteID: id, NoteCreatorID: 2, NoteUpdateTime: 1700000000}
+		global.Db.Create(&note)
+		if materialize {
+			global.Db.Create(&models.TimelineEntry{UserID: 1, NoteID: id, AuthorID: 2, SortTime: note.NoteUpdateTime})
+		}
+	}
+}
+
+// readAll 按游标翻完关注流，游标经过字符串编码往返
+func readAll(t *testing.T, limit int) []uint {
+	t.Helper()
+	var ids []uint
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		after, err := ParseCursor(cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		notes, next, err := Read(1, after, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) == 0 {
+			return ids
+		}
+		for _, note := range notes {
+			ids = append(ids, note.NoteID)
+		}
+		cursor = next.String()
+	}
+	t.Fatal("翻页没有结束")
+	return nil
+}
+
+func TestReadPagesThroughSameSecond(t *testing.T) {
+	want := []uint{100005, 100004, 100003, 100002, 100001}
+	for _, materialize := range []bool{true, false} {
+		name := "读扩散兜底"
+		if materialize {
+			name = "时间线"
+		}
+		t.Run(name, func(t *testing.T) {
+			setupFollow(t, materialize)
+			if got := readAll(t, 2); !reflect.DeepEqual(got, want) {
+				t.Errorf("翻页结果 %v，want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    Cursor
+		wantErr bool
+	}{
+		{"", Cursor{}, false},
+		{"1700000000_100003", Cursor{SortTime: 1700000000, NoteID: 100003}, false},
+		{"1700000000", Cursor{SortTime: 1700000000}, false}, // 旧版游标
+		{"abc", Cursor{}, true},
+		{"1700000000_x", Cursor{}, true},
+		{"-1_2", Cursor{}, true},
+	}
+	for _, c := range cases {
+		got, err := ParseCursor(c.in)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("ParseCursor(%q) = (%+v, %v)", c.in, got, err)
+		}
+		if !c.wantErr && c.in != "" && c.want.NoteID != 0 && got.String() != c.in {
+			t.Errorf("String() = %q，want %q", got.String(), c.in)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package timeline
+
+//关注流时间线：写扩散为主、读扩散兜底。
+//普通作者发布/更新笔记时把笔记写入每个粉丝的时间线；粉丝数超过阈值的大号不写扩散，
+//读时直接查大号的笔记再与时间线合并。时间线读完（被截断或上线前的旧数据）后退回实时查询
+
+import (
+	"log"
+	"time"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Options 时间线参数
+type Options struct {
+	BigAccountFans int // 粉丝数不低于该值的作者不写扩散，读时拉取
+	MaxEntries     int // 每个用户时间线保留的条数
+	BackfillCount  int // 关注时回填对方最近的笔记数
+	BatchSize      int // 写扩散每批插入的粉丝数
+	TrimMinutes    int // 截断时间线的间隔（分钟）
+}
+
+var opts = Options{
+	BigAccountFans: 5000,
+	MaxEntries:     800,
+	BackfillCount:  50,
+	BatchSize:      1000,
+	TrimMinutes:    60,
+}
+
+// Init 设置参数并启动时间线截断任务，未配置的项使用默认值，需在数据库初始化之后调用
+func Init(o Options) {
+	if o.BigAccountFans > 0 {
+		opts.BigAccountFans = o.BigAccountFans
+	}
+	if o.MaxEntries > 0 {
+		opts.MaxEntries = o.MaxEntries
+	}
+	if o.BackfillCount > 0 {
+		opts.BackfillCount = o.BackfillCount
+	}
+	if o.BatchSize > 0 {
+		opts.BatchSize = o.BatchSize
+	}
+	if o.TrimMinutes > 0 {
+		opts.TrimMinutes = o.TrimMinutes
+	}
+	go trimLoop()
+}
+
+// isBigAccount 作者是否走读扩散
+func isBigAccount(authorID uint) (bool, error) {
+	var fans []uint64
+	if err := global.Db.Model(&models.User{}).Where("user_id = ?", authorID).Pluck("fan_count", &fans).Error; err != nil {
+		return false, err
+	}
+	return len(fans) > 0 && fans[0] >= uint64(opts.BigAccountFans), nil
+}
+
+// upsert 写入时间线，已存在时更新排序时间（笔记被编辑后重新排到前面）
+func upsert(entries []models.TimelineEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return global.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "note_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sort_time"}),
+	}).CreateInBatches(&entries, opts.BatchSize).Error
+}
+
+// Publish 笔记发布或更新后异步写入作者所有粉丝的时间线，大号跳过
+func Publish(note models.Note) {
+	go func() {
+		if err := fanout(note); err != nil {
+			log.Printf("笔记 %d 写入粉丝时间线失败: %v", note.NoteID, err)
+		}
+	}()
+}
+
+// fanout 按批读取粉丝并写入时间线
+func fanout(note models.Note) error {
+	big, err := isBigAccount(note.NoteCreatorID)
+	if err != nil || big {
+		return err
+	}
+	now := time.Now()
+	var followers []models.Follower
+	return global.Db.Select("id", "uid").
+		Where("fid = ?", note.NoteCreatorID).
+		FindInBatches(&followers, opts.BatchSize, func(tx *gorm.DB, batch int) error {
+			entries := make([]models.TimelineEntry, 0, len(followers))
+			for _, f := range followers {
+				entries = append(entries, models.TimelineEntry{
+					UserID:    f.Uid,
+					NoteID:    note.NoteID,
+					AuthorID:  note.NoteCreatorID,
+					SortTime:  note.NoteUpdateTime,
+					CreatedAt: now,
+				})
+			}
+			return upsert(entries)
+		}).Error
+}
+
+// Backfill 关注后把对方最近的笔记回填到时间线，大号读时拉取，无需回填
+func Backfill(userID, authorID uint) {
+	big, err := isBigAccount(authorID)
+	if err != nil || big {
+		if err != nil {
+			log.Printf("回填时间线失败: %v", err)
+		}
+		return
+	}
+	var notes []models.Note
+	if err := global.Db.Select("note_id", "note_creator_id", "note_update_time").
+		Where("note_creator_id = ?", authorID).
+		Order("note_update_time DESC").
+		Limit(opts.BackfillCount).
+		Find(&notes).Error; err != nil {
+		log.Printf("回填时间线失败: %v", err)
+		return
+	}
+	now := time.Now()
+	entries := make([]models.TimelineEntry, 0, len(notes))
+	for _, note := range notes {
+		entries = append(entries, models.TimelineEntry{
+			UserID:    userID,
+			NoteID:    note.NoteID,
+			AuthorID:  authorID,
+			SortTime:  note.NoteUpdateTime,
+			CreatedAt: now,
+		})
+	}
+	if err := upsert(entries); err != nil {
+		log.Printf("回填时间线失败: %v", err)
+	}
+}
+
+// RemoveAuthor 取消关注或拉黑后，从用户时间线中移除该作者的笔记
+func RemoveAuthor(userID, authorID uint) {
+	if err := global.Db.Where("user_id = ? AND author_id = ?", userID, authorID).
+		Delete(&models.TimelineEntry{}).Error; err != nil {
+		log.Printf("移除时间线中作者 %d 的笔记失败: %v", authorID, err)
+	}
+}
+
+// Remove 笔记删除或不再对粉丝可见时，从所有时间线中移除
+func Remove(noteID uint) {
+	for {
+		result := global.Db.Where("note_id = ?", noteID).Limit(opts.BatchSize).Delete(&models.TimelineEntry{})
+		if result.Error != nil {
+			log.Printf("从时间线移除笔记 %d 失败: %v", noteID, result.Error)
+			return
+		}
+		if result.RowsAffected < int64(opts.BatchSize) {
+			return
+		}
+	}
+}
+
+// trimLoop 定期截断过长的时间线，只保留最近 MaxEntries 条
+func trimLoop() {
+	ticker := time.NewTicker(time.Duration(opts.TrimMinutes) * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := trim(); err != nil {
+			log.Printf("截断时间线失败: %v", err)
+		}
+	}
+}
+
+func trim() error {
+	var users []uint
+	if err := global.Db.Model(&models.TimelineEntry{}).
+		Group("user_id").
+		Having("COUNT(*) > ?", opts.MaxEntries).
+		Pluck("user_id", &users).Error; err != nil {
+		return err
+	}
+	for _, userID := range users {
+		// 第 MaxEntries 条的排序时间，更早的全部删除
+		var cutoff []int64
+		if err := global.Db.Model(&models.TimelineEntry{}).
+			Where("user_id = ?", userID).
+			Order("sort_time DESC").
+			Offset(opts.MaxEntries-1).
+			Limit(1).
+			Pluck("sort_time", &cutoff).Error; err != nil {
+			return err
+		}
+		if len(cutoff) == 0 {
+			continue
+		}
+		if err := global.Db.Where("user_id = ? AND sort_time < ?", userID, cutoff[0]).
+			Delete(&models.TimelineEntry{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,12 +3,20 @@ package utils
 //工具文件
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/go-sql-driver/mysql"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
-	"log"
+	"gorm.io/gorm"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/imaging"
 	"travel-from-sysu-backend/models"
+	"unicode/utf8"
 )
 
 func HashPwd(pwd string) (string, error) {
@@ -25,90 +33,32 @@ func GenerateJWT(username string) (string, error) {
 	return "Bearer " + signedToken, err
 }
 
-func CheckPwd(hashedPwd, plainPwd string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(plainPwd))
-}
-
-// 归一化函数：将值归一化到 0 - 100 区间
-func normalize(value, min, max int64) int64 {
-	if max == min {
-		return 0
+// ParseJWT 校验 GenerateJWT 生成的令牌（可带 "Bearer " 前缀），返回其中的用户名
+func ParseJWT(tokenString string) (string, error) {
+	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte("secret"), nil
+	})
+	if err != nil {
+		return "", err
 	}
-	return (value - min) * 100 / (max - min)
-}
 
-// 热度计算公式（计算时会归一化）
-func calculateScore(note models.Note, minLikes, maxLikes, minCollects, maxCollects, minComments, maxComments, minTimestamp, maxTimestamp int64) float64 {
-	// 归一化各项指标
-	normalizedLikes := normalize(int64(note.LikeCounts), minLikes, maxLikes)
-	normalizedCollects := normalize(int64(note.CollectCounts), minCollects, maxCollects)
-	normalizedComments := normalize(int64(note.CommentCounts), minComments, maxComments)
-	normalizedUpdateTime := normalize(note.NoteUpdateTime, minTimestamp, maxTimestamp)
-
-	// 假设计算热度时，采用以下权重：收藏数 40%，点赞数 30%，评论数 20%，更新时间 10%
-	return 0.4*float64(normalizedCollects) + 0.3*float64(normalizedLikes) +
-		0.2*float64(normalizedComments) + 0.1*float64(normalizedUpdateTime)
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "", errors.New("invalid token")
+	}
+	username, ok := claims["username"].(string)
+	if !ok || username == "" {
+		return "", errors.New("invalid token")
+	}
+	return username, nil
 }
 
-// UpdateHotRecommendations 每分钟计算一次热度并更新到数据库
-func UpdateHotRecommendations() {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		// 获取所有笔记数据
-		var notes []models.Note
-		if err := global.Db.Find(&notes).Error; err != nil {
-			log.Printf("Failed to fetch notes for hot recommendations: %v", err)
-			continue
-		}
-
-		// 计算最大最小值
-		var maxCollects, minCollects, maxComments, minComments uint
-		var maxLikes, minLikes int
-		var maxTimestamp, minTimestamp int64
-
-		// 获取最大值和最小值
-		for _, note := range notes {
-			if note.LikeCounts > maxLikes {
-				maxLikes = note.LikeCounts
-			}
-			if note.LikeCounts < minLikes {
-				minLikes = note.LikeCounts
-			}
-			if note.CollectCounts > maxCollects {
-				maxCollects = note.CollectCounts
-			}
-			if note.CollectCounts < minCollects {
-				minCollects = note.CollectCounts
-			}
-			if note.CommentCounts > maxComments {
-				maxComments = note.CommentCounts
-			}
-			if note.CommentCounts < minComments {
-				minComments = note.CommentCounts
-			}
-			if note.NoteUpdateTime > maxTimestamp {
-				maxTimestamp = note.NoteUpdateTime
-			}
-			if note.NoteUpdateTime < minTimestamp {
-				minTimestamp = note.NoteUpdateTime
-			}
-		}
-
-		// 计算每个笔记的热度并更新到数据库
-		for _, note := range notes {
-			// 计算归一化后的热度
-			score := calculateScore(note, int64(minLikes), int64(maxLikes), int64(minCollects), int64(maxCollects), int64(minComments), int64(maxComments), minTimestamp, maxTimestamp)
-
-			// 更新笔记的热度分数
-			if err := global.Db.Model(&note).Update("score", score).Error; err != nil {
-				log.Printf("Failed to update score for note %d: %v", note.NoteID, err)
-			}
-		}
-
-		log.Println("Hot recommendations updated")
-	}
+func CheckPwd(hashedPwd, plainPwd string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(plainPwd))
 }
 
 // CheckUserFollow 检查用户是否关注帖子作者
@@ -144,3 +94,132 @@ func CheckIfUserCollected(userID int, noteID int) int {
 	}
 	return 0 // 未收藏
 }
+
+// TruncateRunes 按字符（而非字节）截断字符串，超出部分用省略号代替
+func TruncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// ParseNoteURLs 解析笔记的 NoteURLs 字段，兼容 JSON 数组和单个 URL 两种存储格式
+func ParseNoteURLs(noteURLs string) []string {
+	var urls []string
+	if err := json.Unmarshal([]byte(noteURLs), &urls); err == nil {
+		return urls
+	}
+	if noteURLs != "" {
+		return []string{noteURLs}
+	}
+	return nil
+}
+
+// NoteThumbnail 返回笔记的缩略图（第一张图片的缩略图规格，旧图片没有时用原图），视频笔记返回空字符串
+func NoteThumbnail(noteURLs string) string {
+	for _, url := range ParseNoteURLs(noteURLs) {
+		ext := strings.ToLower(filepath.Ext(url))
+		if ext == ".mp4" || ext == ".mov" {
+			continue
+		}
+		return imaging.VariantURL(url, imaging.Thumbnail)
+	}
+	return ""
+}
+
+// parseClock 解析 HH:MM 格式的时刻，返回当天零点起的分钟数
+func parseClock(clock string) (int, bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// QuietHoursEnd 判断 now 是否落在用户时区的免打扰时段 [start, end) 内，是则返回时段结束时刻
+// end 早于 start 表示跨天（如 22:00-08:00）；时区或时刻无法解析时视为不在免打扰时段
+func QuietHoursEnd(now time.Time, timeZone, start, end string) (time.Time, bool) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return now, false
+	}
+	startMin, ok1 := parseClock(start)
+	endMin, ok2 := parseClock(end)
+	if !ok1 || !ok2 || startMin == endMin {
+		return now, false
+	}
+
+	local := now.In(loc)
+	nowMin := local.Hour()*60 + local.Minute()
+	endToday := time.Date(local.Year(), local.Month(), local.Day(), endMin/60, endMin%60, 0, 0, loc)
+
+	if startMin < endMin {
+		if nowMin >= startMin && nowMin < endMin {
+			return endToday, true
+		}
+		return now, false
+	}
+	// 跨天时段
+	if nowMin >= startMin {
+		return endToday.AddDate(0, 0, 1), true
+	}
+	if nowMin < endMin {
+		return endToday, true
+	}
+	return now, false
+}
+
+// MentionToken 正文中解析出的一个 @用户名
+type MentionToken struct {
+	Username string
+	Offset   int // 按字符计的起始位置（含 @）
+	Length   int // 按字符计的长度（含 @）
+}
+
+// mentionPattern 匹配 @用户名，用户名遇到空白、@ 或常见中英文标点结束
+var mentionPattern = regexp.MustCompile(`@([^\s@，。！？、；：,.!?;:"'“”‘’()（）\[\]【】<>《》]+)`)
+
+// ParseMentions 解析正文中的 @用户名，位置按字符（rune）计，方便客户端直接定位
+func ParseMentions(content string) []MentionToken {
+	var tokens []MentionToken
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		tokens = append(tokens, MentionToken{
+			Username: content[loc[2]:loc[3]],
+			Offset:   utf8.RuneCountInString(content[:loc[0]]),
+			Length:   utf8.RuneCountInString(content[loc[0]:loc[1]]),
+		})
+	}
+	return tokens
+}
+
+// EditDistance 两个字符串按字符（rune）计算的编辑距离
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// IsDuplicateKey 判断是否为唯一索引冲突（MySQL 1062），用于并发插入时识别记录已存在
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
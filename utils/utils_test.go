@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	at := func(value string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	cases := []struct {
+		name       string
+		now        time.Time
+		tz         string
+		start, end string
+		quiet      bool
+		wantEnd    time.Time
+	}{
+		{"跨天时段-开始前", at("2024-05-01 21:59"), "Asia/Shanghai", "22:00", "08:00", false, time.Time{}},
+		{"跨天时段-开始时刻", at("2024-05-01 22:00"), "Asia/Shanghai", "22:00", "08:00", true, at("2024-05-02 08:00")},
+		{"跨天时段-午夜前", at("2024-05-01 23:59"), "Asia/Shanghai", "22:00", "08:00", true, at("2024-05-02 08:00")},
+		{"跨天时段-午夜后", at("2024-05-02 00:30"), "Asia/Shanghai", "22:00", "08:00", true, at("2024-05-02 08:00")},
+		{"跨天时段-结束时刻", at("2024-05-02 08:00"), "Asia/Shanghai", "22:00", "08:00", false, time.Time{}},
+		{"跨天时段-跨月", at("2024-05-31 23:00"), "Asia/Shanghai", "22:00", "08:00", true, at("2024-06-01 08:00")},
+		{"当天时段内", at("2024-05-01 13:30"), "Asia/Shanghai", "13:00", "14:00", true, at("2024-05-01 14:00")},
+		{"当天时段外", at("2024-05-01 14:00"), "Asia/Shanghai", "13:00", "14:00", false, time.Time{}},
+		// 上海 23:30 是 UTC 15:30，按用户时区判断
+		{"按用户时区", at("2024-05-01 23:30"), "UTC", "22:00", "08:00", false, time.Time{}},
+		{"无效时区", at("2024-05-01 23:00"), "Mars/Base", "22:00", "08:00", false, time.Time{}},
+		{"无效时刻", at("2024-05-01 23:00"), "Asia/Shanghai", "25:00", "08:00", false, time.Time{}},
+		{"开始等于结束", at("2024-05-01 23:00"), "Asia/Shanghai", "08:00", "08:00", false, time.Time{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			end, quiet := QuietHoursEnd(c.now, c.tz, c.start, c.end)
+			if quiet != c.quiet {
+				t.Fatalf("quiet = %v, want %v", quiet, c.quiet)
+			}
+			if quiet && !end.Equal(c.wantEnd) {
+				t.Errorf("end = %v, want %v", end, c.wantEnd)
+			}
+			if !quiet && !end.Equal(c.now) {
+				t.Errorf("不在免打扰时段时应返回 now，实际 %v", end)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"log"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+)
+
+// ViewerState 当前用户对一篇笔记的互动状态，所有笔记列表接口的 status 字段共用
+type ViewerState struct {
+	IsLike    int `json:"is_like"`    // 是否点赞
+	IsCollect int `json:"is_collect"` // 是否收藏
+	IsFollow  int `json:"is_follow"`  // 是否关注作者
+}
+
+// ViewerStates 一页笔记的互动状态
+type ViewerStates struct {
+	liked     map[uint]bool
+	collected map[uint]bool
+	followed  map[uint]bool
+}
+
+// LoadViewerStates 用三次集合查询取出用户对一页笔记的点赞、收藏和关注作者状态，
+// 代替逐条调用 CheckIfUserLiked/CheckIfUserCollected/CheckUserFollow。查询失败时视为未互动
+func LoadViewerStates(userID int, notes []models.Note) ViewerStates {
+	states := ViewerStates{
+		liked:     make(map[uint]bool),
+		collected: make(map[uint]bool),
+		followed:  make(map[uint]bool),
+	}
+	if userID <= 0 || len(notes) == 0 {
+		return states
+	}
+
+	noteIDs := make([]uint, 0, len(notes))
+	authorIDs := make([]uint, 0, len(notes))
+	seenAuthor := make(map[uint]bool, len(notes))
+	for _, note := range notes {
+		noteIDs = append(noteIDs, note.NoteID)
+		if !seenAuthor[note.NoteCreatorID] {
+			seenAuthor[note.NoteCreatorID] = true
+			authorIDs = append(authorIDs, note.NoteCreatorID)
+		}
+	}
+
+	for _, q := range []struct {
+		model  interface{}
+		column string
+		ids    []uint
+		set    map[uint]bool
+	}{
+		{&models.Like{}, "nid", noteIDs, states.liked},
+		{&models.Collect{}, "nid", noteIDs, states.collected},
+		{&models.Follower{}, "fid", authorIDs, states.followed},
+	} {
+		var hits []uint
+		if err := global.Db.Model(q.model).
+			Where("uid = ? AND "+q.column+" IN ?", userID, q.ids).
+			Pluck(q.column, &hits).Error; err != nil {
+			log.Printf("查询用户 %d 的互动状态失败: %v", userID, err)
+			continue
+		}
+		for _, id := range hits {
+			q.set[id] = true
+		}
+	}
+	return states
+}
+
+// Of 取出某篇笔记的互动状态
+func (s ViewerStates) Of(note models.Note) ViewerState {
+	return ViewerState{
+		IsLike:    boolToInt(s.liked[note.NoteID]),
+		IsCollect: boolToInt(s.collected[note.NoteID]),
+		IsFollow:  boolToInt(s.followed[note.NoteCreatorID]),
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"testing"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/models"
+	"travel-from-sysu-backend/testutil"
+)
+
+// viewerPageSize 一页笔记数，与列表接口的默认页大小一致
+const viewerPageSize = 30
+
+// seedViewerStates 用户 1 浏览 30 篇笔记（10 位作者）：点赞了偶数篇，收藏了每第三篇，关注了偶数号作者
+func seedViewerStates(tb testing.TB) []models.Note {
+	tb.Helper()
+	testutil.OpenDB(tb, &models.User{}, &models.Note{}, &models.Comments{}, &models.Like{}, &models.Collect{}, &models.Follower{})
+
+	notes := make([]models.Note, 0, viewerPageSize)
+	for i := 0; i < viewerPageSize; i++ {
+		note := models.Note{NoteID: uint(100001 + i), NoteCreatorID: uint(10 + i%10)}
+		notes = append(notes, note)
+		nid := note.NoteID
+		if i%2 == 0 {
+			global.Db.Create(&models.Like{Uid: 1, Nid: &nid})
+		}
+		if i%3 == 0 {
+			global.Db.Create(&models.Collect{Uid: 1, Nid: &nid})
+		}
+	}
+	global.Db.Create(&notes)
+	for author := uint(10); author < 20; author += 2 {
+		global.Db.Create(&models.Follower{Uid: 1, Fid: author})
+	}
+	return notes
+}
+
+// loadViewerStatesOneByOne 批量查询之前的写法：每篇笔记分别查点赞、收藏和关注
+func loadViewerStatesOneByOne(userID int, notes []models.Note) []ViewerState {
+	states := make([]ViewerState, 0, len(notes))
+	for _, note := range notes {
+		states = append(states, ViewerState{
+			IsLike:    CheckIfUserLiked(userID, int(note.NoteID)),
+			IsCollect: CheckIfUserCollected(userID, int(note.NoteID)),
+			IsFollow:  CheckUserFollow(userID, int(note.NoteCreatorID)),
+		})
+	}
+	return states
+}
+
+func TestLoadViewerStatesMatchesOneByOne(t *testing.T) {
+	notes := seedViewerStates(t)
+	queries := testutil.CountQueries(global.Db)
+
+	states := LoadViewerStates(1, notes)
+	if n := queries(); n != 3 {
+		t.Errorf("一页笔记应只查询 3 次，实际 %d 次", n)
+	}
+	if got := states.Of(notes[0]); got != (ViewerState{IsLike: 1, IsCollect: 1, IsFollow: 1}) {
+		t.Fatalf("第一篇笔记已点赞、收藏并关注作者，实际 %+v", got)
+	}
+	for i, want := range loadViewerStatesOneByOne(1, notes) {
+		if got := states.Of(notes[i]); got != want {
+			t.Errorf("笔记 %d 的状态 %+v，逐条查询为 %+v", notes[i].NoteID, got, want)
+		}
+	}
+
+	// 未登录或空页不查询
+	before := queries()
+	LoadViewerStates(0, notes)
+	LoadViewerStates(1, nil)
+	if n := queries() - before; n != 0 {
+		t.Errorf("未登录或空页不应查询，实际 %d 次", n)
+	}
+}
+
+// BenchmarkViewerStates 对比一页 30 篇笔记的互动状态查询：批量 3 次，逐条 3×30 次
+func BenchmarkViewerStates(b *testing.B) {
+	b.Run("batch", func(b *testing.B) {
+		notes := seedViewerStates(b)
+		queries := testutil.CountQueries(global.Db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			LoadViewerStates(1, notes)
+		}
+		b.ReportMetric(float64(queries())/float64(b.N), "queries/page")
+	})
+	b.Run("one_by_one", func(b *testing.B) {
+		notes := seedViewerStates(b)
+		queries := testutil.CountQueries(global.Db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			loadViewerStatesOneByOne(1, notes)
+		}
+		b.ReportMetric(float64(queries())/float64(b.N), "queries/page")
+	})
+}
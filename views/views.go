@@ -0,0 +1,204 @@
+package views
+
+//笔记浏览计数：同一浏览者（登录用户按 ID，未登录按设备+IP 或 IP）在窗口内重复浏览只计一次，
+//作者本人的浏览不计；计数先累加在内存里，定期合并成一条 UPDATE 写回，同时计入创作者看板并通知热度任务重算
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"travel-from-sysu-backend/analytics"
+	"travel-from-sysu-backend/global"
+	"travel-from-sysu-backend/hotscore"
+)
+
+// Options 浏览计数参数
+type Options struct {
+	DedupeMinutes int // 同一浏览者在该时间内重复浏览同一笔记只计一次
+	FlushSeconds  int // 内存计数写回数据库的间隔（秒）
+	BatchSize     int // 每条 UPDATE 合并的笔记数
+	DevicesPerIP  int // 去重窗口内同一 IP 最多计入的未登录设备数，防止伪造设备标识刷浏览
+}
+
+var opts = Options{
+	DedupeMinutes: 30,
+	FlushSeconds:  10,
+	BatchSize:     500,
+	DevicesPerIP:  5,
+}
+
+var (
+	mu        sync.Mutex
+	seen      = make(map[string]time.Time) // 浏览者+笔记 → 去重窗口到期时间
+	anonymous = make(map[string]ipQuota)   // IP+笔记 → 窗口内已计入的未登录浏览
+	pending   = make(map[pendingKey]uint)  // 待写回的浏览数
+)
+
+// ipQuota 同一 IP 在去重窗口内对一篇笔记已计入的未登录浏览数
+type ipQuota struct {
+	count   int
+	expires time.Time
+}
+
+// pendingKey 按笔记和浏览者合并计数，写回时据此区分粉丝浏览
+type pendingKey struct {
+	noteID   uint
+	authorID uint
+	viewerID uint
+}
+
+// Init 设置参数并启动定期写回，未配置的项使用默认值，需在数据库初始化之后调用
+func Init(o Options) {
+	if o.DedupeMinutes > 0 {
+		opts.DedupeMinutes = o.DedupeMinutes
+	}
+	if o.FlushSeconds > 0 {
+		opts.FlushSeconds = o.FlushSeconds
+	}
+	if o.BatchSize > 0 {
+		opts.BatchSize = o.BatchSize
+	}
+	if o.DevicesPerIP > 0 {
+		opts.DevicesPerIP = o.DevicesPerIP
+	}
+	go flushLoop()
+}
+
+// Viewer 浏览者标识：登录用户用 UserID，未登录用 DeviceID+IP，没有设备标识时用 IP。
+// UserID 只能来自校验过的登录令牌，客户端自报的 uid 会绕过每个 IP 的设备数限制
+type Viewer struct {
+	UserID   uint
+	DeviceID string
+	IP       string
+}
+
+// key 去重用的浏览者标识，无法识别时返回空。
+// 设备标识由客户端上报，单独使用时换一个标识就能重复计数，所以和 IP 组合，并在 Record 中限制每个 IP 的设备数
+func (v Viewer) key() string {
+	deviceID := strings.TrimSpace(v.DeviceID)
+	switch {
+	case v.UserID > 0:
+		return fmt.Sprintf("u:%d", v.UserID)
+	case deviceID != "":
+		return "d:" + deviceID + "@" + v.IP
+	case v.IP != "":
+		return "ip:" + v.IP
+	}
+	return ""
+}
+
+// Record 记录一次浏览，返回是否计入（作者本人、窗口内重复浏览不计）
+func Record(noteID, authorID uint, viewer Viewer) bool {
+	if noteID == 0 || (viewer.UserID > 0 && viewer.UserID == authorID) {
+		return false
+	}
+	key := viewer.key()
+	if key == "" {
+		return false
+	}
+	key = fmt.Sprintf("%s:%d", key, noteID)
+
+	now := time.Now()
+	mu.Lock()
+	defer mu.Unlock()
+	if expires, ok := seen[key]; ok && now.Before(expires) {
+		return false
+	}
+	window := now.Add(time.Duration(opts.DedupeMinutes) * time.Minute)
+	if viewer.UserID == 0 && viewer.IP != "" {
+		// 同一 IP 的未登录浏览在窗口内最多计入 DevicesPerIP 次，兼顾同一出口 IP 下的多台设备
+		quotaKey := fmt.Sprintf("%s:%d", viewer.IP, noteID)
+		quota, ok := anonymous[quotaKey]
+		if !ok || !now.Before(quota.expires) {
+			quota = ipQuota{expires: window}
+		}
+		if quota.count >= opts.DevicesPerIP {
+			return false
+		}
+		quota.count++
+		anonymous[quotaKey] = quota
+	}
+	seen[key] = window
+	pending[pendingKey{noteID: noteID, authorID: authorID, viewerID: viewer.UserID}]++
+	return true
+}
+
+// flushLoop 定期写回计数并清理过期的去重记录
+func flushLoop() {
+	ticker := time.NewTicker(time.Duration(opts.FlushSeconds) * time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		flush()
+		mu.Lock()
+		for key, expires := range seen {
+			if !now.Before(expires) {
+				delete(seen, key)
+			}
+		}
+		for key, quota := range anonymous {
+			if !now.Before(quota.expires) {
+				delete(anonymous, key)
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+// flush 把内存中的计数按批写回数据库，失败的计数放回下次重试
+func flush() {
+	mu.Lock()
+	taken := pending
+	pending = make(map[pendingKey]uint)
+	mu.Unlock()
+	if len(taken) == 0 {
+		return
+	}
+
+	counts := make(map[uint]uint)
+	batches := make([]analytics.ViewBatch, 0, len(taken))
+	for key, count := range taken {
+		counts[key.noteID] += count
+		batches = append(batches, analytics.ViewBatch{NoteID: key.noteID, AuthorID: key.authorID, ViewerID: key.viewerID, Count: count})
+	}
+	ids := make([]uint, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	// 写回失败的笔记放回下次重试，看板只记入已写回的部分
+	failed := make(map[uint]bool)
+	for start := 0; start < len(ids); start += opts.BatchSize {
+		batch := ids[start:min(start+opts.BatchSize, len(ids))]
+		var cases strings.Builder
+		args := make([]interface{}, 0, len(batch)*2+1)
+		for _, id := range batch {
+			cases.WriteString(" WHEN ? THEN ?")
+			args = append(args, id, counts[id])
+		}
+		args = append(args, batch)
+		sql := fmt.Sprintf("UPDATE notes SET view_count = view_count + CASE note_id%s ELSE 0 END WHERE note_id IN ?", cases.String())
+		if err := global.Db.Exec(sql, args...).Error; err != nil {
+			log.Printf("写回浏览计数失败: %v", err)
+			for _, id := range ids[start:] {
+				failed[id] = true
+			}
+			break
+		}
+		hotscore.MarkDirty(batch...)
+	}
+
+	written := batches[:0]
+	mu.Lock()
+	for _, b := range batches {
+		if failed[b.NoteID] {
+			pending[pendingKey{noteID: b.NoteID, authorID: b.AuthorID, viewerID: b.ViewerID}] += b.Count
+		} else {
+			written = append(written, b)
+		}
+	}
+	mu.Unlock()
+	if err := analytics.AddViews(written); err != nil {
+		log.Printf("记入创作者看板浏览数失败: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package views
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// resetViews 清空内存中的去重记录和待写回计数
+func resetViews(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	seen = make(map[string]time.Time)
+	anonymous = make(map[string]ipQuota)
+	pending = make(map[pendingKey]uint)
+	mu.Unlock()
+}
+
+func TestRecordDedupe(t *testing.T) {
+	const noteID, authorID = 100001, 7
+	cases := []struct {
+		name    string
+		viewers []Viewer
+		want    []bool
+	}{
+		{
+			name:    "作者本人不计",
+			viewers: []Viewer{{UserID: authorID, IP: "1.1.1.1"}},
+			want:    []bool{false},
+		},
+		{
+			name:    "登录用户换 IP 也只计一次",
+			viewers: []Viewer{{UserID: 1, IP: "1.1.1.1"}, {UserID: 1, IP: "2.2.2.2"}},
+			want:    []bool{true, false},
+		},
+		{
+			name:    "同一设备同一 IP 只计一次",
+			viewers: []Viewer{{DeviceID: "a", IP: "1.1.1.1"}, {DeviceID: " a ", IP: "1.1.1.1"}},
+			want:    []bool{true, false},
+		},
+		{
+			name:    "同一设备标识换了 IP 视为不同浏览者",
+			viewers: []Viewer{{DeviceID: "a", IP: "1.1.1.1"}, {DeviceID: "a", IP: "2.2.2.2"}},
+			want:    []bool{true, true},
+		},
+		{
+			name:    "没有设备标识按 IP 去重",
+			viewers: []Viewer{{IP: "1.1.1.1"}, {IP: "1.1.1.1"}, {IP: "2.2.2.2"}},
+			want:    []bool{true, false, true},
+		},
+		{
+			name:    "无法识别的浏览者不计",
+			viewers: []Viewer{{}},
+			want:    []bool{false},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resetViews(t)
+			for i, viewer := range c.viewers {
+				if got := Record(noteID, authorID, viewer); got != c.want[i] {
+					t.Errorf("第 %d 次浏览 %+v 计入 = %v，want %v", i+1, viewer, got, c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecordCapsDevicesPerIP(t *testing.T) {
+	resetViews(t)
+	// 同一 IP 每次换一个设备标识，只有前 DevicesPerIP 次计入
+	counted := 0
+	for i := 0; i < 50; i++ {
+		if Record(100001, 7, Viewer{DeviceID: fmt.Sprintf("fake-%d", i), IP: "1.1.1.1"}) {
+			counted++
+		}
+	}
+	if counted != opts.DevicesPerIP {
+		t.Errorf("同一 IP 计入 %d 次，want %d", counted, opts.DevicesPerIP)
+	}
+
+	// 上限按笔记分别计算，也不影响登录用户和其他 IP
+	if !Record(100002, 7, Viewer{DeviceID: "fake-0", IP: "1.1.1.1"}) {
+		t.Error("其他笔记不受上限影响")
+	}
+	if !Record(100001, 7, Viewer{UserID: 1, IP: "1.1.1.1"}) {
+		t.Error("登录用户不受上限影响")
+	}
+	if !Record(100001, 7, Viewer{DeviceID: "fake-0", IP: "2.2.2.2"}) {
+		t.Error("其他 IP 不受上限影响")
+	}
+
+	mu.Lock()
+	total := pending[pendingKey{noteID: 100001, authorID: 7}]
+	mu.Unlock()
+	if total != uint(opts.DevicesPerIP)+1 {
+		t.Errorf("未登录浏览待写回 %d 次，want %d", total, opts.DevicesPerIP+1)
+	}
+}